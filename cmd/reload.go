@@ -0,0 +1,73 @@
+package main
+
+import (
+	"leti/pkg/config"
+	"leti/pkg/logging"
+	"leti/pkg/models"
+	"leti/pkg/ratelimit"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// reloader перечитывает конфигурацию по SIGHUP или при изменении файла и
+// применяет то, что можно поменять на ходу: уровень журнала, общий лимит
+// запросов и Cache-Control каталога. Остальные изменения только записываются
+// в журнал — они вступят в силу после перезапуска.
+type reloader struct {
+	mu sync.Mutex
+	// running — действующая конфигурация: начальная плюс применённые изменения
+	running  *config.Config
+	logger   *slog.Logger
+	logLevel *slog.LevelVar
+	// limiter — nil, если ограничение частоты выключено
+	limiter      *ratelimit.Limiter
+	cacheControl interface{ SetCacheControl(map[string]string) }
+}
+
+// reload загружает конфигурацию заново. Новая конфигурация сначала целиком
+// проверяется: с ошибкой она не применяется ни частично, ни полностью.
+func (r *reloader) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := loadConfig(os.Args[1:])
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		r.logger.Error("Configuration reload rejected, keeping the running configuration", "error", err)
+		return
+	}
+	level, _ := logging.ParseLevel(next.Log.Level) // уже проверен в Validate
+
+	updated := *r.running
+	applied, restart := updated.Update(next)
+	if len(restart) > 0 {
+		r.logger.Warn("Configuration changes require a restart", "keys", restart)
+	}
+	if len(applied) == 0 {
+		r.logger.Info("Configuration reloaded, nothing to apply")
+		return
+	}
+
+	r.logLevel.Set(level)
+	if r.limiter != nil {
+		r.limiter.SetDefaultLimit(defaultRateLimit(updated.RateLimit))
+	}
+	r.cacheControl.SetCacheControl(cacheControlPolicies(updated.Cache.Control))
+	r.running = &updated
+	r.logger.Info("Configuration reloaded", "applied", applied)
+}
+
+func defaultRateLimit(cfg config.RateLimit) ratelimit.Limit {
+	return ratelimit.Limit{Requests: cfg.Requests, Period: cfg.Period, Burst: cfg.Burst}
+}
+
+func cacheControlPolicies(cfg config.CacheControl) map[string]string {
+	return map[string]string{
+		models.CollectionBooks:   cfg.Books,
+		models.CollectionAuthors: cfg.Authors,
+		models.CollectionGenres:  cfg.Genres,
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"leti/pkg/config"
+	"leti/pkg/health"
+	"leti/pkg/lockout"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/repository/fake"
+	psg "leti/pkg/repository/postgres"
+	"leti/pkg/repository/sqlite"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+)
+
+// storage — выбранное хранилище данных и то, что от него зависит.
+type storage struct {
+	repo    repository.DataBase
+	lockout lockout.Store
+	// pg — только для storage: postgres: пул для метрик, LISTEN/NOTIFY кэша,
+	// общие лимиты запросов и ключи идемпотентности.
+	pg     *psg.PGRepo
+	checks []health.Option
+	close  func()
+}
+
+// storageFromConfig открывает хранилище из cfg.Storage: postgres, sqlite или memory.
+func storageFromConfig(cfg *config.Config, logger *slog.Logger) (*storage, error) {
+	switch cfg.Storage {
+	case "sqlite":
+		db, err := sqlite.New(cfg.SQLite.Path, sqlite.WithQueryTimeout(cfg.Database.QueryTimeout))
+		if err != nil {
+			return nil, err
+		}
+		return &storage{
+			repo:    db,
+			lockout: db,
+			checks: []health.Option{
+				health.WithCheck("database", db.Ping),
+				health.WithCheck("migrations", db.CheckSchema),
+			},
+			close: db.Close,
+		}, nil
+	case "memory":
+		db := &fake.FakeRepo{}
+		if err := seedMemory(db); err != nil {
+			return nil, err
+		}
+		return &storage{
+			repo:    db,
+			lockout: lockout.NewMemoryStore(),
+			close:   func() {},
+		}, nil
+	default:
+		db, err := psg.New(cfg.Database.URL,
+			psg.WithLogger(logger),
+			psg.WithTracerProvider(otel.GetTracerProvider()),
+			psg.WithQueryTimeout(cfg.Database.QueryTimeout),
+			psg.WithPool(psg.PoolConfig{
+				MaxConns:          int32(cfg.Database.MaxConns),
+				MinConns:          int32(cfg.Database.MinConns),
+				MaxConnLifetime:   cfg.Database.MaxConnLifetime,
+				MaxConnIdleTime:   cfg.Database.MaxConnIdleTime,
+				HealthCheckPeriod: cfg.Database.HealthCheckPeriod,
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &storage{
+			repo: db,
+			// счётчики в БД, чтобы блокировка действовала на всех экземплярах
+			lockout: db,
+			pg:      db,
+			checks: []health.Option{
+				health.WithCheck("database", db.Ping),
+				health.WithCheck("migrations", db.CheckSchema),
+			},
+			close: db.Close,
+		}, nil
+	}
+}
+
+// memoryAdminHash — пароль администратора Den, тот же, что в миграции 000006.
+const memoryAdminHash = "$2a$10$PwpqIpesHQr7S66hrZGkzumVjFuwZwU2l9Eq084Ctk0ZNZeHla3ni"
+
+// seedMemory наполняет хранилище в памяти теми же данными, что миграции
+// 000002 и 000006 кладут в базу: иначе в нём некому даже войти.
+func seedMemory(db *fake.FakeRepo) error {
+	ctx := context.Background()
+	db.AddUser(models.User{Username: "Den", Password: memoryAdminHash, Role: "admin"})
+
+	authors := map[string]int{}
+	for _, name := range []string{"Лев Толстой", "Федор Достоевский", "Антон Чехов"} {
+		id, err := db.NewAuthor(ctx, models.Author{Author: name})
+		if err != nil {
+			return err
+		}
+		authors[name] = id
+	}
+	genres := map[string]int{}
+	for _, name := range []string{"Роман", "Драма", "Комедия", "Трагедия", "Фантастика"} {
+		id, err := db.NewGenre(ctx, models.Genre{Genre: name})
+		if err != nil {
+			return err
+		}
+		genres[name] = id
+	}
+	for _, book := range []struct {
+		name, author, genre string
+		price               int
+	}{
+		{"Война и мир", "Лев Толстой", "Роман", 500},
+		{"Анна Каренина", "Лев Толстой", "Роман", 450},
+		{"Преступление и наказание", "Федор Достоевский", "Роман", 400},
+		{"Братья Карамазовы", "Федор Достоевский", "Роман", 420},
+		{"Вишневый сад", "Антон Чехов", "Драма", 300},
+	} {
+		_, err := db.NewBook(ctx, models.Book{
+			Name:      book.name,
+			Price:     book.price,
+			Author_id: authors[book.author],
+			Genre_id:  genres[book.genre],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
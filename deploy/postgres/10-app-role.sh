@@ -0,0 +1,10 @@
+#!/bin/sh
+# Выполняется образом postgres один раз, при создании пустого тома: заводит
+# роль, под которой подключается сервис. Права на таблицы ей выдаёт миграция
+# 000019, RLS на неё действует (в отличие от суперпользователя POSTGRES_USER).
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v password="$APP_DB_PASSWORD" <<'EOSQL'
+CREATE ROLE library_app LOGIN PASSWORD :'password' NOSUPERUSER NOCREATEDB NOCREATEROLE NOBYPASSRLS;
+EOSQL
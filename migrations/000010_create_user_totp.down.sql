@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS mfa_required_roles;
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
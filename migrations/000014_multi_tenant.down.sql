@@ -0,0 +1,29 @@
+DROP POLICY IF EXISTS tenant_isolation ON books;
+DROP POLICY IF EXISTS tenant_isolation ON genres;
+DROP POLICY IF EXISTS tenant_isolation ON authors;
+ALTER TABLE books NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE books DISABLE ROW LEVEL SECURITY;
+ALTER TABLE genres NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE genres DISABLE ROW LEVEL SECURITY;
+ALTER TABLE authors NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE authors DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS users_tenant_id;
+DROP INDEX IF EXISTS books_tenant_id;
+
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_genre_fkey;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_author_fkey;
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_tenant_id_id_key;
+ALTER TABLE authors DROP CONSTRAINT IF EXISTS authors_tenant_id_id_key;
+ALTER TABLE books ADD CONSTRAINT books_author_id_fkey FOREIGN KEY (author_id) REFERENCES authors (id);
+ALTER TABLE books ADD CONSTRAINT books_genre_id_fkey FOREIGN KEY (genre_id) REFERENCES genres (id);
+
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_tenant_genre_key;
+ALTER TABLE genres ADD CONSTRAINT genres_genre_key UNIQUE (genre);
+
+ALTER TABLE books DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE genres DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE authors DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(200) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Всё, что уже есть в базе, принадлежит арендатору по умолчанию
+INSERT INTO tenants (id, slug, name) VALUES (1, 'default', 'Default library') ON CONFLICT DO NOTHING;
+SELECT setval('tenants_id_seq', (SELECT max(id) FROM tenants));
+
+ALTER TABLE users ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE authors ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE genres ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE books ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+
+-- Новые строки каталога получают арендатора из настройки сессии, а не 1
+ALTER TABLE authors ALTER COLUMN tenant_id SET DEFAULT NULLIF(current_setting('app.tenant_id', true), '')::int;
+ALTER TABLE genres ALTER COLUMN tenant_id SET DEFAULT NULLIF(current_setting('app.tenant_id', true), '')::int;
+ALTER TABLE books ALTER COLUMN tenant_id SET DEFAULT NULLIF(current_setting('app.tenant_id', true), '')::int;
+
+-- Жанр уникален в пределах библиотеки
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_genre_key;
+ALTER TABLE genres ADD CONSTRAINT genres_tenant_genre_key UNIQUE (tenant_id, genre);
+
+-- Книга может ссылаться только на автора и жанр своей библиотеки
+ALTER TABLE authors ADD CONSTRAINT authors_tenant_id_id_key UNIQUE (tenant_id, id);
+ALTER TABLE genres ADD CONSTRAINT genres_tenant_id_id_key UNIQUE (tenant_id, id);
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_author_id_fkey;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_genre_id_fkey;
+ALTER TABLE books ADD CONSTRAINT books_author_fkey FOREIGN KEY (tenant_id, author_id) REFERENCES authors (tenant_id, id);
+ALTER TABLE books ADD CONSTRAINT books_genre_fkey FOREIGN KEY (tenant_id, genre_id) REFERENCES genres (tenant_id, id);
+
+CREATE INDEX books_tenant_id ON books (tenant_id);
+CREATE INDEX users_tenant_id ON users (tenant_id);
+
+-- Row-level security: даже запрос без фильтра видит только строки арендатора,
+-- записанного в app.tenant_id (см. PGRepo.inTenant). Без настройки не видно ничего.
+-- FORCE нужен, чтобы политики действовали и на владельца таблиц;
+-- суперпользователь их всё равно обходит, поэтому сервис должен работать под отдельной ролью.
+ALTER TABLE authors ENABLE ROW LEVEL SECURITY;
+ALTER TABLE authors FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON authors
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE genres ENABLE ROW LEVEL SECURITY;
+ALTER TABLE genres FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON genres
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE books ENABLE ROW LEVEL SECURITY;
+ALTER TABLE books FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON books
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
@@ -0,0 +1,4 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn TEXT;
+
+-- ISBN необязателен, но в пределах библиотеки не повторяется
+CREATE UNIQUE INDEX IF NOT EXISTS books_tenant_isbn_key ON books (tenant_id, isbn) WHERE isbn IS NOT NULL;
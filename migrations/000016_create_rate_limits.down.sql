@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS rate_limit_refill(DOUBLE PRECISION, INTERVAL, DOUBLE PRECISION, DOUBLE PRECISION);
+DROP TABLE IF EXISTS rate_limits;
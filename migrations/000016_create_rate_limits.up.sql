@@ -0,0 +1,17 @@
+-- Корзины ограничителя частоты запросов, общие для всех экземпляров сервиса.
+-- key — '<клиент>' или '<маршрут>|<клиент>', клиент — 'ip:…', 'user:…', 'apikey:…'
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(300) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    -- пропущен ли последний запрос: upsert не может вернуть это иначе
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_idx ON rate_limits (updated_at);
+
+-- Пополнение корзины, как ratelimit.Bucket.Refill
+CREATE OR REPLACE FUNCTION rate_limit_refill(tokens DOUBLE PRECISION, elapsed INTERVAL, capacity DOUBLE PRECISION, rate DOUBLE PRECISION)
+RETURNS DOUBLE PRECISION
+LANGUAGE SQL IMMUTABLE
+AS $$ SELECT LEAST(capacity, tokens + GREATEST(0, EXTRACT(EPOCH FROM elapsed)) * rate) $$;
@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS books_bump_catalog_version ON books;
+DROP TRIGGER IF EXISTS genres_bump_catalog_version ON genres;
+DROP TRIGGER IF EXISTS authors_bump_catalog_version ON authors;
+DROP FUNCTION IF EXISTS bump_catalog_version();
+DROP TABLE IF EXISTS catalog_versions;
+
+DROP TRIGGER IF EXISTS books_set_updated_at ON books;
+DROP TRIGGER IF EXISTS genres_set_updated_at ON genres;
+DROP TRIGGER IF EXISTS authors_set_updated_at ON authors;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+ALTER TABLE books DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE genres DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE authors DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,67 @@
+-- Время последнего изменения строк каталога. clock_timestamp(), а не начало
+-- транзакции: значение берётся под блокировкой строки и не идёт назад.
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE genres ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.updated_at := GREATEST(OLD.updated_at, clock_timestamp());
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER authors_set_updated_at BEFORE UPDATE ON authors FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+CREATE TRIGGER genres_set_updated_at BEFORE UPDATE ON genres FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+CREATE TRIGGER books_set_updated_at BEFORE UPDATE ON books FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Версия коллекции целиком: max(updated_at) по строкам не замечает удалений,
+-- поэтому каждое изменение строки увеличивает счётчик коллекции. Блокировка строки
+-- счётчика упорядочивает версии так же, как коммиты.
+CREATE TABLE IF NOT EXISTS catalog_versions (
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id),
+    collection VARCHAR(20) NOT NULL,
+    version BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, collection)
+);
+
+INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+SELECT t.id, c.collection, 1, CURRENT_TIMESTAMP
+FROM tenants t CROSS JOIN (VALUES ('authors'), ('genres'), ('books')) AS c(collection)
+ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION bump_catalog_version() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    row_tenant INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_tenant := OLD.tenant_id;
+    ELSE
+        row_tenant := NEW.tenant_id;
+    END IF;
+
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (row_tenant, TG_TABLE_NAME, 1, clock_timestamp())
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = catalog_versions.version + 1,
+        updated_at = GREATEST(catalog_versions.updated_at, EXCLUDED.updated_at);
+    RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER authors_bump_catalog_version AFTER INSERT OR UPDATE OR DELETE ON authors FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();
+CREATE TRIGGER genres_bump_catalog_version AFTER INSERT OR UPDATE OR DELETE ON genres FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();
+CREATE TRIGGER books_bump_catalog_version AFTER INSERT OR UPDATE OR DELETE ON books FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();
+
+-- Как у самих таблиц каталога (миграция 000014); включаем после заполнения,
+-- иначе вставка без app.tenant_id не прошла бы политику
+ALTER TABLE catalog_versions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE catalog_versions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON catalog_versions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
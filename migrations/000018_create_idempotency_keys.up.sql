@@ -0,0 +1,13 @@
+-- Ответы на запросы с Idempotency-Key, общие для всех экземпляров сервиса.
+-- key — '<библиотека>|<клиент>|<метод> <маршрут>|<ключ клиента>'.
+-- status IS NULL — первый запрос ещё выполняется, expires_at тогда — срок блокировки.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(600) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    status INTEGER,
+    content_type VARCHAR(100),
+    body BYTEA,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,13 @@
+-- Роль общая для всего кластера (у неё могут быть права в других базах),
+-- поэтому здесь отзываются только права в этой базе, а сама роль остаётся.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'library_app') THEN
+        ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM library_app;
+        ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM library_app;
+        REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM library_app;
+        REVOKE ALL ON ALL TABLES IN SCHEMA public FROM library_app;
+        REVOKE USAGE ON SCHEMA public FROM library_app;
+    END IF;
+END
+$$;
@@ -0,0 +1,20 @@
+-- Роль сервиса. RLS из 000014 не действует на суперпользователя и роли с
+-- BYPASSRLS, а владелец таблиц может его выключить, поэтому сервис подключается
+-- под library_app: не владелец, только права на данные. Пароль и LOGIN задаёт администратор
+-- (docker-compose — скрипт deploy/postgres/10-app-role.sh), здесь роль
+-- только создаётся, если её ещё нет, и получает права на данные.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'library_app') THEN
+        CREATE ROLE library_app NOLOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOBYPASSRLS;
+    END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO library_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO library_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO library_app;
+
+-- таблицы следующих миграций создаёт та же роль, что и эту, — права выдадутся сами
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO library_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO library_app;
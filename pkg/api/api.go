@@ -2,11 +2,18 @@ package api
 
 import (
 	"leti/pkg/auth"
+	"leti/pkg/idempotency"
+	"leti/pkg/metrics"
+	"leti/pkg/ratelimit"
 	"leti/pkg/service"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // одна из самах популярных библиотек в go для роутинга
@@ -17,17 +24,64 @@ type api struct {
 	srv        *service.Service
 	logger     *slog.Logger
 	jwtService *auth.JWTService
+	oidc       *auth.OIDCProvider
+	// tenantDomain — базовый домен, поддомены которого соответствуют библиотекам
+	tenantDomain string
+	// requestTimeout и routeTimeouts ограничивают время обработки запроса (см. chain.go)
+	requestTimeout time.Duration
+	routeTimeouts  map[string]time.Duration
+	metrics        *metrics.Metrics
+	tracer         trace.Tracer
+	limiter        *ratelimit.Limiter
+	// cacheControl — Cache-Control по коллекциям каталога (см. conditional.go);
+	// заменяется целиком при перечитывании конфигурации
+	cacheControl atomic.Pointer[map[string]string]
+	idempotency  *idempotency.Guard
 }
 
-func New(router *mux.Router, srv *service.Service, logger *slog.Logger, at *auth.JWTService) *api {
-	return &api{r: router, srv: srv, logger: logger, jwtService: at}
+// Option подключает необязательные возможности API.
+type Option func(*api)
+
+// WithOIDC включает вход через внешний OpenID Connect провайдер.
+func WithOIDC(provider *auth.OIDCProvider) Option {
+	return func(a *api) { a.oidc = provider }
+}
+
+// WithTenantDomain включает выбор библиотеки по поддомену: запросы к
+// <slug>.<domain> работают с данными библиотеки slug.
+func WithTenantDomain(domain string) Option {
+	return func(a *api) { a.tenantDomain = domain }
+}
+
+// WithMetrics включает учёт запросов по шаблону маршрута в метриках Prometheus.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(a *api) { a.metrics = m }
+}
+
+// WithTracerProvider задаёт, куда пишутся спаны запросов (по умолчанию — глобальный провайдер otel).
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(a *api) { a.tracer = tp.Tracer(tracerName) }
+}
+
+func New(router *mux.Router, srv *service.Service, logger *slog.Logger, at *auth.JWTService, opts ...Option) *api {
+	a := &api{r: router, srv: srv, logger: logger, jwtService: at, requestTimeout: defaultRequestTimeout,
+		tracer: otel.Tracer(tracerName)}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 func (api *api) RegistreRoutes() {
+	api.useChain()
+	api.r.Use(api.resolveTenant)
 	api.HandleAuth()
 	api.HandleBooks()
 	api.HandleAuthors()
 	api.HandleGenres()
+	api.HandleOAuth()
+	api.HandleAdmin()
+	api.HandleMe()
 }
 
 func (api *api) HandleBooks() {
@@ -38,24 +92,91 @@ func (api *api) HandleBooks() {
 
 	// Приватные операции - с middleware
 	privateBooks := api.r.PathPrefix("/api/books").Subrouter()
-	privateBooks.Use(api.middleware)
-	privateBooks.HandleFunc("", api.createBook).Methods(http.MethodPost)
+	privateBooks.Use(api.middleware, api.requireScope(auth.ScopeBooksWrite))
+	privateBooks.HandleFunc("", api.idempotent(api.createBook)).Methods(http.MethodPost)
 	privateBooks.HandleFunc("", api.deleteBook).Methods(http.MethodDelete).Queries("id", "{id}")
 	privateBooks.HandleFunc("", api.updateBook).Methods(http.MethodPatch).Queries("id", "{id}")
 }
 
 func (api *api) HandleAuthors() {
 	api.r.HandleFunc("/api/authors", api.getAuthors).Methods(http.MethodGet)
-	api.r.HandleFunc("/api/authors", api.postAuthors).Methods(http.MethodPost)
+	api.r.HandleFunc("/api/authors", api.idempotent(api.postAuthors)).Methods(http.MethodPost)
 }
 
 func (api *api) HandleGenres() {
-	api.r.HandleFunc("/api/genres", api.postGenres).Methods(http.MethodPost)
+	api.r.HandleFunc("/api/genres", api.idempotent(api.postGenres)).Methods(http.MethodPost)
 	api.r.HandleFunc("/api/genres", api.getGenres).Methods(http.MethodGet)
 }
 
 func (api *api) HandleAuth() {
 	api.r.HandleFunc("/api/auth/login", api.login).Methods(http.MethodPost)
+	api.r.HandleFunc("/api/auth/login/2fa", api.loginSecondFactor).Methods(http.MethodPost)
+	api.r.HandleFunc("/api/auth/password/reset", api.requestPasswordReset).Methods(http.MethodPost)
+	api.r.HandleFunc("/api/auth/password/reset/confirm", api.confirmPasswordReset).Methods(http.MethodPost)
+
+	password := api.r.PathPrefix("/api/auth/password").Subrouter()
+	password.Use(api.middleware, api.requireScope(auth.ScopeAccount))
+	password.HandleFunc("", api.changePassword).Methods(http.MethodPost)
+
+	// Управление 2FA доступно и с токеном, выданным только для подключения
+	mfa := api.r.PathPrefix("/api/auth/2fa").Subrouter()
+	mfa.Use(api.middleware, api.requireScope(auth.ScopeMFAEnroll))
+	mfa.HandleFunc("/enroll", api.enrollTOTP).Methods(http.MethodPost)
+	mfa.HandleFunc("/verify", api.verifyTOTP).Methods(http.MethodPost)
+	mfa.HandleFunc("", api.disableTOTP).Methods(http.MethodDelete)
+
+	// Вход по паролю остаётся запасным вариантом, даже если OIDC включён
+	if api.oidc != nil {
+		api.r.HandleFunc("/api/auth/oidc/login", api.oidcLogin).Methods(http.MethodGet)
+		api.r.HandleFunc("/api/auth/oidc/callback", api.oidcCallback).Methods(http.MethodGet)
+	}
+}
+
+// Админские операции - только с областью admin
+func (api *api) HandleAdmin() {
+	// Настройки всей платформы — только администраторам библиотеки по умолчанию
+	platform := api.r.PathPrefix("/api/admin").Subrouter()
+	platform.Use(api.middleware, api.requireScope(auth.ScopeAdmin), api.requirePlatformAdmin)
+	platform.HandleFunc("/tenants", api.idempotent(api.createTenant)).Methods(http.MethodPost)
+	platform.HandleFunc("/tenants", api.listTenants).Methods(http.MethodGet)
+	platform.HandleFunc("/oauth-clients", api.createOAuthClient).Methods(http.MethodPost)
+	platform.HandleFunc("/oauth-clients", api.listOAuthClients).Methods(http.MethodGet)
+	platform.HandleFunc("/mfa/required-roles", api.getMFARequiredRoles).Methods(http.MethodGet)
+	platform.HandleFunc("/mfa/required-roles", api.setMFARequiredRoles).Methods(http.MethodPut)
+	platform.HandleFunc("/lockout/unlock", api.unlockLogin).Methods(http.MethodPost)
+
+	// Пользователи и API-ключи — в пределах библиотеки администратора
+	admin := api.r.PathPrefix("/api/admin").Subrouter()
+	admin.Use(api.middleware, api.requireScope(auth.ScopeAdmin))
+	admin.HandleFunc("/api-keys", api.createAPIKey).Methods(http.MethodPost)
+	admin.HandleFunc("/api-keys", api.listAPIKeys).Methods(http.MethodGet)
+	admin.HandleFunc("/api-keys", api.revokeAPIKey).Methods(http.MethodDelete).Queries("id", "{id}")
+	admin.HandleFunc("/users", api.getUser).Methods(http.MethodGet).Queries("id", "{id}")
+	admin.HandleFunc("/users", api.listUsers).Methods(http.MethodGet)
+	admin.HandleFunc("/users", api.deleteUser).Methods(http.MethodDelete).Queries("id", "{id}")
+	admin.HandleFunc("/users/role", api.changeUserRole).Methods(http.MethodPut).Queries("id", "{id}")
+	admin.HandleFunc("/users/disable", api.disableUser).Methods(http.MethodPost).Queries("id", "{id}")
+	admin.HandleFunc("/users/enable", api.enableUser).Methods(http.MethodPost).Queries("id", "{id}")
+	admin.HandleFunc("/users/reset-password", api.forcePasswordReset).Methods(http.MethodPost).Queries("id", "{id}")
+}
+
+// Текущий пользователь — для любого аутентифицированного клиента
+func (api *api) HandleMe() {
+	me := api.r.PathPrefix("/api/me").Subrouter()
+	me.Use(api.middleware)
+	me.HandleFunc("", api.me).Methods(http.MethodGet)
+}
+
+// Сервер авторизации OAuth2 для сторонних приложений
+func (api *api) HandleOAuth() {
+	api.r.HandleFunc("/oauth/authorize", api.authorizeForm).Methods(http.MethodGet)
+	api.r.HandleFunc("/oauth/authorize", api.authorizeDecision).Methods(http.MethodPost)
+	api.r.HandleFunc("/oauth/token", api.oauthToken).Methods(http.MethodPost)
+	api.r.HandleFunc("/oauth/introspect", api.oauthIntrospect).Methods(http.MethodPost)
+
+	userInfo := api.r.PathPrefix("/api/oauth/userinfo").Subrouter()
+	userInfo.Use(api.middleware, api.requireScope(auth.ScopeProfileRead))
+	userInfo.HandleFunc("", api.userInfo).Methods(http.MethodGet)
 }
 
 func (api *api) ListenAndServe(addr string) error {
@@ -203,11 +203,11 @@ func marshal(t *testing.T, v interface{}) []byte {
 	return data
 }
 
-func newTestAPI(srv *service.Service) *mux.Router {
+func newTestAPI(srv *service.Service, opts ...Option) *mux.Router {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	jwtService := auth.NewJWTService(testJWTSecret)
 	r := mux.NewRouter()
-	apiInst := New(r, srv, logger, jwtService)
+	apiInst := New(r, srv, logger, jwtService, opts...)
 	apiInst.RegistreRoutes()
 	return r
 }
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys_AdminFlow(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	adminID := repo.AddUser(models.User{Username: "admin", Role: auth.RoleAdmin})
+	ts := httptest.NewServer(newTestAPI(service.NewService(repo)))
+	defer ts.Close()
+
+	adminToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+
+	body := marshal(t, dto.CreateAPIKeyRequest{Name: "import", Scopes: []string{auth.ScopeBooksWrite}})
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/admin/api-keys", adminToken, body))
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created dto.CreateAPIKeyResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	require.NotEmpty(t, created.Key)
+	createAuthor(t, ts.URL, "Dostoevsky")
+	createGenre(t, ts.URL, "Novel")
+
+	createBookWithKey := func() *http.Response {
+		req := newRequest(t, http.MethodPost, ts.URL+"/api/books", marshal(t, dto.CreateBookRequest{
+			Name: "Idiot", AuthorID: 1, GenreID: 1, Price: 100,
+		}))
+		req.Header.Set("Authorization", "ApiKey "+created.Key)
+		return doRequest(t, req)
+	}
+
+	// ключ работает там же, где и Bearer
+	resp = createBookWithKey()
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// но без области admin в админку не пускает
+	listReq := newRequest(t, http.MethodGet, ts.URL+"/api/admin/api-keys", nil)
+	listReq.Header.Set("Authorization", "ApiKey "+created.Key)
+	resp = doRequest(t, listReq)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodDelete, ts.URL+"/api/admin/api-keys?id="+strconv.Itoa(created.ID), adminToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = createBookWithKey()
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
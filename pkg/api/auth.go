@@ -1,32 +1,89 @@
 package api
 
 import (
-	"context"
+	"errors"
+	"leti/pkg/auth"
+	"leti/pkg/service"
+	"leti/pkg/tenant"
 	"net/http"
 	"strings"
 )
 
+// RightAuth принимает `Authorization: Bearer <jwt>` или `Authorization: ApiKey <key>`
+// и в обоих случаях кладёт в контекст один и тот же *auth.Principal.
 func (api *api) RightAuth(w http.ResponseWriter, r *http.Request) bool {
 	authHeader := r.Header.Get("Authorization")
-	api.logger.Info("Auth header", "header", authHeader)
 
 	const bearerPrefix = "Bearer "
-	if !strings.HasPrefix(authHeader, bearerPrefix) {
-		http.Error(w, "invalid Authorization header format", http.StatusUnauthorized)
-		return false
-	}
+	var principal *auth.Principal
+	switch {
+	case strings.HasPrefix(authHeader, bearerPrefix):
+		tokenStr := strings.TrimPrefix(authHeader, bearerPrefix)
 
-	tokenStr := strings.TrimPrefix(authHeader, bearerPrefix)
-	api.logger.Info("Token to parse", "token", tokenStr)
+		claims, err := api.jwtService.ParseToken(tokenStr)
+		if err != nil {
+			api.logger.ErrorContext(r.Context(), "JWT parse error", "error", err)
+			writeProblem(w, r, http.StatusUnauthorized, "invalid token")
+			return false
+		}
+		// Токен client credentials выдан приложению, а не пользователю — сверять нечего
+		if claims.UserID != 0 {
+			if err := api.srv.CheckSession(r.Context(), claims.TokenSubject()); err != nil {
+				if errors.Is(err, service.ErrAccountDisabled) {
+					writeProblem(w, r, http.StatusForbidden, "account disabled")
+					return false
+				}
+				writeProblem(w, r, http.StatusUnauthorized, "session expired")
+				return false
+			}
+		}
+		principal = auth.NewPrincipalFromClaims(claims)
 
-	claims, err := api.jwtService.ParseToken(tokenStr)
-	if err != nil {
-		api.logger.Error("JWT parse error", "error", err)
-		http.Error(w, "invalid token", http.StatusUnauthorized)
+	case strings.HasPrefix(authHeader, auth.APIKeyHeaderScheme):
+		if p, ok := verifiedAPIKey(r.Context()); ok {
+			principal = p
+			break
+		}
+		key := strings.TrimPrefix(authHeader, auth.APIKeyHeaderScheme)
+		p, err := api.srv.AuthenticateAPIKey(r.Context(), key)
+		if err != nil {
+			api.logger.ErrorContext(r.Context(), "API key auth error", "error", err)
+			writeProblem(w, r, http.StatusUnauthorized, "invalid api key")
+			return false
+		}
+		principal = p
+
+	default:
+		writeProblem(w, r, http.StatusUnauthorized, "invalid Authorization header format")
 		return false
 	}
 
-	ctx := context.WithValue(r.Context(), "user_claims", claims)
-	*r = *r.WithContext(ctx)
+	// Токен одной библиотеки не действует на поддомене другой. Дальше запрос
+	// работает с данными библиотеки клиента, даже если поддомена не было.
+	if id, ok := tenant.IDFromContext(r.Context()); ok && id != principal.TenantID {
+		writeProblem(w, r, http.StatusForbidden, "token belongs to another tenant")
+		return false
+	}
+	ctx := tenant.WithID(r.Context(), principal.TenantID)
+	*r = *r.WithContext(auth.WithPrincipal(ctx, principal))
 	return true
 }
+
+// requireScope пропускает запрос дальше, только если у клиента есть нужная область.
+// Ставится после api.middleware, который кладёт Principal в контекст.
+func (api *api) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				writeProblem(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			if !principal.HasScope(scope) {
+				writeProblem(w, r, http.StatusForbidden, "insufficient scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"leti/pkg/logging"
+	"leti/pkg/tracing"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+const tracerName = "leti/pkg/api"
+
+// defaultRequestTimeout меньше WriteTimeout сервера, чтобы клиент успел получить 503,
+// а не оборванное соединение.
+const defaultRequestTimeout = 5 * time.Second
+
+// WithRequestTimeout задаёт время на обработку запроса по умолчанию.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(a *api) { a.requestTimeout = d }
+}
+
+// WithRouteTimeout задаёт отдельный таймаут маршруту; path — шаблон пути,
+// как при регистрации ("/api/books", "/oauth/token").
+func WithRouteTimeout(path string, d time.Duration) Option {
+	return func(a *api) {
+		if a.routeTimeouts == nil {
+			a.routeTimeouts = make(map[string]time.Duration)
+		}
+		a.routeTimeouts[path] = d
+	}
+}
+
+// ParseRouteTimeouts разбирает строку вида "/api/admin/tenants=30s,/oauth/token=2s"
+// в таймауты для WithRouteTimeout.
+func ParseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		path, value, ok := strings.Cut(pair, "=")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route timeout %q", pair)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid route timeout %q", pair)
+		}
+		timeouts[path] = d
+	}
+	return timeouts, nil
+}
+
+// useChain подключает сквозные middleware ко всем маршрутам, в том числе
+// к ответам 404/405, для которых mux свои middleware не вызывает.
+func (api *api) useChain() {
+	api.r.Use(api.requestID, api.trace, api.accessLog, api.recoverPanic, api.timeout, api.rateLimit)
+	api.r.NotFoundHandler = api.requestID(api.accessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, "no such endpoint")
+	})))
+	api.r.MethodNotAllowedHandler = api.requestID(api.accessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	})))
+}
+
+// requestID берёт X-Request-ID от клиента или прокси, а если его нет — генерирует.
+// ID попадает в заголовок ответа и во все записи лога, сделанные с контекстом запроса.
+func (api *api) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := logging.WithAttrs(r.Context(), slog.String("request_id", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID не пускает в логи произвольные строки из заголовка.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder запоминает статус и размер ответа для access-лога.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// recorderFor переиспользует statusRecorder внешнего middleware, чтобы все
+// звенья цепочки видели один и тот же статус ответа.
+func recorderFor(w http.ResponseWriter) *statusRecorder {
+	if rec, ok := w.(*statusRecorder); ok {
+		return rec
+	}
+	return &statusRecorder{ResponseWriter: w}
+}
+
+// Unwrap нужен http.ResponseController (Flush, дедлайны записи).
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (api *api) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := recorderFor(w)
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		latency := time.Since(start)
+		route := routeTemplate(r)
+		api.metrics.ObserveHTTP(r.Method, route, status, latency)
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		api.logger.LogAttrs(r.Context(), level, "HTTP request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("latency", latency),
+			slog.String("ip", clientIP(r)),
+		)
+	})
+}
+
+// trace открывает серверный спан на запрос. Имя спана — шаблон маршрута, а не
+// сырой путь; родитель берётся из заголовка traceparent, если клиент его прислал.
+// trace_id попадает в записи лога этого запроса, включая access-лог.
+func (api *api) trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(r)
+		ctx, span := api.tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.IsValid() {
+			ctx = logging.WithAttrs(ctx, slog.String("trace_id", sc.TraceID().String()))
+		}
+		rec := recorderFor(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// recoverPanic превращает панику хендлера в 500 вместо оборванного соединения.
+func (api *api) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recorderFor(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			// ErrAbortHandler — штатный способ оборвать ответ, net/http его не логирует
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			api.logger.ErrorContext(r.Context(), "Panic in handler", "panic", v, "stack", string(debug.Stack()))
+			if rec.status == 0 {
+				writeProblem(rec, r, http.StatusInternalServerError, "internal server error")
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// timeout ограничивает время обработки через контекст: его получают сервис и
+// запросы к БД. Таймаут берётся из WithRouteTimeout для маршрута или по умолчанию.
+func (api *api) timeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, ok := api.routeTimeouts[routeTemplate(r)]
+		if !ok {
+			d = api.requestTimeout
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return tpl
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"leti/pkg/auth"
+	"leti/pkg/logging"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer — буфер для логов, в который пишут горутины сервера.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newLoggedTestAPI — как newTestAPI, но пишет лог (с уровня Debug, как в main) в буфер.
+func newLoggedTestAPI(t *testing.T, srv *service.Service, opts ...Option) (*mux.Router, *syncBuffer) {
+	t.Helper()
+	logs := &syncBuffer{}
+	logger, err := logging.New(logs, logging.Config{Level: slog.LevelDebug})
+	require.NoError(t, err)
+	r := mux.NewRouter()
+	New(r, srv, logger, auth.NewJWTService(testJWTSecret), opts...).RegistreRoutes()
+	return r, logs
+}
+
+func TestChain_RequestIDAndAccessLog(t *testing.T) {
+	r, logs := newLoggedTestAPI(t, service.NewService(&fake.FakeRepo{}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/books", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	id := w.Header().Get(requestIDHeader)
+	require.Len(t, id, 32)
+	require.Contains(t, logs.String(), "request_id="+id)
+	require.Contains(t, logs.String(), `msg="HTTP request" method=GET path=/api/books route=/api/books status=200 bytes=3`)
+
+	// ID от прокси сохраняется, мусор в заголовке заменяется своим
+	req := httptest.NewRequest(http.MethodGet, "/api/genres", nil)
+	req.Header.Set(requestIDHeader, "edge-42")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, "edge-42", w.Header().Get(requestIDHeader))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/genres", nil)
+	req.Header.Set(requestIDHeader, "bad id\nlevel=ERROR")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.NotContains(t, w.Header().Get(requestIDHeader), " ")
+
+	// неизвестный путь тоже логируется и отвечает problem+json
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	require.NotEmpty(t, w.Header().Get(requestIDHeader))
+	require.Contains(t, logs.String(), "path=/nope route=\"\" status=404")
+}
+
+func TestChain_RecoversFromPanic(t *testing.T) {
+	r, logs := newLoggedTestAPI(t, service.NewService(&fake.FakeRepo{}))
+	r.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	id := w.Header().Get(requestIDHeader)
+	var panicLine string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "Panic in handler") {
+			panicLine = line
+		}
+	}
+	require.Contains(t, panicLine, "panic=boom")
+	require.Contains(t, panicLine, "request_id="+id)
+	require.Contains(t, logs.String(), "status=500")
+}
+
+func TestChain_RouteTimeout(t *testing.T) {
+	r, _ := newLoggedTestAPI(t, service.NewService(&fake.FakeRepo{}), WithRequestTimeout(time.Minute), WithRouteTimeout("/slow", 10*time.Millisecond))
+	var deadlines sync.Map
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		deadlines.Store(r.URL.Path, time.Until(deadline))
+		<-r.Context().Done()
+		api := &api{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+		api.writeError(w, r, "slow handler", r.Context().Err())
+	}
+	r.HandleFunc("/slow", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	d, _ := deadlines.Load("/slow")
+	require.Less(t, d.(time.Duration), time.Second)
+
+	r.HandleFunc("/default", func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		deadlines.Store(r.URL.Path, time.Until(deadline))
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/default", nil))
+	d, _ = deadlines.Load("/default")
+	require.Greater(t, d.(time.Duration), 30*time.Second)
+}
+
+func TestParseRouteTimeouts(t *testing.T) {
+	timeouts, err := ParseRouteTimeouts(" /api/admin/tenants=30s, /oauth/token=1500ms,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Duration{"/api/admin/tenants": 30 * time.Second, "/oauth/token": 1500 * time.Millisecond}, timeouts)
+
+	timeouts, err = ParseRouteTimeouts("")
+	require.NoError(t, err)
+	require.Empty(t, timeouts)
+
+	for _, s := range []string{"/api/books", "api/books=1s", "/api/books=soon", "/api/books=0s", "/api/books=-1s"} {
+		_, err := ParseRouteTimeouts(s)
+		require.Error(t, err, s)
+	}
+}
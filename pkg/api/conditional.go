@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"leti/pkg/models"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultCacheControl — кэшировать можно, но перед каждым использованием
+// сверяться с сервером: с ETag это обычно дешёвый ответ 304.
+const defaultCacheControl = "no-cache"
+
+// SetCacheControl задаёт заголовок Cache-Control для чтения коллекций каталога
+// (ключи — models.CollectionBooks и т.д.; для книги по id действует политика
+// books). Политики заменяются все сразу и без перезапуска — так они задаются и
+// при старте, и при перечитывании конфигурации. Коллекции без политики получают
+// defaultCacheControl.
+func (a *api) SetCacheControl(policies map[string]string) {
+	policies = maps.Clone(policies)
+	a.cacheControl.Store(&policies)
+}
+
+// catalogETag — ETag коллекции: версия меняется при любом изменении её строк.
+func catalogETag(v models.CatalogVersion) string {
+	return fmt.Sprintf(`"v%d"`, v.Version)
+}
+
+// bookETag — ETag одной книги по времени её последнего изменения.
+func bookETag(b models.Book) string {
+	return fmt.Sprintf(`"%d-%d"`, b.ID, b.UpdatedAt.UnixMicro())
+}
+
+// notModified ставит валидаторы и Cache-Control и, если у клиента уже эта
+// версия, отвечает 304. true — ответ отправлен, тело строить не нужно.
+func (api *api) notModified(w http.ResponseWriter, r *http.Request, collection, etag string, lastModified time.Time) bool {
+	h := w.Header()
+	var policy string
+	if policies := api.cacheControl.Load(); policies != nil {
+		policy = (*policies)[collection]
+	}
+	if policy == "" {
+		policy = defaultCacheControl
+	}
+	h.Set("Cache-Control", policy)
+	h.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if !fresh(r, etag, lastModified) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// fresh проверяет условия запроса по RFC 9110: If-None-Match, если он есть,
+// важнее If-Modified-Since.
+func fresh(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Values("If-None-Match"); len(inm) > 0 {
+		return etagMatches(strings.Join(inm, ","), etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// в заголовке секунды, доли отбрасываем
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches — слабое сравнение (W/ не учитывается), как требует RFC для If-None-Match.
+func etagMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"io"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalGet_Collections(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	ctx := context.Background()
+	authorID, err := repo.NewAuthor(ctx, models.Author{Author: "Толстой"})
+	require.NoError(t, err)
+	genreID, err := repo.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+	r := mux.NewRouter()
+	a := New(r, service.NewService(repo), slog.New(slog.NewTextHandler(io.Discard, nil)), auth.NewJWTService(testJWTSecret))
+	a.SetCacheControl(map[string]string{models.CollectionGenres: "public, max-age=300"})
+	a.RegistreRoutes()
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/books", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.Equal(t, `"v0"`, etag)
+	require.Empty(t, w.Header().Get("Last-Modified"), "пустую коллекцию ещё не меняли")
+	require.Equal(t, defaultCacheControl, w.Header().Get("Cache-Control"))
+
+	w = get("/api/books", http.Header{"If-None-Match": {etag}})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+	require.Equal(t, etag, w.Header().Get("ETag"))
+
+	// новая книга меняет версию
+	bookID, err := repo.NewBook(ctx, models.Book{Name: "Война и мир", Author_id: authorID, Genre_id: genreID, Price: 100})
+	require.NoError(t, err)
+	w = get("/api/books", http.Header{"If-None-Match": {etag}})
+	require.Equal(t, http.StatusOK, w.Code)
+	etag = w.Header().Get("ETag")
+	require.Equal(t, `"v1"`, etag)
+	lastModified := w.Header().Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+
+	require.Equal(t, http.StatusNotModified, get("/api/books", http.Header{"If-Modified-Since": {lastModified}}).Code)
+	require.Equal(t, http.StatusNotModified, get("/api/books", http.Header{"If-None-Match": {`"x", W/` + etag}}).Code)
+	require.Equal(t, http.StatusNotModified, get("/api/books", http.Header{"If-None-Match": {"*"}}).Code)
+	// If-None-Match важнее If-Modified-Since
+	require.Equal(t, http.StatusOK, get("/api/books", http.Header{
+		"If-None-Match":     {`"stale"`},
+		"If-Modified-Since": {lastModified},
+	}).Code)
+
+	// удаление тоже меняет версию, хотя max(updated_at) строк бы не сдвинулся
+	require.NoError(t, repo.DeleteBookById(ctx, bookID))
+	require.Equal(t, http.StatusOK, get("/api/books", http.Header{"If-None-Match": {etag}}).Code)
+
+	// у коллекций свои версии и политики
+	w = get("/api/genres", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	require.Equal(t, http.StatusNotModified, get("/api/genres", http.Header{"If-None-Match": {w.Header().Get("ETag")}}).Code)
+	w = get("/api/authors", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `"v1"`, w.Header().Get("ETag"))
+
+	// перечитанная конфигурация заменяет политики целиком
+	a.SetCacheControl(map[string]string{models.CollectionAuthors: "private, max-age=60"})
+	require.Equal(t, "private, max-age=60", get("/api/authors", nil).Header().Get("Cache-Control"))
+	require.Equal(t, defaultCacheControl, get("/api/genres", nil).Header().Get("Cache-Control"))
+}
+
+func TestConditionalGet_Book(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	ctx := context.Background()
+	authorID, err := repo.NewAuthor(ctx, models.Author{Author: "Чехов"})
+	require.NoError(t, err)
+	genreID, err := repo.NewGenre(ctx, models.Genre{Genre: "Рассказ"})
+	require.NoError(t, err)
+	bookID, err := repo.NewBook(ctx, models.Book{Name: "Дама с собачкой", Author_id: authorID, Genre_id: genreID, Price: 50})
+	require.NoError(t, err)
+	r := newTestAPI(service.NewService(repo))
+
+	path := "/api/book?id=" + strconv.Itoa(bookID)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, w.Header().Get("Last-Modified"))
+	require.Equal(t, http.StatusNotModified, get(etag).Code)
+
+	time.Sleep(time.Microsecond)
+	require.NoError(t, repo.UpdateBook(ctx, bookID, models.BookUpdate{Price: ptr(60)}))
+	w = get(etag)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestFresh_IfModifiedSince(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	req := func(ims string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-Modified-Since", ims)
+		return r
+	}
+
+	require.True(t, fresh(req(modified.Format(http.TimeFormat)), `"v1"`, modified), "доли секунды не учитываются")
+	require.False(t, fresh(req(modified.Add(-time.Second).Format(http.TimeFormat)), `"v1"`, modified))
+	require.False(t, fresh(req("not a date"), `"v1"`, modified))
+	require.False(t, fresh(req(modified.Format(http.TimeFormat)), `"v0"`, time.Time{}))
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"leti/pkg/validation"
+	"net/http"
+	"strings"
+)
+
+// maxBodyBytes — предел тела JSON-запроса. Самый большой запрос (OAuth-клиент
+// со списком redirect_uri) занимает несколько килобайт.
+const maxBodyBytes = 1 << 20
+
+// decodeJSON строго читает тело запроса в dst и проверяет его по тегам validate.
+// Неизвестные поля, данные после JSON-объекта и слишком большое тело отклоняются.
+// При ошибке ответ уже записан, и хендлер должен просто выйти.
+func (api *api) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		writeDecodeError(w, r, err)
+		return false
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeDecodeError(w, r, err)
+			return false
+		}
+		writeProblem(w, r, http.StatusBadRequest, "request body must contain a single JSON object")
+		return false
+	}
+
+	if err := validation.Struct(dst); err != nil {
+		api.writeError(w, r, "Failed to validate request", err)
+		return false
+	}
+	return true
+}
+
+// decodeBody читает тело каталожного запроса в формате из Content-Type: JSON
+// (и запросы без заголовка), XML или MessagePack. На остальные типы — 415.
+func (api *api) decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	f, ok := requestFormat(r)
+	if !ok {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "unsupported Content-Type; use "+requestMediaTypes())
+		return false
+	}
+	if f == jsonFormat {
+		return api.decodeJSON(w, r, dst)
+	}
+
+	if err := f.decode(http.MaxBytesReader(w, r.Body, maxBodyBytes), dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeDecodeError(w, r, err)
+		case errors.Is(err, io.EOF):
+			writeProblem(w, r, http.StatusBadRequest, "request body must not be empty")
+		default:
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", strings.ToUpper(f.name), err))
+		}
+		return false
+	}
+
+	if err := validation.Struct(dst); err != nil {
+		api.writeError(w, r, "Failed to validate request", err)
+		return false
+	}
+	return true
+}
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		tooLarge  *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		writeProblem(w, r, http.StatusBadRequest, "invalid JSON")
+	case errors.As(err, &typeErr):
+		writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("invalid JSON: field %q must be %s", typeErr.Field, typeErr.Type))
+	case errors.Is(err, io.EOF):
+		writeProblem(w, r, http.StatusBadRequest, "request body must not be empty")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json не экспортирует тип для этой ошибки
+		writeProblem(w, r, http.StatusBadRequest, "invalid JSON: "+strings.TrimPrefix(err.Error(), "json: "))
+	default:
+		writeProblem(w, r, http.StatusBadRequest, "invalid JSON")
+	}
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"leti/pkg/models"
+	"time"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,notblank"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse — ключ без секрета, как он виден в списке.
+type APIKeyResponse struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse содержит открытый ключ — он показывается один раз.
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func FromAPIKeyModel(key models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+func FromAPIKeyModelsArray(keys []models.APIKey) []APIKeyResponse {
+	resp := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		resp[i] = FromAPIKeyModel(key)
+	}
+	return resp
+}
@@ -1,9 +1,13 @@
 package dto
 
-import "leti/pkg/models"
+import (
+	"encoding/xml"
+	"leti/pkg/models"
+)
 
 type CreateAuthorRequest struct {
-	Name string `json:"author" validate:"required,min=1"`
+	XMLName xml.Name `json:"-" xml:"author"`
+	Name    string   `json:"author" xml:"author" validate:"required,notblank"`
 }
 
 func (crq CreateAuthorRequest) ToAuthorModel() models.Author {
@@ -14,8 +18,9 @@ func (crq CreateAuthorRequest) ToAuthorModel() models.Author {
 
 // AuthorResponse — ответ с информацией об авторе
 type AuthorResponse struct {
-	ID   int    `json:"id"`
-	Name string `json:"author"`
+	XMLName xml.Name `json:"-" xml:"author"`
+	ID      int      `json:"id" xml:"id"`
+	Name    string   `json:"author" xml:"author"`
 }
 
 func FromAuthorModels(author models.Author) AuthorResponse {
@@ -1,12 +1,17 @@
 package dto
 
-import "leti/pkg/models"
+import (
+	"encoding/xml"
+	"leti/pkg/models"
+)
 
 type CreateBookRequest struct {
-	Name     string `json:"name" validate:"required"`
-	AuthorID int    `json:"author_id" validate:"required,min=1"`
-	GenreID  int    `json:"genre_id" validate:"required,min=1"`
-	Price    int    `json:"price" validate:"required,min=0"`
+	XMLName  xml.Name `json:"-" xml:"book"`
+	Name     string   `json:"name" xml:"name" validate:"required,notblank"`
+	AuthorID int      `json:"author_id" xml:"author_id" validate:"required,min=1"`
+	GenreID  int      `json:"genre_id" xml:"genre_id" validate:"required,min=1"`
+	Price    int      `json:"price" xml:"price" validate:"min=0"`
+	ISBN     string   `json:"isbn,omitempty" xml:"isbn,omitempty" validate:"omitempty,isbn"`
 }
 
 func (req CreateBookRequest) ToBookModel() models.Book {
@@ -15,36 +20,43 @@ func (req CreateBookRequest) ToBookModel() models.Book {
 		Author_id: req.AuthorID,
 		Genre_id:  req.GenreID,
 		Price:     req.Price,
+		ISBN:      req.ISBN,
 	}
 }
 
 type UpdateBookRequest struct {
-	Name  *string `json:"name,omitempty"`
-	Price *int    `json:"price,omitempty"`
+	XMLName xml.Name `json:"-" xml:"book"`
+	Name    *string  `json:"name,omitempty" xml:"name,omitempty" validate:"omitempty,notblank"`
+	Price   *int     `json:"price,omitempty" xml:"price,omitempty" validate:"omitempty,min=0"`
+	ISBN    *string  `json:"isbn,omitempty" xml:"isbn,omitempty" validate:"omitempty,isbn|len=0"` // "" стирает ISBN
 }
 
 func (req UpdateBookRequest) ToBookModel() models.BookUpdate {
 	return models.BookUpdate{
 		Name:  req.Name,
 		Price: req.Price,
+		ISBN:  req.ISBN,
 	}
 }
 
 type BookResponse struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	AuthorID int    `json:"author_id"`
-	GenreID  int    `json:"genre_id"`
-	Price    int    `json:"price"`
+	XMLName  xml.Name `json:"-" xml:"book"`
+	ID       int      `json:"id" xml:"id"`
+	Name     string   `json:"name" xml:"name"`
+	AuthorID int      `json:"author_id" xml:"author_id"`
+	GenreID  int      `json:"genre_id" xml:"genre_id"`
+	Price    int      `json:"price" xml:"price"`
+	ISBN     string   `json:"isbn,omitempty" xml:"isbn,omitempty"`
 }
 
 type BookWithAuthorResponse struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	AuthorID   int    `json:"author_id"`
-	AuthorName string `json:"author_name"`
-	GenreID    int    `json:"genre_id"`
-	Price      int    `json:"price"`
+	XMLName    xml.Name `json:"-" xml:"book"`
+	ID         int      `json:"id" xml:"id"`
+	Name       string   `json:"name" xml:"name"`
+	AuthorID   int      `json:"author_id" xml:"author_id"`
+	AuthorName string   `json:"author_name" xml:"author_name"`
+	GenreID    int      `json:"genre_id" xml:"genre_id"`
+	Price      int      `json:"price" xml:"price"`
 }
 
 func FromBookModel(book models.Book) BookResponse {
@@ -54,6 +66,7 @@ func FromBookModel(book models.Book) BookResponse {
 		AuthorID: book.Author_id,
 		GenreID:  book.Genre_id,
 		Price:    book.Price,
+		ISBN:     book.ISBN,
 	}
 }
 
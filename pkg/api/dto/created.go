@@ -0,0 +1,9 @@
+package dto
+
+import "encoding/xml"
+
+// CreatedResponse — ответ на создание записи каталога.
+type CreatedResponse struct {
+	XMLName xml.Name `json:"-" xml:"created"`
+	ID      int      `json:"id" xml:"id"`
+}
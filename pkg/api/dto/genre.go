@@ -1,9 +1,13 @@
 package dto
 
-import "leti/pkg/models"
+import (
+	"encoding/xml"
+	"leti/pkg/models"
+)
 
 type CreateGenreRequest struct {
-	Name string `json:"genre" validate:"required,min=1"`
+	XMLName xml.Name `json:"-" xml:"genre"`
+	Name    string   `json:"genre" xml:"genre" validate:"required,notblank"`
 }
 
 func (cgr CreateGenreRequest) ToGenreModel() models.Genre {
@@ -14,8 +18,9 @@ func (cgr CreateGenreRequest) ToGenreModel() models.Genre {
 
 // GenreResponse — ответ с информацией о жанре
 type GenreResponse struct {
-	ID   int    `json:"id"`
-	Name string `json:"genre"`
+	XMLName xml.Name `json:"-" xml:"genre"`
+	ID      int      `json:"id" xml:"id"`
+	Name    string   `json:"genre" xml:"genre"`
 }
 
 func GenreFromModels(genre models.Genre) GenreResponse {
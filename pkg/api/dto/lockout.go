@@ -0,0 +1,7 @@
+package dto
+
+// UnlockLoginRequest — снять блокировку входа по имени пользователя и/или IP.
+type UnlockLoginRequest struct {
+	Username string `json:"username,omitempty" validate:"required_without=IP"`
+	IP       string `json:"ip,omitempty" validate:"omitempty,ip"`
+}
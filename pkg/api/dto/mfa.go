@@ -0,0 +1,26 @@
+package dto
+
+// TOTPEnrollmentResponse — секрет для приложения-аутентификатора.
+// qr_png — PNG в base64, его можно показать как data:image/png;base64,...
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRPNG      []byte `json:"qr_png"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,notblank"`
+}
+
+// RecoveryCodesResponse — коды восстановления показываются один раз.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFARequiredRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+type MFARequiredRolesResponse struct {
+	Roles []string `json:"roles"`
+}
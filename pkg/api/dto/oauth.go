@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"leti/pkg/models"
+	"time"
+)
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,notblank"`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,url"`
+	Scopes       []string `json:"scopes" validate:"required,min=1"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1"`
+	Confidential bool     `json:"confidential"`
+}
+
+type OAuthClientResponse struct {
+	ID           int       `json:"id"`
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreateOAuthClientResponse содержит секрет клиента — он показывается один раз.
+type CreateOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// UserInfoResponse — данные читателя, доступные по области profile:read.
+type UserInfoResponse struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func FromOAuthClientModel(client models.OAuthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		GrantTypes:   client.GrantTypes,
+		Confidential: client.IsConfidential(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func FromOAuthClientModelsArray(clients []models.OAuthClient) []OAuthClientResponse {
+	resp := make([]OAuthClientResponse, len(clients))
+	for i, client := range clients {
+		resp[i] = FromOAuthClientModel(client)
+	}
+	return resp
+}
+
+func FromUserModel(user models.User) UserInfoResponse {
+	return UserInfoResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"leti/pkg/models"
+	"time"
+)
+
+type CreateTenantRequest struct {
+	Slug  string              `json:"slug" validate:"required,notblank"`
+	Name  string              `json:"name" validate:"required,notblank"`
+	Admin *TenantAdminRequest `json:"admin,omitempty"`
+}
+
+// TenantAdminRequest — первый администратор библиотеки; ссылка для установки
+// пароля уходит ему на email.
+type TenantAdminRequest struct {
+	Username string `json:"username" validate:"required,notblank"`
+	Email    string `json:"email" validate:"required,email"`
+}
+
+type TenantResponse struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func FromTenantModel(t models.Tenant) TenantResponse {
+	return TenantResponse{
+		ID:        t.ID,
+		Slug:      t.Slug,
+		Name:      t.Name,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+func FromTenantModelsArray(tenants []models.Tenant) []TenantResponse {
+	resp := make([]TenantResponse, 0, len(tenants))
+	for _, t := range tenants {
+		resp = append(resp, FromTenantModel(t))
+	}
+	return resp
+}
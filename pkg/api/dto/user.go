@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"leti/pkg/models"
+	"time"
+)
+
+// UserResponse — пользователь, как его видит администратор.
+type UserResponse struct {
+	ID         int        `json:"id"`
+	Username   string     `json:"username"`
+	Email      string     `json:"email,omitempty"`
+	Role       string     `json:"role"`
+	Disabled   bool       `json:"disabled"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type UserListResponse struct {
+	Users   []UserResponse `json:"users"`
+	Total   int            `json:"total"`
+	Page    int            `json:"page"`
+	PerPage int            `json:"per_page"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// MeResponse — текущий клиент запроса. Для токена client credentials
+// пользователя нет, и поля пользователя пустые.
+type MeResponse struct {
+	ID       int      `json:"id,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Scopes   []string `json:"scopes"`
+	AuthType string   `json:"auth_type"`
+	ClientID string   `json:"client_id,omitempty"`
+}
+
+func FromUserAdminModel(user models.User) UserResponse {
+	return UserResponse{
+		ID:         user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		Role:       user.Role,
+		Disabled:   user.DisabledAt != nil,
+		DisabledAt: user.DisabledAt,
+		CreatedAt:  user.CreatedAt,
+	}
+}
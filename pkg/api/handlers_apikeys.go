@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateAPIKey issues a new API key for the caller
+// @Summary Выпустить API-ключ
+// @Description Создаёт ключ для машинного клиента. Ключ возвращается один раз (требуется роль admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key body dto.CreateAPIKeyRequest true "Имя, области доступа и срок действия"
+// @Success 201 {object} dto.CreateAPIKeyResponse
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Ошибка валидации"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/api-keys [post]
+func (api *api) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateAPIKeyRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	key, plain, err := api.srv.CreateAPIKey(r.Context(), principal.UserID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		api.writeError(w, r, "Failed to create api key", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	resp := dto.CreateAPIKeyResponse{APIKeyResponse: dto.FromAPIKeyModel(key), Key: plain}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode api key", "error", err)
+	}
+}
+
+// ListAPIKeys returns API keys of the admin's tenant without secrets
+// @Summary Список API-ключей
+// @Description Возвращает ключи пользователей библиотеки администратора (без секретов) (требуется роль admin)
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.APIKeyResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/api-keys [get]
+func (api *api) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := api.srv.ListAPIKeys(r.Context())
+	if err != nil {
+		api.writeError(w, r, "Failed to list api keys", err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(dto.FromAPIKeyModelsArray(keys)); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode api keys", "error", err)
+	}
+}
+
+// RevokeAPIKey revokes API key by ID
+// @Summary Отозвать API-ключ
+// @Description Отзывает ключ по ID, дальнейшие запросы с ним получат 401. Ключ другой библиотеки — 404 (требуется роль admin)
+// @Tags admin
+// @Param id query int true "ID ключа"
+// @Success 204
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Ключ не найден"
+// @Router /api/admin/api-keys [delete]
+func (api *api) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := api.srv.RevokeAPIKey(r.Context(), id); err != nil {
+		api.writeError(w, r, "Failed to revoke api key", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
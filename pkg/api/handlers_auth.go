@@ -2,8 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"leti/pkg/auth"
+	"leti/pkg/lockout"
+	"leti/pkg/models"
+	"leti/pkg/service"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -15,46 +22,165 @@ import (
 // @Produce json
 // @Param credentials body auth.LoginRequest true "Учётные данные"
 // @Success 200 {object} auth.LoginResponse
-// @Failure 400 {object} string "Невалидные данные"
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Не указано имя или пароль"
 // @Failure 401 {object} string "Неверные учётные данные"
+// @Failure 403 {object} string "Учётная запись заблокирована"
+// @Failure 429 {object} string "Слишком много неудачных попыток"
 // @Router /api/auth/login [post]
 func (api *api) login(w http.ResponseWriter, r *http.Request) {
 	var req auth.LoginRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := api.srv.Authenticate(r.Context(), req.Username, req.Password, clientIP(r))
 	if err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		api.writeLoginError(w, r, req.Username, err)
 		return
 	}
-	defer r.Body.Close()
 
-	if strings.TrimSpace(req.Username) == "" {
-		http.Error(w, "username cannot be empty", http.StatusBadRequest)
+	api.completeLogin(w, r, user)
+}
+
+// writeLoginError отвечает одинаково для неизвестного имени и неверного пароля.
+func (api *api) writeLoginError(w http.ResponseWriter, r *http.Request, username string, err error) {
+	var locked *lockout.LockedError
+	switch {
+	case errors.As(err, &locked):
+		api.logger.WarnContext(r.Context(), "Login blocked", "username", username, "ip", clientIP(r), "retry_after", locked.RetryAfter)
+		writeLocked(w, r, locked)
+	case errors.Is(err, service.ErrAccountDisabled):
+		writeProblem(w, r, http.StatusForbidden, "account disabled")
+	case errors.Is(err, service.ErrInvalidCredentials):
+		api.logger.DebugContext(r.Context(), "Invalid credentials", "username", username)
+		writeProblem(w, r, http.StatusUnauthorized, "invalid credentials")
+	default:
+		api.writeError(w, r, "Login failed", err)
+	}
+}
+
+// writeLocked — ответ на сработавший lockout, одинаковый для пароля и второго фактора.
+func writeLocked(w http.ResponseWriter, r *http.Request, locked *lockout.LockedError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	writeProblem(w, r, http.StatusTooManyRequests, "too many failed login attempts")
+}
+
+// clientIP — адрес клиента для счётчиков. Заголовкам X-Forwarded-For не доверяем:
+// их может подставить сам клиент.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// LoginSecondFactor finishes a login for users with 2FA enabled
+// @Summary Второй шаг входа
+// @Description Принимает challenge_token из /api/auth/login и TOTP-код (или код восстановления), возвращает JWT токен
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body auth.MFALoginRequest true "Токен первого шага и код"
+// @Success 200 {object} auth.LoginResponse
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Не указан токен или код"
+// @Failure 401 {object} string "Неверный код или погашенный challenge"
+// @Failure 429 {object} string "Слишком много неверных кодов: challenge погашен, вход заново"
+// @Router /api/auth/login/2fa [post]
+func (api *api) loginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req auth.MFALoginRequest
+	if !api.decodeJSON(w, r, &req) {
 		return
 	}
-	if strings.TrimSpace(req.Password) == "" {
-		http.Error(w, "password cannot be empty", http.StatusBadRequest)
+
+	challenge, err := api.jwtService.ParseMFAChallengeToken(req.ChallengeToken)
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, "challenge expired")
+		return
+	}
+	err = api.srv.VerifyLoginSecondFactor(r.Context(), challenge, strings.TrimSpace(req.Code), clientIP(r))
+	var locked *lockout.LockedError
+	switch {
+	case err == nil:
+	case errors.As(err, &locked):
+		api.logger.WarnContext(r.Context(), "Second factor blocked", "user_id", challenge.UserID, "ip", clientIP(r), "retry_after", locked.RetryAfter)
+		writeLocked(w, r, locked)
+		return
+	case errors.Is(err, lockout.ErrChallengeSpent):
+		writeProblem(w, r, http.StatusUnauthorized, "challenge expired")
+		return
+	case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrMFANotEnrolled):
+		api.logger.InfoContext(r.Context(), "Second factor rejected", "user_id", challenge.UserID, "error", err)
+		writeProblem(w, r, http.StatusUnauthorized, "invalid code")
+		return
+	default:
+		api.writeError(w, r, "Second factor check failed", err)
+		return
+	}
+	user, err := api.srv.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to load user", "user_id", challenge.UserID, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if user.DisabledAt != nil {
+		writeProblem(w, r, http.StatusForbidden, "account disabled")
+		return
 	}
 
-	user, err := api.srv.ValidateUserCredentials(r.Context(), req.Username, req.Password)
+	accessToken, err := api.jwtService.GenerateAccessToken(subjectOf(user))
 	if err != nil {
-		api.logger.Error("Invalid credentials", "username", req.Username, "error", err)
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		api.writeError(w, r, "Failed to generate access token", err)
+		return
+	}
+	api.writeLoginResponse(w, r, auth.LoginResponse{AccessToken: accessToken})
+}
+
+// completeLogin выдаёт токены после того, как пользователь подтвердил личность
+// первым фактором (паролем или через OIDC).
+func (api *api) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	step, err := api.srv.NextLoginStep(r.Context(), user)
+	if err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to check 2fa status", "user_id", user.ID, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
-	// Генерация JWT с реальными данными пользователя
-	accessToken, err := api.jwtService.GenerateAccessToken(user.ID, user.Role)
+	var response auth.LoginResponse
+	switch step {
+	case service.LoginNeedsSecondFactor:
+		response.MFARequired = true
+		response.ChallengeToken, err = api.jwtService.GenerateMFAChallengeToken(user.ID)
+	case service.LoginNeedsMFAEnrollment:
+		response.MFAEnrollmentRequired = true
+		response.AccessToken, err = api.jwtService.GenerateMFAEnrollmentToken(subjectOf(user))
+	default:
+		// Генерация JWT с реальными данными пользователя
+		response.AccessToken, err = api.jwtService.GenerateAccessToken(subjectOf(user))
+	}
 	if err != nil {
-		api.logger.Error("Failed to generate access token", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to generate access token", err)
 		return
 	}
+	api.writeLoginResponse(w, r, response)
+}
 
-	response := auth.LoginResponse{
-		AccessToken: accessToken,
+// subjectOf — данные пользователя, которые попадают в его токены.
+func subjectOf(user *models.User) auth.Subject {
+	return auth.Subject{
+		UserID:       user.ID,
+		Role:         user.Role,
+		TenantID:     user.TenantID,
+		TokenVersion: user.TokenVersion,
 	}
+}
 
+func (api *api) writeLoginResponse(w http.ResponseWriter, r *http.Request, response auth.LoginResponse) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode login response", "error", err)
+	}
 }
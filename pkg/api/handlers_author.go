@@ -1,67 +1,82 @@
 package api
 
 import (
-	"encoding/json"
 	"leti/pkg/api/dto"
+	"leti/pkg/models"
 	"net/http"
-	"strings"
 )
 
 // Get all authors
 // @Summary Получить всех авторов
 // @Description Возвращает список всех авторов в каталоге
 // @Tags authors
-// @Produce json
+// @Produce json,xml,text/csv,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, csv, msgpack)
+// @Param If-None-Match header string false "ETag из прошлого ответа"
+// @Param If-Modified-Since header string false "Last-Modified из прошлого ответа"
 // @Success 200 {array} dto.AuthorResponse
+// @Header 200 {string} ETag "Версия списка авторов"
+// @Header 200 {string} Last-Modified "Время последнего изменения"
+// @Success 304 "Авторы не изменились"
+// @Failure 406 {object} string "Формат ответа недоступен"
 // @Router /api/authors [get]
 func (api *api) getAuthors(w http.ResponseWriter, r *http.Request) {
+	f, ok := api.negotiate(w, r, true)
+	if !ok {
+		return
+	}
+	version, err := api.srv.CatalogVersion(r.Context(), models.CollectionAuthors)
+	if err != nil {
+		api.writeError(w, r, "Failed to get authors version", err)
+		return
+	}
+	if api.notModified(w, r, models.CollectionAuthors, formatETag(catalogETag(version), f), version.UpdatedAt) {
+		return
+	}
+
 	data, err := api.srv.GetAllAuthors(r.Context())
 	if err != nil {
-		api.logger.Error("Failed to get authors", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to get authors", err)
 		return
 	}
 
 	resp := dto.FromAuthorModelsArray(data)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		api.logger.Error("Failed to encode authors", "error", err)
-	}
+	api.write(w, r, f, http.StatusOK, resp)
 }
 
 // Create new author
 // @Summary Создать нового автора
 // @Description Добавляет нового автора в каталог
 // @Tags authors
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, msgpack)
 // @Param author body dto.CreateAuthorRequest true "Данные автора"
-// @Success 201 {object} map[string]int "ID созданного автора"
-// @Failure 400 {object} string "Невалидные данные"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернёт первый ответ"
+// @Success 201 {object} dto.CreatedResponse "ID созданного автора"
+// @Failure 400 {object} string "Невалидное тело запроса"
+// @Failure 422 {object} string "Ошибка валидации"
 // @Failure 500 {object} string "Внутренняя ошибка сервера"
+// @Failure 409 {object} string "Запрос с этим Idempotency-Key ещё выполняется"
+// @Failure 406 {object} string "Формат ответа недоступен"
+// @Failure 415 {object} string "Неподдерживаемый Content-Type"
 // @Router /api/authors [post]
 func (api *api) postAuthors(w http.ResponseWriter, r *http.Request) {
-	var req dto.CreateAuthorRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	f, ok := api.negotiate(w, r, false)
+	if !ok {
 		return
 	}
-
-	// Валидация до преобразования
-	if strings.TrimSpace(req.Name) == "" {
-		http.Error(w, "author name cannot be empty", http.StatusBadRequest)
+	var req dto.CreateAuthorRequest
+	if !api.decodeBody(w, r, &req) {
 		return
 	}
 
 	author := req.ToAuthorModel()
 	id, err := api.srv.NewAuthor(r.Context(), author)
 	if err != nil {
-		api.logger.Error("Failed to create author", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to create author", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]int{"id": id}); err != nil {
-		api.logger.Error("Failed to encode author ID", "error", err)
-	}
+	api.write(w, r, f, http.StatusCreated, dto.CreatedResponse{ID: id})
 }
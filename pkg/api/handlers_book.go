@@ -1,11 +1,10 @@
 package api
 
 import (
-	"encoding/json"
 	"leti/pkg/api/dto"
+	"leti/pkg/models"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -14,139 +13,121 @@ import (
 // @Summary Получить список книг с авторами
 // @Description Возвращает все книги из каталога вместе с именами авторов
 // @Tags books
-// @Produce json
+// @Produce json,xml,text/csv,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, csv, msgpack)
 // @Success 200 {array} dto.BookWithAuthorResponse "Список книг"
+// @Failure 406 {object} string "Формат ответа недоступен"
 // @Router /api/books/withauthors [get]
 func (api *api) booksWithAuthor(w http.ResponseWriter, r *http.Request) {
+	f, ok := api.negotiate(w, r, true)
+	if !ok {
+		return
+	}
 	data, err := api.srv.GetAllWithAuthors(r.Context())
 	if err != nil {
-		api.logger.Error("Failed to get books with authors", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to get books with authors", err)
 		return
 	}
 	response := make([]dto.BookWithAuthorResponse, len(data))
 	for i, book := range data {
 		response[i] = dto.FromBookWithAuthorModel(book)
 	}
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		api.logger.Error("Failed to encode books with authors", "error", err)
-	}
+	api.write(w, r, f, http.StatusOK, response)
 }
 
 // CreateBook creates a new book in catalog
 // @Summary Создать новую книгу
 // @Description Добавляет книгу в каталог (требуется авторизация)
 // @Tags books
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, msgpack)
 // @Param book body dto.CreateBookRequest true "Данные книги"
-// @Success 201 {object} map[string]int "ID созданной книги"
-// @Failure 400 {object} string "Невалидные данные"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернёт первый ответ"
+// @Success 201 {object} dto.CreatedResponse "ID созданной книги"
+// @Failure 400 {object} string "Невалидное тело запроса"
+// @Failure 422 {object} string "Ошибка валидации"
 // @Failure 401 {object} string "Неавторизован"
+// @Failure 409 {object} string "Книга с таким ISBN уже есть или запрос с этим Idempotency-Key ещё выполняется"
+// @Failure 406 {object} string "Формат ответа недоступен"
+// @Failure 415 {object} string "Неподдерживаемый Content-Type"
 // @Router /api/books [post]
 func (api *api) createBook(w http.ResponseWriter, r *http.Request) {
-	var req dto.CreateBookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
-		return
-	}
-
-	if strings.TrimSpace(req.Name) == "" {
-		http.Error(w, "book name cannot be empty", http.StatusBadRequest)
-		return
-	}
-	if req.AuthorID <= 0 {
-		http.Error(w, "author_id must be positive", http.StatusBadRequest)
-		return
-	}
-	if req.GenreID <= 0 {
-		http.Error(w, "genre_id must be positive", http.StatusBadRequest)
+	f, ok := api.negotiate(w, r, false)
+	if !ok {
 		return
 	}
-	if req.Price < 0 {
-		http.Error(w, "price must be non-negative", http.StatusBadRequest)
+	var req dto.CreateBookRequest
+	if !api.decodeBody(w, r, &req) {
 		return
 	}
 
 	book := req.ToBookModel()
 	id, err := api.srv.CreateBook(r.Context(), book)
 	if err != nil {
-		api.logger.Error("Failed to create book", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to create book", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]int{"id": id}); err != nil {
-		api.logger.Error("Failed to encode book ID", "error", err)
-	}
+	api.write(w, r, f, http.StatusCreated, dto.CreatedResponse{ID: id})
 }
 
 // Update info about book
 // @Summary Частично обновить информацию о книге
 // @Description Обновляет указанные поля книги (требуется авторизация)
 // @Tags books
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, msgpack)
 // @Param book body dto.UpdateBookRequest true "Поля для обновления"
 // @Param id query int true "ID книги"
 // @Success 200 {object} dto.BookResponse "Измененная книга"
-// @Failure 400 {object} string "Невалидные данные"
+// @Failure 400 {object} string "Невалидное тело запроса"
+// @Failure 422 {object} string "Ошибка валидации"
 // @Failure 401 {object} string "Неавторизован"
+// @Failure 406 {object} string "Формат ответа недоступен"
+// @Failure 415 {object} string "Неподдерживаемый Content-Type"
 func (api *api) updateBook(w http.ResponseWriter, r *http.Request) {
+	f, ok := api.negotiate(w, r, false)
+	if !ok {
+		return
+	}
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "missing id query parameter", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "missing id query parameter")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 
 	var req dto.UpdateBookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	if !api.decodeBody(w, r, &req) {
 		return
 	}
 
 	update := req.ToBookModel()
 	if err := api.srv.UpdateBook(r.Context(), id, update); err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "book not found", http.StatusNotFound)
-			return
-		}
-		if strings.Contains(err.Error(), "non-negative") {
-			http.Error(w, "price must be non-negative", http.StatusBadRequest)
-			return
-		}
-		api.logger.Error("Failed to update book", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to update book", err)
 		return
 	}
 
 	data, err := api.srv.GetBookByID(r.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "no rows") {
-			http.Error(w, "book not found", http.StatusNotFound)
-			return
-		}
-		api.logger.Error("Failed to get book by ID", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to get book by ID", err)
 		return
 	}
 	response := dto.FromBookModel(data)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		api.logger.Error("Failed to encode book", "error", err)
-	}
+	api.write(w, r, f, http.StatusOK, response)
 }
 
 // DeleteBook deletes book by ID
 // @Summary Удалить книгу из каталога
-// @Description Удаляет книгу из каталога по ID (требуется авторизация)
+// @Description Удаляет книгу из каталога по ID (требуется авторизация). Удаление несуществующей книги — тоже 204
 // @Tags books
 // @Param id query int true "ID книги"
 // @Success 204
@@ -156,17 +137,16 @@ func (api *api) deleteBook(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "missing id", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "missing id")
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 	if err := api.srv.RemoveBook(r.Context(), id); err != nil {
-		api.logger.Error("Failed to delete book", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to delete book", err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -176,58 +156,84 @@ func (api *api) deleteBook(w http.ResponseWriter, r *http.Request) {
 // @Summary Получить книгу по ID
 // @Description Получает информацию о книге по ID в каталоге
 // @Tags books
-// @Produce json
+// @Produce json,xml,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, msgpack)
 // @Param id query int false "ID книги"
+// @Param If-None-Match header string false "ETag из прошлого ответа"
+// @Param If-Modified-Since header string false "Last-Modified из прошлого ответа"
 // @Success 200 {object} dto.BookResponse
+// @Header 200 {string} ETag "Версия книги"
+// @Header 200 {string} Last-Modified "Время последнего изменения"
+// @Success 304 "Книга не изменилась"
 // @Failure 404 {object} string "Книга не найдена"
+// @Failure 406 {object} string "Формат ответа недоступен"
 // @Router /api/book [get]
 func (api *api) getBookById(w http.ResponseWriter, r *http.Request) {
+	f, ok := api.negotiate(w, r, false)
+	if !ok {
+		return
+	}
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		http.Error(w, "missing id", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "missing id")
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 	data, err := api.srv.GetBookByID(r.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "no rows") {
-			http.Error(w, "book not found", http.StatusNotFound)
-			return
-		}
-		api.logger.Error("Failed to get book by ID", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to get book by ID", err)
 		return
 	}
-	response := dto.FromBookModel(data)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		api.logger.Error("Failed to encode book", "error", err)
+	if api.notModified(w, r, models.CollectionBooks, formatETag(bookETag(data), f), data.UpdatedAt) {
+		return
 	}
+	response := dto.FromBookModel(data)
+	api.write(w, r, f, http.StatusOK, response)
 }
 
 // Get all books
 // @Summary Получить все книги
 // @Description Получает информацию о всех книгах в каталоге
 // @Tags books
-// @Produce json
+// @Produce json,xml,text/csv,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, csv, msgpack)
+// @Param If-None-Match header string false "ETag из прошлого ответа"
+// @Param If-Modified-Since header string false "Last-Modified из прошлого ответа"
 // @Success 200 {array} dto.BookResponse
+// @Header 200 {string} ETag "Версия списка книг"
+// @Header 200 {string} Last-Modified "Время последнего изменения"
+// @Success 304 "Книги не изменились"
+// @Failure 406 {object} string "Формат ответа недоступен"
 // @Router /api/books [get]
 func (api *api) getBooks(w http.ResponseWriter, r *http.Request) {
+	f, ok := api.negotiate(w, r, true)
+	if !ok {
+		return
+	}
+	version, err := api.srv.CatalogVersion(r.Context(), models.CollectionBooks)
+	if err != nil {
+		api.writeError(w, r, "Failed to get books version", err)
+		return
+	}
+	// версия читается до данных: если каталог изменится между запросами, клиент
+	// получит новые данные со старым ETag и просто перезапросит их позже
+	if api.notModified(w, r, models.CollectionBooks, formatETag(catalogETag(version), f), version.UpdatedAt) {
+		return
+	}
+
 	data, err := api.srv.GetAllBooks(r.Context())
 	if err != nil {
-		api.logger.Error("Failed to get all books", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to get all books", err)
 		return
 	}
 	response := make([]dto.BookResponse, len(data))
 	for i, book := range data {
 		response[i] = dto.FromBookModel(book)
 	}
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		api.logger.Error("Failed to encode books", "error", err)
-	}
+	api.write(w, r, f, http.StatusOK, response)
 }
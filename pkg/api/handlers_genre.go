@@ -1,67 +1,82 @@
 package api
 
 import (
-	"encoding/json"
 	"leti/pkg/api/dto"
+	"leti/pkg/models"
 	"net/http"
-	"strings"
 )
 
 // Get all genres
 // @Summary Получить все жанры
 // @Description Возвращает список всех жанров в каталоге
 // @Tags genres
-// @Produce json
+// @Produce json,xml,text/csv,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, csv, msgpack)
+// @Param If-None-Match header string false "ETag из прошлого ответа"
+// @Param If-Modified-Since header string false "Last-Modified из прошлого ответа"
 // @Success 200 {array} dto.GenreResponse
+// @Header 200 {string} ETag "Версия списка жанров"
+// @Header 200 {string} Last-Modified "Время последнего изменения"
+// @Success 304 "Жанры не изменились"
+// @Failure 406 {object} string "Формат ответа недоступен"
 // @Router /api/genres [get]
 func (api *api) getGenres(w http.ResponseWriter, r *http.Request) {
+	f, ok := api.negotiate(w, r, true)
+	if !ok {
+		return
+	}
+	version, err := api.srv.CatalogVersion(r.Context(), models.CollectionGenres)
+	if err != nil {
+		api.writeError(w, r, "Failed to get genres version", err)
+		return
+	}
+	if api.notModified(w, r, models.CollectionGenres, formatETag(catalogETag(version), f), version.UpdatedAt) {
+		return
+	}
+
 	data, err := api.srv.GetAllGenres(r.Context())
 	if err != nil {
-		api.logger.Error("Failed to get genres", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to get genres", err)
 		return
 	}
 
 	resp := dto.FromGenreModelsArray(data)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		api.logger.Error("Failed to encode genres", "error", err)
-	}
+	api.write(w, r, f, http.StatusOK, resp)
 }
 
 // Create new genre
 // @Summary Создать новый жанр
 // @Description Добавляет новый жанр в каталог
 // @Tags genres
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
+// @Param format query string false "Формат ответа вместо Accept" Enums(json, xml, msgpack)
 // @Param genre body dto.CreateGenreRequest true "Данные о жанре"
-// @Success 201 {object} map[string]int "ID созданного жанра"
-// @Failure 400 {object} string "Невалидные данные"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернёт первый ответ"
+// @Success 201 {object} dto.CreatedResponse "ID созданного жанра"
+// @Failure 400 {object} string "Невалидное тело запроса"
+// @Failure 422 {object} string "Ошибка валидации"
 // @Failure 500 {object} string "Внутренняя ошибка сервера"
+// @Failure 409 {object} string "Жанр уже есть или запрос с этим Idempotency-Key ещё выполняется"
+// @Failure 406 {object} string "Формат ответа недоступен"
+// @Failure 415 {object} string "Неподдерживаемый Content-Type"
 // @Router /api/genres [post]
 func (api *api) postGenres(w http.ResponseWriter, r *http.Request) {
-	var req dto.CreateGenreRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	f, ok := api.negotiate(w, r, false)
+	if !ok {
 		return
 	}
-
-	// Валидация
-	if strings.TrimSpace(req.Name) == "" {
-		http.Error(w, "genre name cannot be empty", http.StatusBadRequest)
+	var req dto.CreateGenreRequest
+	if !api.decodeBody(w, r, &req) {
 		return
 	}
 
 	genre := req.ToGenreModel()
 	id, err := api.srv.NewGenre(r.Context(), genre)
 	if err != nil {
-		api.logger.Error("Failed to create genre", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		api.writeError(w, r, "Failed to create genre", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]int{"id": id}); err != nil {
-		api.logger.Error("Failed to encode genre ID", "error", err)
-	}
+	api.write(w, r, f, http.StatusCreated, dto.CreatedResponse{ID: id})
 }
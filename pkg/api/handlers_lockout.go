@@ -0,0 +1,32 @@
+package api
+
+import (
+	"leti/pkg/api/dto"
+	"net/http"
+)
+
+// UnlockLogin clears login lockout counters
+// @Summary Снять блокировку входа
+// @Description Сбрасывает счётчики неудачных входов по имени пользователя и/или IP (требуется роль admin)
+// @Tags admin
+// @Accept json
+// @Param request body dto.UnlockLoginRequest true "Имя пользователя и/или IP"
+// @Success 204 "Блокировка снята"
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Не указано ни имя, ни IP"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/lockout/unlock [post]
+func (api *api) unlockLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.UnlockLoginRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := api.srv.UnlockLogin(r.Context(), req.Username, req.IP); err != nil {
+		api.writeError(w, r, "Failed to unlock login", err)
+		return
+	}
+	api.logger.InfoContext(r.Context(), "Login unlocked", "username", req.Username, "ip", req.IP)
+	w.WriteHeader(http.StatusNoContent)
+}
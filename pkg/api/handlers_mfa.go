@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/service"
+	"net/http"
+	"strings"
+)
+
+// EnrollTOTP starts 2FA enrollment for the caller
+// @Summary Начать подключение 2FA
+// @Description Генерирует TOTP-секрет и QR-код для приложения-аутентификатора. 2FA включается после подтверждения кодом
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.TOTPEnrollmentResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 409 {object} string "2FA уже включена"
+// @Router /api/auth/2fa/enroll [post]
+func (api *api) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	enrollment, err := api.srv.BeginTOTPEnrollment(r.Context(), principal.UserID)
+	if err != nil {
+		api.writeError(w, r, "Failed to start 2fa enrollment", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	resp := dto.TOTPEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		QRPNG:      enrollment.QRCode,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode 2fa enrollment", "error", err)
+	}
+}
+
+// VerifyTOTP confirms enrollment and turns 2FA on
+// @Summary Подтвердить подключение 2FA
+// @Description Проверяет код из приложения, включает 2FA и возвращает коды восстановления (показываются один раз)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TOTPCodeRequest true "Код из приложения"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} string "Неверный код"
+// @Failure 422 {object} string "Код не указан"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 409 {object} string "2FA уже включена"
+// @Router /api/auth/2fa/verify [post]
+func (api *api) verifyTOTP(w http.ResponseWriter, r *http.Request) {
+	var req dto.TOTPCodeRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	codes, err := api.srv.ConfirmTOTPEnrollment(r.Context(), principal.UserID, strings.TrimSpace(req.Code))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrMFANotEnrolled):
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+		default:
+			api.writeError(w, r, "Failed to confirm 2fa enrollment", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode recovery codes", "error", err)
+	}
+}
+
+// DisableTOTP turns 2FA off for the caller
+// @Summary Отключить 2FA
+// @Description Отключает 2FA; нужен действующий код или код восстановления
+// @Tags auth
+// @Accept json
+// @Param request body dto.TOTPCodeRequest true "Код из приложения или код восстановления"
+// @Success 204 "2FA отключена"
+// @Failure 400 {object} string "Неверный код"
+// @Failure 422 {object} string "Код не указан"
+// @Failure 401 {object} string "Неавторизован"
+// @Router /api/auth/2fa [delete]
+func (api *api) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	var req dto.TOTPCodeRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if err := api.srv.DisableTOTP(r.Context(), principal.UserID, strings.TrimSpace(req.Code)); err != nil {
+		if errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrMFANotEnrolled) {
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		api.logger.ErrorContext(r.Context(), "Failed to disable 2fa", "user_id", principal.UserID, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMFARequiredRoles lists roles that must use 2FA
+// @Summary Роли с обязательной 2FA
+// @Description Возвращает роли, которым вход без 2FA запрещён (требуется роль admin)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} dto.MFARequiredRolesResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/mfa/required-roles [get]
+func (api *api) getMFARequiredRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := api.srv.GetMFARequiredRoles(r.Context())
+	if err != nil {
+		api.writeError(w, r, "Failed to get mfa required roles", err)
+		return
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dto.MFARequiredRolesResponse{Roles: roles}); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode mfa required roles", "error", err)
+	}
+}
+
+// SetMFARequiredRoles replaces the set of roles that must use 2FA
+// @Summary Задать роли с обязательной 2FA
+// @Description Пользователи этих ролей без настроенной 2FA после входа смогут только подключить её (требуется роль admin)
+// @Tags admin
+// @Accept json
+// @Param request body dto.MFARequiredRolesRequest true "Роли"
+// @Success 204 "Сохранено"
+// @Failure 422 {object} string "Неизвестная роль"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/mfa/required-roles [put]
+func (api *api) setMFARequiredRoles(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFARequiredRolesRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := api.srv.SetMFARequiredRoles(r.Context(), req.Roles); err != nil {
+		api.writeError(w, r, "Failed to set mfa required roles", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/lockout"
+	"leti/pkg/models"
+	"leti/pkg/service"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//go:embed templates/consent.html
+var templatesFS embed.FS
+
+var consentTemplate = template.Must(template.ParseFS(templatesFS, "templates/consent.html"))
+
+type consentPage struct {
+	ClientName string
+	Scopes     []string
+	Scope      string
+	Request    auth.AuthorizeRequest
+	Error      string
+	// ChallengeToken — пароль принят, форма спрашивает код 2FA (см. authorizeSecondFactor)
+	ChallengeToken string
+}
+
+func authorizeRequestFromValues(v url.Values) auth.AuthorizeRequest {
+	return auth.AuthorizeRequest{
+		ResponseType:        v.Get("response_type"),
+		ClientID:            v.Get("client_id"),
+		RedirectURI:         v.Get("redirect_uri"),
+		Scopes:              auth.ParseScope(v.Get("scope")),
+		State:               v.Get("state"),
+		CodeChallenge:       v.Get("code_challenge"),
+		CodeChallengeMethod: v.Get("code_challenge_method"),
+	}
+}
+
+// Authorize shows consent page
+// @Summary Страница согласия OAuth2
+// @Description Authorization endpoint (RFC 6749, 4.1.1). Требуется PKCE S256
+// @Tags oauth
+// @Produce html
+// @Param client_id query string true "ID клиента"
+// @Param redirect_uri query string false "Адрес возврата"
+// @Param response_type query string true "Только code"
+// @Param scope query string false "Области через пробел"
+// @Param state query string false "Состояние клиента"
+// @Param code_challenge query string true "PKCE challenge"
+// @Param code_challenge_method query string true "Только S256"
+// @Success 200 {string} string "HTML-страница согласия"
+// @Failure 302 {string} string "Перенаправление с ошибкой"
+// @Failure 400 {string} string "Неизвестный клиент или redirect_uri"
+// @Router /oauth/authorize [get]
+func (api *api) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	req := authorizeRequestFromValues(r.URL.Query())
+	client, err := api.srv.ValidateAuthorizeRequest(r.Context(), &req)
+	if err != nil {
+		api.authorizeError(w, r, client != nil, req, err)
+		return
+	}
+	api.renderConsent(w, r, http.StatusOK, consentPage{ClientName: client.Name, Request: req})
+}
+
+// AuthorizeDecision handles consent form
+// @Summary Решение пользователя на странице согласия
+// @Description Проверяет логин и пароль и перенаправляет на redirect_uri с кодом или ошибкой access_denied. Если у пользователя включена 2FA, форма возвращается с challenge_token и полем для кода — код авторизации выдаётся только после проверки второго фактора
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 200 {string} string "Форма ввода кода 2FA"
+// @Success 302 {string} string "Перенаправление с code и state"
+// @Failure 401 {string} string "Неверные учётные данные или код"
+// @Failure 403 {string} string "Учётная запись заблокирована или 2FA не подключена"
+// @Failure 429 {string} string "Слишком много неудачных попыток"
+// @Router /oauth/authorize [post]
+func (api *api) authorizeDecision(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid form")
+		return
+	}
+	req := authorizeRequestFromValues(r.PostForm)
+	client, err := api.srv.ValidateAuthorizeRequest(r.Context(), &req)
+	if err != nil {
+		api.authorizeError(w, r, client != nil, req, err)
+		return
+	}
+
+	if r.PostForm.Get("decision") != "approve" {
+		api.authorizeError(w, r, true, req, auth.NewOAuthError(auth.OAuthErrAccessDenied, "user denied access"))
+		return
+	}
+	page := consentPage{ClientName: client.Name, Request: req}
+	if challenge := r.PostForm.Get("challenge_token"); challenge != "" {
+		api.authorizeSecondFactor(w, r, page, challenge)
+		return
+	}
+
+	user, err := api.srv.Authenticate(r.Context(), r.PostForm.Get("username"), r.PostForm.Get("password"), clientIP(r))
+	if err != nil {
+		page.Error = "Неверный логин или пароль"
+		status := http.StatusUnauthorized
+		var locked *lockout.LockedError
+		switch {
+		case errors.As(err, &locked):
+			page.Error = "Слишком много неудачных попыток, попробуйте позже"
+			status = http.StatusTooManyRequests
+		case errors.Is(err, service.ErrAccountDisabled):
+			page.Error = "Учётная запись заблокирована"
+			status = http.StatusForbidden
+		}
+		api.renderConsent(w, r, status, page)
+		return
+	}
+
+	// пароль — только первый фактор, как и в completeLogin
+	step, err := api.srv.NextLoginStep(r.Context(), user)
+	if err != nil {
+		api.writeError(w, r, "Failed to check 2fa status", err)
+		return
+	}
+	switch step {
+	case service.LoginNeedsSecondFactor:
+		page.ChallengeToken, err = api.jwtService.GenerateMFAChallengeToken(user.ID)
+		if err != nil {
+			api.writeError(w, r, "Failed to generate mfa challenge", err)
+			return
+		}
+		api.renderConsent(w, r, http.StatusOK, page)
+	case service.LoginNeedsMFAEnrollment:
+		page.Error = "Сначала подключите двухфакторную аутентификацию"
+		api.renderConsent(w, r, http.StatusForbidden, page)
+	default:
+		api.issueAuthorizationCode(w, r, user, req)
+	}
+}
+
+// authorizeSecondFactor — второй шаг согласия для пользователей с 2FA: код
+// проверяется так же, как в loginSecondFactor, с тем же учётом неудачных попыток.
+func (api *api) authorizeSecondFactor(w http.ResponseWriter, r *http.Request, page consentPage, token string) {
+	challenge, err := api.jwtService.ParseMFAChallengeToken(token)
+	if err != nil {
+		page.Error = "Время на ввод кода истекло, войдите заново"
+		api.renderConsent(w, r, http.StatusUnauthorized, page)
+		return
+	}
+	err = api.srv.VerifyLoginSecondFactor(r.Context(), challenge, strings.TrimSpace(r.PostForm.Get("code")), clientIP(r))
+	var locked *lockout.LockedError
+	switch {
+	case err == nil:
+	case errors.As(err, &locked):
+		page.Error = "Слишком много неудачных попыток, попробуйте позже"
+		api.renderConsent(w, r, http.StatusTooManyRequests, page)
+		return
+	case errors.Is(err, lockout.ErrChallengeSpent):
+		page.Error = "Время на ввод кода истекло, войдите заново"
+		api.renderConsent(w, r, http.StatusUnauthorized, page)
+		return
+	case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrMFANotEnrolled):
+		page.Error = "Неверный код"
+		page.ChallengeToken = token
+		api.renderConsent(w, r, http.StatusUnauthorized, page)
+		return
+	default:
+		api.writeError(w, r, "Second factor check failed", err)
+		return
+	}
+
+	user, err := api.srv.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		api.writeError(w, r, "Failed to load user", err)
+		return
+	}
+	if user.DisabledAt != nil {
+		page.Error = "Учётная запись заблокирована"
+		api.renderConsent(w, r, http.StatusForbidden, page)
+		return
+	}
+	api.issueAuthorizationCode(w, r, user, page.Request)
+}
+
+func (api *api) issueAuthorizationCode(w http.ResponseWriter, r *http.Request, user *models.User, req auth.AuthorizeRequest) {
+	code, err := api.srv.IssueAuthorizationCode(r.Context(), user, req)
+	if err != nil {
+		api.authorizeError(w, r, true, req, err)
+		return
+	}
+	http.Redirect(w, r, redirectWithParams(req.RedirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	}), http.StatusFound)
+}
+
+// Token issues access tokens
+// @Summary Token endpoint OAuth2
+// @Description Обмен кода (authorization_code + PKCE) или client_credentials на access-токен. Клиент аутентифицируется через Basic или client_id/client_secret в форме
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} auth.TokenResponse
+// @Failure 400 {object} auth.OAuthError
+// @Failure 401 {object} auth.OAuthError
+// @Router /oauth/token [post]
+func (api *api) oauthToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		api.writeOAuthError(w, r, auth.NewOAuthError(auth.OAuthErrInvalidRequest, "invalid form"))
+		return
+	}
+	clientID, secret := oauthClientCredentials(r)
+	client, err := api.srv.AuthenticateOAuthClient(r.Context(), clientID, secret)
+	if err != nil {
+		api.writeOAuthError(w, r, err)
+		return
+	}
+
+	var (
+		// для client credentials субъект пустой: токен выдан самому клиенту
+		subject auth.Subject
+		scopes  []string
+	)
+	switch r.PostForm.Get("grant_type") {
+	case auth.GrantTypeAuthorizationCode:
+		user, granted, err := api.srv.ExchangeAuthorizationCode(r.Context(), client,
+			r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+		if err != nil {
+			api.writeOAuthError(w, r, err)
+			return
+		}
+		subject, scopes = subjectOf(user), granted
+	case auth.GrantTypeClientCredentials:
+		scopes, err = api.srv.ClientCredentialsScopes(client, auth.ParseScope(r.PostForm.Get("scope")))
+		if err != nil {
+			api.writeOAuthError(w, r, err)
+			return
+		}
+	default:
+		api.writeOAuthError(w, r, auth.NewOAuthError(auth.OAuthErrUnsupportedGrantType, ""))
+		return
+	}
+
+	token, err := api.jwtService.GenerateOAuthAccessToken(subject, client.ClientID, scopes)
+	if err != nil {
+		api.writeError(w, r, "Failed to generate oauth access token", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := auth.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(api.jwtService.AccessTokenTTL().Seconds()),
+		Scope:       auth.FormatScope(scopes),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode token response", "error", err)
+	}
+}
+
+// Introspect reports token state
+// @Summary Интроспекция токена (RFC 7662)
+// @Description Доступна только аутентифицированным конфиденциальным клиентам
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} auth.IntrospectionResponse
+// @Failure 401 {object} auth.OAuthError
+// @Router /oauth/introspect [post]
+func (api *api) oauthIntrospect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		api.writeOAuthError(w, r, auth.NewOAuthError(auth.OAuthErrInvalidRequest, "invalid form"))
+		return
+	}
+	clientID, secret := oauthClientCredentials(r)
+	client, err := api.srv.AuthenticateOAuthClient(r.Context(), clientID, secret)
+	if err != nil {
+		api.writeOAuthError(w, r, err)
+		return
+	}
+	if !client.IsConfidential() {
+		api.writeOAuthError(w, r, auth.NewOAuthError(auth.OAuthErrUnauthorizedClient, "introspection requires a confidential client"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := api.jwtService.Introspect(r.PostForm.Get("token"))
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode introspection response", "error", err)
+	}
+}
+
+// UserInfo returns resource owner profile
+// @Summary Профиль читателя для OAuth-приложений
+// @Description Требуется область profile:read в токене, выданном от имени пользователя
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} dto.UserInfoResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/oauth/userinfo [get]
+func (api *api) userInfo(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID == 0 {
+		writeProblem(w, r, http.StatusForbidden, "token is not bound to a user")
+		return
+	}
+	user, err := api.srv.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to get user", "error", err)
+		writeProblem(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+	if err := json.NewEncoder(w).Encode(dto.FromUserModel(*user)); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode user info", "error", err)
+	}
+}
+
+// CreateOAuthClient registers third-party application
+// @Summary Зарегистрировать OAuth-клиента
+// @Description Регистрирует стороннее приложение. Секрет конфиденциального клиента возвращается один раз (требуется роль admin)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param client body dto.CreateOAuthClientRequest true "Параметры клиента"
+// @Success 201 {object} dto.CreateOAuthClientResponse
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Ошибка валидации"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/oauth-clients [post]
+func (api *api) createOAuthClient(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateOAuthClientRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	client, secret, err := api.srv.RegisterOAuthClient(r.Context(), req.Name, req.RedirectURIs, req.Scopes, req.GrantTypes, req.Confidential)
+	if err != nil {
+		api.writeError(w, r, "Failed to register oauth client", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	resp := dto.CreateOAuthClientResponse{OAuthClientResponse: dto.FromOAuthClientModel(client), ClientSecret: secret}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode oauth client", "error", err)
+	}
+}
+
+// ListOAuthClients returns registered applications
+// @Summary Список OAuth-клиентов
+// @Description Возвращает зарегистрированные приложения без секретов (требуется роль admin)
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.OAuthClientResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/oauth-clients [get]
+func (api *api) listOAuthClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := api.srv.ListOAuthClients(r.Context())
+	if err != nil {
+		api.writeError(w, r, "Failed to list oauth clients", err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(dto.FromOAuthClientModelsArray(clients)); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode oauth clients", "error", err)
+	}
+}
+
+// authorizeError либо показывает ошибку пользователю (если клиенту нельзя доверять
+// redirect_uri), либо возвращает её клиенту через redirect (RFC 6749, 4.1.2.1).
+func (api *api) authorizeError(w http.ResponseWriter, r *http.Request, canRedirect bool, req auth.AuthorizeRequest, err error) {
+	var oauthErr *auth.OAuthError
+	if !errors.As(err, &oauthErr) {
+		api.writeError(w, r, "Authorization request failed", err)
+		return
+	}
+	if !canRedirect {
+		writeProblem(w, r, http.StatusBadRequest, oauthErr.Error())
+		return
+	}
+	http.Redirect(w, r, redirectWithParams(req.RedirectURI, map[string]string{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+		"state":             req.State,
+	}), http.StatusFound)
+}
+
+func (api *api) renderConsent(w http.ResponseWriter, r *http.Request, status int, page consentPage) {
+	page.Scopes = page.Request.Scopes
+	page.Scope = auth.FormatScope(page.Request.Scopes)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// страницу согласия нельзя встраивать во фреймы (clickjacking)
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := consentTemplate.Execute(w, page); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to render consent page", "error", err)
+	}
+}
+
+func (api *api) writeOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *auth.OAuthError
+	if !errors.As(err, &oauthErr) {
+		api.writeError(w, r, "OAuth request failed", err)
+		return
+	}
+	status := http.StatusBadRequest
+	if oauthErr.Code == auth.OAuthErrInvalidClient {
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(oauthErr); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode oauth error", "error", err)
+	}
+}
+
+// oauthClientCredentials достаёт данные клиента из Basic (client_secret_basic)
+// или из тела формы (client_secret_post).
+func oauthClientCredentials(r *http.Request) (clientID, secret string) {
+	if id, pass, ok := r.BasicAuth(); ok {
+		// RFC 6749, 2.3.1: значения в Basic закодированы как form-urlencoded
+		if unescaped, err := url.QueryUnescape(id); err == nil {
+			id = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(pass); err == nil {
+			pass = unescaped
+		}
+		return id, pass
+	}
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+}
+
+func redirectWithParams(redirectURI string, params map[string]string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"leti/pkg/auth"
+	"leti/pkg/service"
+	"net/http"
+)
+
+const oidcFlowCookie = "oidc_flow"
+
+// OIDCLogin starts login via corporate identity provider
+// @Summary Вход через корпоративный OIDC-провайдер
+// @Description Перенаправляет на страницу входа провайдера (authorization code + PKCE)
+// @Tags auth
+// @Success 302 {string} string "Перенаправление к провайдеру"
+// @Failure 502 {object} string "Провайдер недоступен"
+// @Router /api/auth/oidc/login [get]
+func (api *api) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	state, err1 := auth.RandomString()
+	nonce, err2 := auth.RandomString()
+	verifier, err3 := auth.RandomString()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		api.writeError(w, r, "Failed to generate oidc flow values", err)
+		return
+	}
+
+	redirectURL, err := api.oidc.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		api.logger.ErrorContext(r.Context(), "OIDC provider unavailable", "error", err)
+		writeProblem(w, r, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+	flow, err := api.jwtService.GenerateOIDCFlowToken(state, nonce, verifier)
+	if err != nil {
+		api.writeError(w, r, "Failed to sign oidc flow state", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    flow,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax: cookie должна прийти при переходе обратно с сайта провайдера
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// OIDCCallback finishes login via identity provider
+// @Summary Завершение входа через OIDC
+// @Description Проверяет state и ID-токен, при первом входе создаёт локального пользователя и возвращает JWT
+// @Tags auth
+// @Produce json
+// @Param code query string true "Код авторизации"
+// @Param state query string true "State"
+// @Success 200 {object} auth.LoginResponse
+// @Failure 400 {object} string "Невалидный запрос"
+// @Failure 401 {object} string "Вход отклонён"
+// @Router /api/auth/oidc/callback [get]
+func (api *api) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "login flow not started")
+		return
+	}
+	// cookie одноразовая
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Path: "/api/auth/oidc", MaxAge: -1})
+
+	flow, err := api.jwtService.ParseOIDCFlowToken(cookie.Value)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "login flow expired")
+		return
+	}
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(flow.State)) != 1 {
+		writeProblem(w, r, http.StatusBadRequest, "state mismatch")
+		return
+	}
+	if idpErr := query.Get("error"); idpErr != "" {
+		api.logger.InfoContext(r.Context(), "OIDC login rejected by provider", "error", idpErr)
+		writeProblem(w, r, http.StatusUnauthorized, "login rejected by identity provider")
+		return
+	}
+
+	identity, err := api.oidc.Exchange(r.Context(), query.Get("code"), flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCNoRole) || errors.Is(err, auth.ErrOIDCInvalidToken) {
+			api.logger.ErrorContext(r.Context(), "OIDC identity rejected", "error", err)
+			writeProblem(w, r, http.StatusUnauthorized, "login rejected")
+			return
+		}
+		api.logger.ErrorContext(r.Context(), "OIDC code exchange failed", "error", err)
+		writeProblem(w, r, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+
+	user, err := api.srv.LoginWithOIDC(r.Context(), identity)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountDisabled) {
+			writeProblem(w, r, http.StatusForbidden, "account disabled")
+			return
+		}
+		api.logger.ErrorContext(r.Context(), "Failed to provision oidc user", "subject", identity.Subject, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	api.completeLogin(w, r, user)
+}
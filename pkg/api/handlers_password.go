@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"leti/pkg/auth"
+	"leti/pkg/service"
+	"net/http"
+)
+
+// ChangePassword changes the caller's password
+// @Summary Смена пароля
+// @Description Меняет пароль после проверки текущего. Все ранее выданные токены перестают действовать, в ответе — новый токен
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body auth.ChangePasswordRequest true "Текущий и новый пароль"
+// @Success 200 {object} auth.LoginResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Неверный текущий пароль"
+// @Failure 422 {object} string "Слишком простой пароль"
+// @Router /api/auth/password [post]
+func (api *api) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req auth.ChangePasswordRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	user, err := api.srv.ChangePassword(r.Context(), principal.UserID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		api.writeError(w, r, "Failed to change password", err)
+		return
+	}
+
+	// Старые токены отозваны, включая тот, с которым пришёл запрос
+	accessToken, err := api.jwtService.GenerateAccessToken(subjectOf(user))
+	if err != nil {
+		api.writeError(w, r, "Failed to generate access token", err)
+		return
+	}
+	api.writeLoginResponse(w, r, auth.LoginResponse{AccessToken: accessToken})
+}
+
+// RequestPasswordReset sends a password reset link
+// @Summary Запросить сброс пароля
+// @Description Отправляет одноразовую ссылку для сброса пароля. Ответ не зависит от того, существует ли пользователь
+// @Tags auth
+// @Accept json
+// @Param request body auth.PasswordResetRequest true "Имя пользователя"
+// @Success 202 "Если пользователь существует, письмо отправлено"
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Не указано имя пользователя"
+// @Router /api/auth/password/reset [post]
+func (api *api) requestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req auth.PasswordResetRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Ошибку доставки только логируем: ответ не должен выдавать, есть ли такой пользователь
+	if err := api.srv.RequestPasswordReset(r.Context(), req.Username); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to send password reset", "username", req.Username, "error", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset sets a new password using a reset token
+// @Summary Сбросить пароль по токену
+// @Description Устанавливает новый пароль по токену из письма. Токен одноразовый; все выданные ранее токены доступа отзываются
+// @Tags auth
+// @Accept json
+// @Param request body auth.PasswordResetConfirmRequest true "Токен и новый пароль"
+// @Success 204 "Пароль изменён"
+// @Failure 400 {object} string "Неверный или просроченный токен"
+// @Failure 422 {object} string "Слишком простой пароль"
+// @Router /api/auth/password/reset/confirm [post]
+func (api *api) confirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req auth.PasswordResetConfirmRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := api.srv.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		api.writeError(w, r, "Failed to reset password", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/service"
+	"net/http"
+)
+
+// CreateTenant registers a new library
+// @Summary Создать библиотеку
+// @Description Регистрирует арендатора с собственным каталогом. Если передан admin, создаёт его администратора и отправляет ему ссылку для установки пароля (требуется роль admin библиотеки по умолчанию)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param tenant body dto.CreateTenantRequest true "Поддомен, название и первый администратор"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернёт первый ответ"
+// @Success 201 {object} dto.TenantResponse
+// @Failure 400 {object} string "Невалидный JSON"
+// @Failure 422 {object} string "Ошибка валидации"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 409 {object} string "Поддомен или имя администратора заняты, либо запрос с этим Idempotency-Key ещё выполняется"
+// @Router /api/admin/tenants [post]
+func (api *api) createTenant(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateTenantRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	var admin *service.TenantAdmin
+	if req.Admin != nil {
+		admin = &service.TenantAdmin{Username: req.Admin.Username, Email: req.Admin.Email}
+	}
+	created, err := api.srv.CreateTenant(r.Context(), req.Slug, req.Name, admin)
+	if err != nil {
+		api.writeError(w, r, "Failed to create tenant", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(dto.FromTenantModel(*created)); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode tenant", "error", err)
+	}
+}
+
+// ListTenants returns all libraries
+// @Summary Список библиотек
+// @Description Возвращает всех арендаторов (требуется роль admin библиотеки по умолчанию)
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.TenantResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/tenants [get]
+func (api *api) listTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := api.srv.ListTenants(r.Context())
+	if err != nil {
+		api.writeError(w, r, "Failed to list tenants", err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(dto.FromTenantModelsArray(tenants)); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode tenants", "error", err)
+	}
+}
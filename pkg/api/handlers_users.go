@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Me returns the current caller
+// @Summary Текущий пользователь
+// @Description Возвращает пользователя и области доступа токена или API-ключа, с которым пришёл запрос
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.MeResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Router /api/me [get]
+func (api *api) me(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	resp := dto.MeResponse{
+		Role:     principal.Role,
+		Scopes:   principal.Scopes,
+		AuthType: principal.AuthType,
+		ClientID: principal.ClientID,
+	}
+	if principal.UserID != 0 {
+		user, err := api.srv.GetUserByID(r.Context(), principal.UserID)
+		if err != nil {
+			api.logger.ErrorContext(r.Context(), "Failed to load current user", "user_id", principal.UserID, "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		resp.ID, resp.Username, resp.Email = user.ID, user.Username, user.Email
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode current user", "error", err)
+	}
+}
+
+// ListUsers returns a page of users
+// @Summary Список пользователей
+// @Description Постраничный список пользователей с поиском по имени и email (требуется роль admin)
+// @Tags admin
+// @Produce json
+// @Param q query string false "Подстрока имени или email"
+// @Param page query int false "Номер страницы, с 1"
+// @Param per_page query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} dto.UserListResponse
+// @Failure 400 {object} string "Невалидные параметры"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Router /api/admin/users [get]
+func (api *api) listUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := optionalInt(query.Get("page"), 1)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid page")
+		return
+	}
+	perPage, err := optionalInt(query.Get("per_page"), 0)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid per_page")
+		return
+	}
+
+	result, err := api.srv.ListUsers(r.Context(), strings.TrimSpace(query.Get("q")), page, perPage)
+	if err != nil {
+		api.writeError(w, r, "Failed to list users", err)
+		return
+	}
+
+	resp := dto.UserListResponse{
+		Users:   make([]dto.UserResponse, 0, len(result.Users)),
+		Total:   result.Total,
+		Page:    result.Page,
+		PerPage: result.PerPage,
+	}
+	for _, user := range result.Users {
+		resp.Users = append(resp.Users, dto.FromUserAdminModel(user))
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode users", "error", err)
+	}
+}
+
+// GetUser returns a single user
+// @Summary Получить пользователя
+// @Description Возвращает пользователя по ID (требуется роль admin)
+// @Tags admin
+// @Produce json
+// @Param id query int true "ID пользователя"
+// @Success 200 {object} dto.UserResponse
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Router /api/admin/users [get]
+func (api *api) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromVars(w, r)
+	if !ok {
+		return
+	}
+	user, err := api.srv.GetTenantUser(r.Context(), id)
+	if err != nil {
+		api.writeError(w, r, "Failed to get user", err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(dto.FromUserAdminModel(*user)); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode user", "error", err)
+	}
+}
+
+// ChangeUserRole changes a user's role
+// @Summary Изменить роль пользователя
+// @Description Меняет роль; выданные пользователю токены со старой ролью перестают действовать (требуется роль admin)
+// @Tags admin
+// @Accept json
+// @Param id query int true "ID пользователя"
+// @Param request body dto.ChangeRoleRequest true "Новая роль"
+// @Success 204 "Роль изменена"
+// @Failure 422 {object} string "Неизвестная роль"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Failure 409 {object} string "Нельзя понизить самого себя"
+// @Router /api/admin/users/role [put]
+func (api *api) changeUserRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromVars(w, r)
+	if !ok {
+		return
+	}
+	var req dto.ChangeRoleRequest
+	if !api.decodeJSON(w, r, &req) {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if err := api.srv.ChangeUserRole(r.Context(), principal.UserID, id, req.Role); err != nil {
+		api.writeError(w, r, "Failed to change user role", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableUser blocks an account
+// @Summary Заблокировать пользователя
+// @Description Запрещает вход; уже выданные токены и API-ключи отклоняются при следующем запросе (требуется роль admin)
+// @Tags admin
+// @Param id query int true "ID пользователя"
+// @Success 204 "Заблокирован"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Failure 409 {object} string "Нельзя заблокировать самого себя"
+// @Router /api/admin/users/disable [post]
+func (api *api) disableUser(w http.ResponseWriter, r *http.Request) {
+	api.setUserDisabled(w, r, true)
+}
+
+// EnableUser unblocks an account
+// @Summary Разблокировать пользователя
+// @Description Снимает блокировку учётной записи (требуется роль admin)
+// @Tags admin
+// @Param id query int true "ID пользователя"
+// @Success 204 "Разблокирован"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Router /api/admin/users/enable [post]
+func (api *api) enableUser(w http.ResponseWriter, r *http.Request) {
+	api.setUserDisabled(w, r, false)
+}
+
+func (api *api) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	id, ok := userIDFromVars(w, r)
+	if !ok {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if err := api.srv.SetUserDisabled(r.Context(), principal.UserID, id, disabled); err != nil {
+		api.writeError(w, r, "Failed to update user status", err)
+		return
+	}
+	api.logger.InfoContext(r.Context(), "User status changed", "user_id", id, "disabled", disabled, "by", principal.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForcePasswordReset invalidates a user's password
+// @Summary Принудительный сброс пароля
+// @Description Текущий пароль и все сессии пользователя перестают действовать, ему отправляется ссылка для установки нового (требуется роль admin)
+// @Tags admin
+// @Param id query int true "ID пользователя"
+// @Success 202 "Ссылка отправлена"
+// @Failure 409 {object} string "У пользователя нет локального пароля"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Router /api/admin/users/reset-password [post]
+func (api *api) forcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromVars(w, r)
+	if !ok {
+		return
+	}
+	if err := api.srv.ForcePasswordReset(r.Context(), id); err != nil {
+		api.writeError(w, r, "Failed to force password reset", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// DeleteUser removes a user
+// @Summary Удалить пользователя
+// @Description Удаляет пользователя вместе с его API-ключами, 2FA и привязками к IdP (требуется роль admin)
+// @Tags admin
+// @Param id query int true "ID пользователя"
+// @Success 204 "Удалён"
+// @Failure 401 {object} string "Неавторизован"
+// @Failure 403 {object} string "Недостаточно прав"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Failure 409 {object} string "Нельзя удалить самого себя"
+// @Router /api/admin/users [delete]
+func (api *api) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromVars(w, r)
+	if !ok {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if err := api.srv.DeleteUser(r.Context(), principal.UserID, id); err != nil {
+		api.writeError(w, r, "Failed to delete user", err)
+		return
+	}
+	api.logger.InfoContext(r.Context(), "User deleted", "user_id", id, "by", principal.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func userIDFromVars(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+func optionalInt(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
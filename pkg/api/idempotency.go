@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"leti/pkg/auth"
+	"leti/pkg/idempotency"
+	"leti/pkg/tenant"
+	"net/http"
+	"strconv"
+)
+
+// maxIdempotencyKeyLen — предел длины Idempotency-Key; обычно это UUID.
+const maxIdempotencyKeyLen = 255
+
+// WithIdempotency включает поддержку Idempotency-Key на создающих запросах (см. idempotent).
+func WithIdempotency(g *idempotency.Guard) Option {
+	return func(a *api) { a.idempotency = g }
+}
+
+// idempotent запоминает первый ответ на запрос с Idempotency-Key и отдаёт его
+// на повторы с тем же ключом от того же клиента. Ответы 5xx не запоминаются:
+// повтор после сбоя должен выполниться заново.
+//
+// Ответы с секретами (новый API-ключ, секрет OAuth-клиента) через idempotent не
+// пропускаются, чтобы секрет не оседал в хранилище.
+func (api *api) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if api.idempotency == nil || key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLen))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		scope := fmt.Sprintf("%d|%s|%s %s|%s", tenant.FromContext(ctx), idempotencyClient(r), r.Method, routeTemplate(r), key)
+		fingerprint := requestFingerprint(r, body)
+
+		rec, ok, err := api.idempotency.Begin(ctx, scope, fingerprint)
+		if err != nil {
+			api.logger.ErrorContext(ctx, "Idempotency store failed", "error", err)
+			writeProblem(w, r, http.StatusServiceUnavailable, "idempotency store unavailable, retry later")
+			return
+		}
+		if !ok {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				writeProblem(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			case rec.Response == nil:
+				w.Header().Set("Retry-After", "1")
+				writeProblem(w, r, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+			default:
+				replay(w, *rec.Response)
+			}
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// сбой или паника: ключ освобождается, чтобы повтор выполнился заново
+			if !completed {
+				if err := api.idempotency.Abort(context.WithoutCancel(ctx), scope); err != nil {
+					api.logger.ErrorContext(ctx, "Failed to release idempotency key", "error", err)
+				}
+			}
+		}()
+		next(capture, r)
+
+		if capture.status >= http.StatusInternalServerError {
+			return
+		}
+		resp := idempotency.Response{Status: capture.status, ContentType: w.Header().Get("Content-Type"), Body: capture.body.Bytes()}
+		if err := api.idempotency.Complete(context.WithoutCancel(ctx), scope, resp); err != nil {
+			api.logger.ErrorContext(ctx, "Failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// idempotencyClient — чьи ключи: у разных клиентов одинаковые ключи не пересекаются.
+func idempotencyClient(r *http.Request) string {
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		switch {
+		case p.APIKeyID != 0:
+			return "apikey:" + strconv.Itoa(p.APIKeyID)
+		case p.UserID != 0:
+			return "user:" + strconv.Itoa(p.UserID)
+		case p.ClientID != "":
+			return "client:" + p.ClientID
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// requestFingerprint — отпечаток запроса, чтобы отличить повтор от другого
+// запроса с тем же ключом. Сохранённый ответ отдаётся как есть, поэтому в
+// отпечаток входит и формат ответа: повтор с другим Accept получит 422, а не
+// тело в чужом формате.
+func requestFingerprint(r *http.Request, body []byte) string {
+	mediaType := ""
+	if f := negotiated(r, false); f != nil {
+		mediaType = f.mediaType
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n%s\n", r.Method, r.URL.RequestURI(), mediaType)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, resp idempotency.Response) {
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// responseCapture копирует ответ, чтобы сохранить его для повторов.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"leti/pkg/idempotency"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	r := newTestAPI(service.NewService(repo), WithIdempotency(idempotency.New(idempotency.NewMemoryStore())))
+
+	postAs := func(accept, key, remoteAddr, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/authors", bytes.NewBufferString(body))
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	post := func(key, remoteAddr, body string) *httptest.ResponseRecorder {
+		return postAs("", key, remoteAddr, body)
+	}
+	authors := func() []models.Author {
+		list, err := repo.GetAllAuthors(t.Context())
+		require.NoError(t, err)
+		return list
+	}
+
+	first := post("k1", "10.0.0.1:1000", `{"author":"Гончаров"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	replayed := post("k1", "10.0.0.1:1001", `{"author":"Гончаров"}`)
+	require.Equal(t, http.StatusCreated, replayed.Code)
+	require.JSONEq(t, first.Body.String(), replayed.Body.String())
+	require.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	require.Len(t, authors(), 1, "повтор не создаёт второго автора")
+
+	// тот же ключ с другим телом — ошибка клиента
+	require.Equal(t, http.StatusUnprocessableEntity, post("k1", "10.0.0.1:1002", `{"author":"Лесков"}`).Code)
+	// и с другим форматом ответа: сохранённое тело в JSON не отдаётся как XML
+	require.Equal(t, http.StatusUnprocessableEntity, postAs("application/xml", "k1", "10.0.0.1:1002", `{"author":"Гончаров"}`).Code)
+	// Accept, означающий тот же JSON, — обычный повтор
+	require.Equal(t, "true", postAs("application/json, */*;q=0.5", "k1", "10.0.0.1:1002", `{"author":"Гончаров"}`).Header().Get("Idempotent-Replayed"))
+
+	// ключи разных клиентов не пересекаются, без ключа запрос выполняется как обычно
+	require.Equal(t, http.StatusCreated, post("k1", "10.0.0.2:1000", `{"author":"Гончаров"}`).Code)
+	require.Equal(t, http.StatusCreated, post("", "10.0.0.1:1003", `{"author":"Гончаров"}`).Code)
+	require.Len(t, authors(), 3)
+
+	// ошибки валидации тоже запоминаются: повтор получит тот же ответ
+	invalid := post("k2", "10.0.0.1:1004", `{"author":""}`)
+	require.Equal(t, http.StatusUnprocessableEntity, invalid.Code)
+	require.Equal(t, invalid.Body.String(), post("k2", "10.0.0.1:1005", `{"author":""}`).Body.String())
+}
+
+func TestIdempotency_ServerErrorIsNotStored(t *testing.T) {
+	repo := &fake.FakeRepo{NewGenreErr: errors.New("connection reset")}
+	r := newTestAPI(service.NewService(repo), WithIdempotency(idempotency.New(idempotency.NewMemoryStore())))
+
+	post := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/genres", bytes.NewBufferString(`{"genre":"Сказка"}`))
+		req.Header.Set("Idempotency-Key", "retry-me")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusInternalServerError, post())
+
+	repo.NewGenreErr = nil
+	require.Equal(t, http.StatusCreated, post(), "после сбоя повтор выполняется заново")
+}
+
+func TestIdempotency_ConcurrentDuplicate(t *testing.T) {
+	a := &api{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), idempotency: idempotency.New(idempotency.NewMemoryStore())}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	h := a.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": 1})
+	})
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewBufferString(`{}`))
+		req.Header.Set("Idempotency-Key", "slow")
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = send()
+	}()
+	<-started
+	dup := send()
+	require.Equal(t, http.StatusConflict, dup.Code)
+	require.Equal(t, "1", dup.Header().Get("Retry-After"))
+
+	close(release)
+	wg.Wait()
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Equal(t, http.StatusCreated, send().Code)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func attemptLogin(t *testing.T, ts *httptest.Server, username, password string) *http.Response {
+	t.Helper()
+	resp := doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/login",
+		marshal(t, auth.LoginRequest{Username: username, Password: password})))
+	resp.Body.Close()
+	return resp
+}
+
+func TestLogin_LockoutAfterRepeatedFailures(t *testing.T) {
+	ts, repo := newMFATestServer(t, auth.RoleUser)
+
+	// неизвестное имя и неверный пароль неотличимы
+	require.Equal(t, http.StatusUnauthorized, attemptLogin(t, ts, "ghost", "password").StatusCode)
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusUnauthorized, attemptLogin(t, ts, "Den", "wrong").StatusCode)
+	}
+
+	// заблокирован даже с верным паролем
+	resp := attemptLogin(t, ts, "Den", "password")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+	adminID := repo.AddUser(models.User{Username: "admin", Role: auth.RoleAdmin})
+	adminToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/admin/lockout/unlock", adminToken,
+		marshal(t, dto.UnlockLoginRequest{Username: "Den"})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Equal(t, http.StatusOK, attemptLogin(t, ts, "Den", "password").StatusCode)
+}
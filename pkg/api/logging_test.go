@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogs_NeverContainCredentials(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	repo.AddUser(models.User{Username: "Den", Password: hash, Role: auth.RoleAdmin})
+	router, logs := newLoggedTestAPI(t, service.NewService(repo))
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	login := loginAs(t, ts, "Den", "password")
+	require.NotEmpty(t, login.AccessToken)
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/admin/api-keys", login.AccessToken,
+		marshal(t, dto.CreateAPIKeyRequest{Name: "import", Scopes: []string{auth.ScopeBooksWrite}})))
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var key dto.CreateAPIKeyResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&key))
+	resp.Body.Close()
+
+	me := func(authorization string) int {
+		req := newRequest(t, http.MethodGet, ts.URL+"/api/me", nil)
+		req.Header.Set("Authorization", authorization)
+		resp := doRequest(t, req)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	tampered := login.AccessToken[:len(login.AccessToken)-4] + "AAAA"
+	wrongKey := key.Prefix + "_" + strings.Repeat("0", 48)
+	require.Equal(t, http.StatusOK, me("Bearer "+login.AccessToken))
+	require.Equal(t, http.StatusOK, me(auth.APIKeyHeaderScheme+key.Key))
+	require.Equal(t, http.StatusUnauthorized, me("Bearer "+tampered))
+	require.Equal(t, http.StatusUnauthorized, me(auth.APIKeyHeaderScheme+wrongKey))
+
+	out := logs.String()
+	require.Contains(t, out, "JWT parse error")
+	require.Contains(t, out, "API key auth error")
+	for _, secret := range []string{login.AccessToken, login.RefreshToken, tampered, key.Key, wrongKey, "password"} {
+		if secret == "" {
+			continue
+		}
+		require.NotContains(t, out, secret)
+	}
+	// подпись и полезная нагрузка токена не должны попасть в лог даже частично
+	parts := strings.Split(login.AccessToken, ".")
+	require.NotContains(t, out, parts[1])
+	require.NotContains(t, out, parts[2])
+}
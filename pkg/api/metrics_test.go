@@ -0,0 +1,56 @@
+package api
+
+import (
+	"io"
+	"leti/pkg/auth"
+	"leti/pkg/metrics"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics_HTTPAndLogins(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	repo.AddUser(models.User{Username: "Den", Password: hash, Role: auth.RoleUser})
+	m := metrics.New()
+	srv := service.NewService(m.InstrumentDB(repo), service.WithMetrics(m))
+	ts := httptest.NewServer(newTestAPI(srv, WithMetrics(m)))
+	defer ts.Close()
+
+	for _, id := range []string{"1", "2", "3"} {
+		resp := doRequest(t, newRequest(t, http.MethodGet, ts.URL+"/api/book?id="+id, nil))
+		resp.Body.Close()
+	}
+	resp := doRequest(t, newRequest(t, http.MethodGet, ts.URL+"/no/such/path", nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, attemptLogin(t, ts, "Den", "password").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, attemptLogin(t, ts, "Den", "wrong").StatusCode)
+
+	out := scrape(t, m)
+	// метка — шаблон маршрута, а не сырой URL с параметрами
+	require.Contains(t, out, `library_http_requests_total{method="GET",route="/api/book",status="404"} 3`)
+	require.Contains(t, out, `library_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	require.Contains(t, out, `library_http_request_duration_seconds_count{method="GET",route="/api/book"} 3`)
+	require.NotContains(t, out, "id=1")
+	require.Contains(t, out, `library_logins_total{result="success"} 1`)
+	require.Contains(t, out, `library_logins_total{result="failure"} 1`)
+	require.Contains(t, out, `library_db_query_duration_seconds_count{method="GetBookByID",outcome="rejected"} 3`)
+	require.Contains(t, out, `library_db_query_duration_seconds_count{method="GetUserByUsername",outcome="ok"} 2`)
+}
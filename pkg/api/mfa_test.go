@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/lockout"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newMFATestServer(t *testing.T, role string) (*httptest.Server, *fake.FakeRepo) {
+	t.Helper()
+	repo := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	repo.AddUser(models.User{Username: "Den", Password: hash, Role: role})
+	ts := httptest.NewServer(newTestAPI(service.NewService(repo)))
+	t.Cleanup(ts.Close)
+	return ts, repo
+}
+
+func loginAs(t *testing.T, ts *httptest.Server, username, password string) auth.LoginResponse {
+	t.Helper()
+	resp := doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/login",
+		marshal(t, auth.LoginRequest{Username: username, Password: password})))
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var login auth.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+	return login
+}
+
+func totpCode(t *testing.T, secret string, skew int64) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+skew)
+	require.NoError(t, err)
+	return code
+}
+
+func TestMFA_EnrollAndTwoStepLogin(t *testing.T) {
+	ts, _ := newMFATestServer(t, auth.RoleUser)
+	token := loginAs(t, ts, "Den", "password").AccessToken
+	require.NotEmpty(t, token)
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/enroll", token, nil))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var enrollment dto.TOTPEnrollmentResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+	resp.Body.Close()
+	require.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+	require.NotEmpty(t, enrollment.QRPNG)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/verify", token,
+		marshal(t, dto.TOTPCodeRequest{Code: "000000"})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	enrollCode := totpCode(t, enrollment.Secret, 0)
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/verify", token,
+		marshal(t, dto.TOTPCodeRequest{Code: enrollCode})))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var recovery dto.RecoveryCodesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&recovery))
+	resp.Body.Close()
+	require.Len(t, recovery.RecoveryCodes, 10)
+
+	// теперь пароля недостаточно
+	login := loginAs(t, ts, "Den", "password")
+	require.True(t, login.MFARequired)
+	require.Empty(t, login.AccessToken)
+	require.NotEmpty(t, login.ChallengeToken)
+
+	secondStep := func(code string) *http.Response {
+		return doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/login/2fa",
+			marshal(t, auth.MFALoginRequest{ChallengeToken: login.ChallengeToken, Code: code})))
+	}
+
+	// код, уже использованный при подключении, повторно не принимается
+	resp = secondStep(enrollCode)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = secondStep(totpCode(t, enrollment.Secret, 1))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var final auth.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&final))
+	resp.Body.Close()
+	require.NotEmpty(t, final.AccessToken)
+
+	// код восстановления срабатывает ровно один раз
+	resp = secondStep(recovery.RecoveryCodes[0])
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = secondStep(recovery.RecoveryCodes[0])
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// access-токен нельзя подменить challenge-токеном
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/enroll", login.ChallengeToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodDelete, ts.URL+"/api/auth/2fa", final.AccessToken,
+		marshal(t, dto.TOTPCodeRequest{Code: recovery.RecoveryCodes[1]})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.NotEmpty(t, loginAs(t, ts, "Den", "password").AccessToken)
+}
+
+func TestMFA_RequiredRoleGetsEnrollmentOnlyToken(t *testing.T) {
+	ts, repo := newMFATestServer(t, auth.RoleAdmin)
+	require.NoError(t, repo.SetMFARequiredRoles(t.Context(), []string{auth.RoleAdmin}))
+
+	login := loginAs(t, ts, "Den", "password")
+	require.True(t, login.MFAEnrollmentRequired)
+	require.NotEmpty(t, login.AccessToken)
+
+	// токен годится только для подключения 2FA
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/admin/api-keys", login.AccessToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/enroll", login.AccessToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMFA_AdminRequiredRoles(t *testing.T) {
+	ts, repo := newMFATestServer(t, auth.RoleUser)
+	adminID := repo.AddUser(models.User{Username: "admin", Role: auth.RoleAdmin})
+	adminToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPut, ts.URL+"/api/admin/mfa/required-roles", adminToken,
+		marshal(t, dto.MFARequiredRolesRequest{Roles: []string{"root"}})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPut, ts.URL+"/api/admin/mfa/required-roles", adminToken,
+		marshal(t, dto.MFARequiredRolesRequest{Roles: []string{auth.RoleUser}})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/admin/mfa/required-roles", adminToken, nil))
+	var roles dto.MFARequiredRolesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&roles))
+	resp.Body.Close()
+	require.Equal(t, []string{auth.RoleUser}, roles.Roles)
+
+	require.True(t, loginAs(t, ts, "Den", "password").MFAEnrollmentRequired)
+}
+
+// enrollTOTP включает пользователю 2FA и возвращает секрет и коды восстановления.
+func enrollTOTP(t *testing.T, ts *httptest.Server, token string) (string, []string) {
+	t.Helper()
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/enroll", token, nil))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var enrollment dto.TOTPEnrollmentResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+	resp.Body.Close()
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/verify", token,
+		marshal(t, dto.TOTPCodeRequest{Code: totpCode(t, enrollment.Secret, 0)})))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var recovery dto.RecoveryCodesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&recovery))
+	resp.Body.Close()
+	return enrollment.Secret, recovery.RecoveryCodes
+}
+
+func TestMFA_SecondFactorLockout(t *testing.T) {
+	ts, _ := newMFATestServer(t, auth.RoleUser)
+	secret, recovery := enrollTOTP(t, ts, loginAs(t, ts, "Den", "password").AccessToken)
+
+	challenge := loginAs(t, ts, "Den", "password").ChallengeToken
+	secondStep := func(challenge, code string) *http.Response {
+		return doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/login/2fa",
+			marshal(t, auth.MFALoginRequest{ChallengeToken: challenge, Code: code})))
+	}
+
+	// неверные коды считаются, как пароли: после DefaultUserPolicy.Threshold ошибок — 429
+	for i := 1; i < lockout.DefaultUserPolicy.Threshold; i++ {
+		resp := secondStep(challenge, "000000")
+		resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode, "attempt %d", i)
+	}
+	resp := secondStep(challenge, "000000")
+	resp.Body.Close()
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	// погашенный challenge больше не принимается даже с верным кодом
+	resp = secondStep(challenge, totpCode(t, secret, 1))
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// а новый упирается в блокировку пользователя
+	fresh := loginAs(t, ts, "Den", "password").ChallengeToken
+	resp = secondStep(fresh, recovery[0])
+	resp.Body.Close()
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
@@ -1,22 +1,47 @@
 package api
 
 import (
-	"context"
+	"leti/pkg/auth"
+	"leti/pkg/tenant"
 	"net/http"
-	"time"
 )
 
 func (api *api) middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		api.logger.Info("Received request", "method", r.Method, "path", r.URL.Path)
 		w.Header().Set("Content-Type", "application/json")
 
-		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-		defer cancel()
+		if !api.RightAuth(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		r = r.WithContext(ctx)
+// resolveTenant определяет библиотеку по поддомену (<slug>.<tenantDomain>).
+// Без поддомена запрос относится к библиотеке из токена или к библиотеке по умолчанию.
+func (api *api) resolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slug := tenant.SlugFromHost(r.Host, api.tenantDomain)
+		if slug == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		t, err := api.srv.ResolveTenant(r.Context(), slug)
+		if err != nil {
+			writeProblem(w, r, http.StatusNotFound, "tenant not found")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), t.ID)))
+	})
+}
 
-		if !api.RightAuth(w, r) {
+// requirePlatformAdmin пропускает только клиентов библиотеки по умолчанию: её
+// администраторы управляют всей платформой, администраторы остальных — только своей библиотекой.
+func (api *api) requirePlatformAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok || principal.TenantID != tenant.DefaultID {
+			writeProblem(w, r, http.StatusForbidden, "available to platform administrators only")
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://reader.example/callback"
+
+func newOAuthTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	repo := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	adminID := repo.AddUser(models.User{Username: "Den", Password: hash, Role: auth.RoleAdmin})
+
+	ts := httptest.NewServer(newTestAPI(service.NewService(repo)))
+	t.Cleanup(ts.Close)
+
+	token, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+	return ts, token
+}
+
+func registerOAuthClient(t *testing.T, baseURL, adminToken string, req dto.CreateOAuthClientRequest) dto.CreateOAuthClientResponse {
+	t.Helper()
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, baseURL+"/api/admin/oauth-clients", adminToken, marshal(t, req)))
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var client dto.CreateOAuthClientResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&client))
+	return client
+}
+
+func postForm(t *testing.T, endpoint string, form url.Values, clientID, secret string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if secret != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	return doRequest(t, req)
+}
+
+// noRedirectClient позволяет прочитать Location вместо перехода по нему.
+var noRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+func TestOAuth_AuthorizationCodeWithPKCE(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+	client := registerOAuthClient(t, ts.URL, adminToken, dto.CreateOAuthClientRequest{
+		Name:         "Reading App",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{auth.ScopeProfileRead},
+		GrantTypes:   []string{auth.GrantTypeAuthorizationCode},
+	})
+	require.Empty(t, client.ClientSecret)
+
+	verifier := strings.Repeat("v", 64)
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {auth.ScopeProfileRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {auth.PKCEChallengeS256(verifier)},
+		"code_challenge_method": {auth.PKCEMethodS256},
+	}
+
+	resp, err := http.Get(ts.URL + "/oauth/authorize?" + params.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+
+	consent := url.Values{}
+	for k, v := range params {
+		consent[k] = v
+	}
+	consent.Set("username", "Den")
+	consent.Set("password", "password")
+	consent.Set("decision", "approve")
+	resp, err = noRedirectClient.PostForm(ts.URL+"/oauth/authorize", consent)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {auth.GrantTypeAuthorizationCode},
+		"client_id":     {client.ClientID},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	}
+	resp = postForm(t, ts.URL+"/oauth/token", exchange, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var token auth.TokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	resp.Body.Close()
+	require.Equal(t, auth.ScopeProfileRead, token.Scope)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/oauth/userinfo", token.AccessToken, nil))
+	var info dto.UserInfoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	resp.Body.Close()
+	require.Equal(t, "Den", info.Username)
+
+	// токен приложения не даёт прав, на которые пользователь не соглашался
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/admin/api-keys", token.AccessToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// код одноразовый
+	resp = postForm(t, ts.URL+"/oauth/token", exchange, "", "")
+	var oauthErr auth.OAuthError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&oauthErr))
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, auth.OAuthErrInvalidGrant, oauthErr.Code)
+}
+
+func TestOAuth_AuthorizeRejectsUnknownRedirect(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+	client := registerOAuthClient(t, ts.URL, adminToken, dto.CreateOAuthClientRequest{
+		Name:         "Reading App",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{auth.ScopeProfileRead},
+		GrantTypes:   []string{auth.GrantTypeAuthorizationCode},
+	})
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {"https://evil.example/steal"},
+		"code_challenge":        {auth.PKCEChallengeS256(strings.Repeat("v", 64))},
+		"code_challenge_method": {auth.PKCEMethodS256},
+	}
+	resp, err := noRedirectClient.Get(ts.URL + "/oauth/authorize?" + params.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOAuth_ClientCredentialsAndIntrospection(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+	client := registerOAuthClient(t, ts.URL, adminToken, dto.CreateOAuthClientRequest{
+		Name:         "Partner import",
+		Scopes:       []string{auth.ScopeBooksWrite},
+		GrantTypes:   []string{auth.GrantTypeClientCredentials},
+		Confidential: true,
+	})
+	require.NotEmpty(t, client.ClientSecret)
+
+	resp := postForm(t, ts.URL+"/oauth/token", url.Values{"grant_type": {auth.GrantTypeClientCredentials}}, client.ClientID, "wrong")
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = postForm(t, ts.URL+"/oauth/token", url.Values{"grant_type": {auth.GrantTypeClientCredentials}}, client.ClientID, client.ClientSecret)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	var token auth.TokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	resp.Body.Close()
+
+	createAuthor(t, ts.URL, "Dostoevsky")
+	createGenre(t, ts.URL, "Novel")
+	bookID := createBook(t, ts.URL, token.AccessToken, dto.CreateBookRequest{Name: "Idiot", AuthorID: 1, GenreID: 1, Price: 100})
+	require.Equal(t, 1, bookID)
+
+	resp = postForm(t, ts.URL+"/oauth/introspect", url.Values{"token": {token.AccessToken}}, client.ClientID, client.ClientSecret)
+	var introspection auth.IntrospectionResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&introspection))
+	resp.Body.Close()
+	require.True(t, introspection.Active)
+	require.Equal(t, client.ClientID, introspection.ClientID)
+
+	resp = postForm(t, ts.URL+"/oauth/introspect", url.Values{"token": {"garbage"}}, client.ClientID, client.ClientSecret)
+	introspection = auth.IntrospectionResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&introspection))
+	resp.Body.Close()
+	require.False(t, introspection.Active)
+}
+
+func TestOAuth_AuthorizeRequiresSecondFactor(t *testing.T) {
+	ts, repo := newMFATestServer(t, auth.RoleUser)
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	adminID := repo.AddUser(models.User{Username: "Olga", Password: hash, Role: auth.RoleAdmin})
+	adminToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+	client := registerOAuthClient(t, ts.URL, adminToken, dto.CreateOAuthClientRequest{
+		Name:         "Reading App",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{auth.ScopeProfileRead},
+		GrantTypes:   []string{auth.GrantTypeAuthorizationCode},
+	})
+	_, recovery := enrollTOTP(t, ts, loginAs(t, ts, "Den", "password").AccessToken)
+
+	approve := func(fields url.Values) (*http.Response, string) {
+		form := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {client.ClientID},
+			"redirect_uri":          {testRedirectURI},
+			"scope":                 {auth.ScopeProfileRead},
+			"state":                 {"xyz"},
+			"code_challenge":        {auth.PKCEChallengeS256(strings.Repeat("v", 64))},
+			"code_challenge_method": {auth.PKCEMethodS256},
+			"decision":              {"approve"},
+		}
+		for k, v := range fields {
+			form[k] = v
+		}
+		resp, err := noRedirectClient.PostForm(ts.URL+"/oauth/authorize", form)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+	challengeOf := func(page string) string {
+		m := regexp.MustCompile(`name="challenge_token" value="([^"]+)"`).FindStringSubmatch(page)
+		require.Len(t, m, 2, "форма должна спрашивать код")
+		return m[1]
+	}
+
+	// пароля мало: вместо кода авторизации — форма для второго фактора
+	resp, page := approve(url.Values{"username": {"Den"}, "password": {"password"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Location"))
+	challenge := challengeOf(page)
+
+	resp, page = approve(url.Values{"challenge_token": {challenge}, "code": {"000000"}})
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, challenge, challengeOf(page))
+
+	resp, _ = approve(url.Values{"challenge_token": {"forged"}, "code": {recovery[0]}})
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, _ = approve(url.Values{"challenge_token": {challenge}, "code": {recovery[0]}})
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.NotEmpty(t, location.Query().Get("code"))
+
+	// роль требует 2FA, а она не подключена — кода не будет
+	require.NoError(t, repo.SetMFARequiredRoles(t.Context(), []string{auth.RoleAdmin}))
+	resp, _ = approve(url.Values{"username": {"Olga"}, "password": {"password"}})
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Location"))
+}
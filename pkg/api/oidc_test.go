@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/auth"
+	"leti/pkg/auth/oidctest"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDC_LoginProvisionsUser(t *testing.T) {
+	idp := oidctest.NewProvider("library", "idp-secret")
+	defer idp.Close()
+
+	repo := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	repo.AddUser(models.User{Username: "Den", Password: hash, Role: auth.RoleAdmin})
+
+	r := mux.NewRouter()
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	provider := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:       idp.Issuer,
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  ts.URL + "/api/auth/oidc/callback",
+		GroupRoles:   map[string]string{"library-admins": auth.RoleAdmin},
+		DefaultRole:  auth.RoleUser,
+	})
+	jwtService := auth.NewJWTService(testJWTSecret)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	New(r, service.NewService(repo), logger, jwtService, WithOIDC(provider)).RegistreRoutes()
+
+	oidcLogin := func() *auth.Claims {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		client := &http.Client{Jar: jar}
+		resp, err := client.Get(ts.URL + "/api/auth/oidc/login")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var login auth.LoginResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+		claims, err := jwtService.ParseToken(login.AccessToken)
+		require.NoError(t, err)
+		return claims
+	}
+
+	idp.SetUser(oidctest.User{Subject: "emp-1", Username: "anna", Groups: []string{"library-admins"}})
+	first := oidcLogin()
+	require.Equal(t, auth.RoleAdmin, first.Role)
+
+	user, err := repo.GetUserByIdentity(t.Context(), idp.Issuer, "emp-1")
+	require.NoError(t, err)
+	require.Equal(t, "anna", user.Username)
+
+	// повторный вход — тот же пользователь, роль пересчитана по группам
+	idp.SetUser(oidctest.User{Subject: "emp-1", Username: "anna", Groups: []string{"readers"}})
+	second := oidcLogin()
+	require.Equal(t, first.UserID, second.UserID)
+	require.Equal(t, auth.RoleUser, second.Role)
+
+	// совпадение имени с локальной учёткой не даёт к ней доступа
+	idp.SetUser(oidctest.User{Subject: "emp-2", Username: "Den", Email: "den@corp.example"})
+	other := oidcLogin()
+	require.NotEqual(t, 1, other.UserID)
+
+	// вход по паролю по-прежнему работает
+	require.NotEmpty(t, login(t, ts.URL, "Den", "password"))
+}
+
+func TestOIDC_CallbackWithoutFlowCookie(t *testing.T) {
+	idp := oidctest.NewProvider("library", "idp-secret")
+	defer idp.Close()
+
+	r := mux.NewRouter()
+	provider := auth.NewOIDCProvider(auth.OIDCConfig{Issuer: idp.Issuer, ClientID: idp.ClientID})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	New(r, service.NewService(&fake.FakeRepo{}), logger, auth.NewJWTService(testJWTSecret), WithOIDC(provider)).RegistreRoutes()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=x&state=y", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
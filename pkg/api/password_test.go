@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/auth"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePassword_RevokesExistingSessions(t *testing.T) {
+	ts, _ := newMFATestServer(t, auth.RoleUser)
+	oldToken := loginAs(t, ts, "Den", "password").AccessToken
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/password", oldToken,
+		marshal(t, auth.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password"})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/password", oldToken,
+		marshal(t, auth.ChangePasswordRequest{CurrentPassword: "password", NewPassword: "new-password"})))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var changed auth.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&changed))
+	resp.Body.Close()
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/enroll", oldToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/auth/2fa/enroll", changed.AccessToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NotEmpty(t, loginAs(t, ts, "Den", "new-password").AccessToken)
+}
+
+func TestPasswordReset_UnknownUserLooksTheSame(t *testing.T) {
+	ts, _ := newMFATestServer(t, auth.RoleUser)
+
+	for _, username := range []string{"Den", "nobody"} {
+		resp := doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/password/reset",
+			marshal(t, auth.PasswordResetRequest{Username: username})))
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+
+	resp := doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/password/reset/confirm",
+		marshal(t, auth.PasswordResetConfirmRequest{Token: "bogus", NewPassword: "new-password"})))
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"leti/pkg/apperr"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem — тело ошибки по RFC 7807. Type не заполняем: about:blank означает,
+// что смысл ошибки передаёт HTTP-статус, а подробности — Detail.
+type Problem struct {
+	Type     string              `json:"type,omitempty"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
+// writeProblem отвечает ошибкой в формате application/problem+json.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblemBody(w, Problem{
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
+
+func writeProblemBody(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// writeError выбирает статус по виду ошибки (см. пакет apperr). Непредвиденные
+// ошибки логируются с msg, а клиент видит только «internal server error».
+// Клиенту показывается лишь Msg из *apperr.Error, иначе — текст вида ошибки.
+func (api *api) writeError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		api.logger.WarnContext(r.Context(), msg, "error", err)
+		writeProblem(w, r, http.StatusServiceUnavailable, "request timed out")
+		return
+	}
+	status := statusForError(err)
+	if status == http.StatusInternalServerError {
+		api.logger.ErrorContext(r.Context(), msg, "error", err)
+		writeProblem(w, r, status, "internal server error")
+		return
+	}
+
+	detail, ok := apperr.Message(err)
+	if !ok {
+		// вид есть, а текста для клиента нет: err.Error() может содержать
+		// подробности хранилища, поэтому наружу — только общий текст вида
+		api.logger.WarnContext(r.Context(), msg, "error", err)
+		detail = apperr.Kind(err).Error()
+	}
+	writeProblemBody(w, Problem{
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   apperr.Fields(err),
+	})
+}
+
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperr.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"leti/pkg/api/dto"
+	"leti/pkg/apperr"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeProblem(t *testing.T, resp *http.Response) Problem {
+	t.Helper()
+	defer resp.Body.Close()
+	require.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	require.Equal(t, resp.StatusCode, p.Status)
+	return p
+}
+
+func TestProblem_ForeignKeyViolation(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+	genreID := createGenre(t, ts.URL, "Novel")
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/books", adminToken,
+		marshal(t, dto.CreateBookRequest{Name: "Idiot", AuthorID: 42, GenreID: genreID, Price: 100})))
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	p := decodeProblem(t, resp)
+	require.Len(t, p.Errors, 1)
+	require.Equal(t, "author_id", p.Errors[0].Field)
+}
+
+func TestProblem_NotFound(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+
+	resp := doRequest(t, newRequest(t, http.MethodGet, ts.URL+"/api/book?id=7", nil))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	p := decodeProblem(t, resp)
+	require.Equal(t, "/api/book", p.Instance)
+	require.Contains(t, p.Detail, "not found")
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPatch, ts.URL+"/api/books?id=7", adminToken,
+		marshal(t, dto.UpdateBookRequest{Price: ptr(1)})))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	decodeProblem(t, resp)
+}
+
+func TestWriteError_SentinelWithoutMessage(t *testing.T) {
+	var logs bytes.Buffer
+	a := &api{logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	w := httptest.NewRecorder()
+	err := fmt.Errorf("select from books_v2 where id=7: %w", apperr.ErrNotFound)
+	a.writeError(w, httptest.NewRequest(http.MethodGet, "/api/book?id=7", nil), "Failed to get book", err)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	// текст обёртки остаётся в журнале, клиент видит только вид ошибки
+	require.Equal(t, "not found", p.Detail)
+	require.Contains(t, logs.String(), "books_v2")
+}
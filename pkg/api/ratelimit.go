@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"leti/pkg/auth"
+	"leti/pkg/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithRateLimiter включает ограничение частоты запросов (см. rateLimit).
+func WithRateLimiter(l *ratelimit.Limiter) Option {
+	return func(a *api) { a.limiter = l }
+}
+
+type apiKeyPrincipalKey struct{}
+
+// rateLimit списывает запрос с корзины клиента. Клиент — пользователь или
+// приложение из проверенного токена, API-ключ или, для анонимных запросов, IP.
+// Непроверенные учётные данные не дают отдельной корзины: иначе случайный
+// заголовок Authorization обходил бы лимит по IP.
+func (api *api) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := routeTemplate(r)
+		res, err := api.limiter.Allow(r.Context(), route, api.rateLimitClient(r))
+		if err == nil && res.Allowed {
+			if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), auth.APIKeyHeaderScheme); ok {
+				r, res, err = api.rateLimitAPIKey(r, route, key, res)
+			}
+		}
+		if err != nil {
+			// недоступное хранилище лимитов не должно останавливать сервис
+			api.logger.ErrorContext(r.Context(), "Rate limiter failed", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Capacity()))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", res.Limit.Requests, ceilSeconds(res.Limit.Period), res.Limit.Capacity()))
+		if !res.Allowed {
+			retry := max(1, ceilSeconds(res.RetryAfter))
+			h.Set("Retry-After", strconv.Itoa(retry))
+			writeProblem(w, r, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry after %d s", retry))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClient определяет, чей бюджет расходует запрос до проверки API-ключа.
+// Запрос с ключом сначала платит из корзины IP: ключ проверяется по базе, и
+// поток поддельных ключей не должен превращаться в неограниченные запросы к ней.
+func (api *api) rateLimitClient(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		claims, err := api.jwtService.ParseToken(token)
+		if err == nil && claims.UserID != 0 {
+			return "user:" + strconv.Itoa(claims.UserID)
+		}
+		if err == nil && claims.ClientID != "" {
+			return "client:" + claims.ClientID
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// rateLimitAPIKey проверяет ключ, уже оплативший запрос из корзины IP, и
+// списывает запрос с корзины самого ключа. Проверенный ключ кладётся в
+// контекст, чтобы RightAuth не проверял его второй раз. Неверный ключ
+// оставляет в силе результат по IP — дальше его отклонит RightAuth.
+func (api *api) rateLimitAPIKey(r *http.Request, route, key string, ipRes ratelimit.Result) (*http.Request, ratelimit.Result, error) {
+	p, err := api.srv.AuthenticateAPIKey(r.Context(), key)
+	if err != nil {
+		return r, ipRes, nil
+	}
+	r = r.WithContext(context.WithValue(r.Context(), apiKeyPrincipalKey{}, p))
+	res, err := api.limiter.Allow(r.Context(), route, "apikey:"+strconv.Itoa(p.APIKeyID))
+	return r, res, err
+}
+
+// verifiedAPIKey — API-ключ, уже проверенный в rateLimit.
+func verifiedAPIKey(ctx context.Context) (*auth.Principal, bool) {
+	p, ok := ctx.Value(apiKeyPrincipalKey{}).(*auth.Principal)
+	return p, ok
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
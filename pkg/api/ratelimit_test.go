@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/ratelimit"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 100, Period: time.Minute},
+		ratelimit.WithRoute("/api/books/withauthors", ratelimit.Limit{Requests: 2, Period: time.Minute}))
+	r := newTestAPI(service.NewService(&fake.FakeRepo{}), WithRateLimiter(limiter))
+
+	get := func(remoteAddr, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/books/withauthors", nil)
+		req.RemoteAddr = remoteAddr
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("10.0.0.1:5000", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2;w=60;burst=2", w.Header().Get("RateLimit-Policy"))
+
+	require.Equal(t, http.StatusOK, get("10.0.0.1:5001", "").Code)
+	w = get("10.0.0.1:5002", "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+	require.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	// поддельный токен не даёт отдельной корзины
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.1:5003", "Bearer forged").Code)
+
+	// пользователь с настоящим токеном расходует свой бюджет, а не бюджет IP
+	token, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: 7, Role: auth.RoleUser})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, get("10.0.0.1:5004", "Bearer "+token).Code)
+
+	// другие IP и маршруты не затронуты
+	require.Equal(t, http.StatusOK, get("10.0.0.2:5000", "").Code)
+	req := httptest.NewRequest(http.MethodGet, "/api/books", nil)
+	req.RemoteAddr = "10.0.0.1:5005"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "100", w.Header().Get("RateLimit-Limit"))
+}
+
+// countingKeysRepo считает обращения к базе за API-ключом.
+type countingKeysRepo struct {
+	*fake.FakeRepo
+	lookups int
+}
+
+func (r *countingKeysRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	r.lookups++
+	return r.FakeRepo.GetAPIKeyByPrefix(ctx, prefix)
+}
+
+func TestRateLimit_APIKeyHasOwnBudget(t *testing.T) {
+	repo := &countingKeysRepo{FakeRepo: &fake.FakeRepo{}}
+	ownerID := repo.AddUser(models.User{Username: "import-bot", Role: auth.RoleUser})
+	srv := service.NewService(repo)
+	_, key, err := srv.CreateAPIKey(t.Context(), ownerID, "import", []string{auth.ScopeProfileRead}, nil)
+	require.NoError(t, err)
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Period: time.Minute})
+	r := newTestAPI(srv, WithRateLimiter(limiter))
+
+	me := func(remoteAddr, authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	// ключ, проверенный ограничителем, принимается и при авторизации
+	require.Equal(t, http.StatusOK, me("10.0.0.1:5000", auth.APIKeyHeaderScheme+key))
+	require.Equal(t, http.StatusOK, me("10.0.0.2:5000", auth.APIKeyHeaderScheme+key))
+	// у ключа своя корзина: с нового IP бюджет ключа всё равно исчерпан
+	require.Equal(t, http.StatusTooManyRequests, me("10.0.0.3:5000", auth.APIKeyHeaderScheme+key))
+
+	// неверный ключ расходует бюджет IP
+	forged := auth.APIKeyHeaderScheme + "lib_00000000_0000"
+	require.Equal(t, http.StatusUnauthorized, me("10.0.0.4:5000", forged))
+	require.Equal(t, http.StatusUnauthorized, me("10.0.0.4:5000", forged))
+	// после исчерпания бюджета IP ключ в базе уже не ищется
+	lookups := repo.lookups
+	require.Equal(t, http.StatusTooManyRequests, me("10.0.0.4:5000", forged))
+	require.Equal(t, http.StatusTooManyRequests, me("10.0.0.4:5000", auth.APIKeyHeaderScheme+key))
+	require.Equal(t, lookups, repo.lookups)
+}
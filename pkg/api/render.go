@@ -0,0 +1,441 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// format — представление ответа (и, кроме CSV, тела запроса).
+type format struct {
+	// name — значение параметра ?format=
+	name      string
+	mediaType string
+	// aliases — другие типы, которые клиенты присылают для того же формата
+	aliases []string
+	// listOnly — формат подходит только для списков (CSV — таблица)
+	listOnly bool
+	encode   func(w io.Writer, v any) error
+	// decode — nil, если тело запроса в этом формате не принимается
+	decode func(r io.Reader, dst any) error
+}
+
+var (
+	jsonFormat = &format{
+		name:      "json",
+		mediaType: "application/json",
+		encode:    func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) },
+	}
+	xmlFormat = &format{
+		name:      "xml",
+		mediaType: "application/xml",
+		aliases:   []string{"text/xml"},
+		encode:    encodeXML,
+		decode:    decodeXML,
+	}
+	csvFormat = &format{
+		name:      "csv",
+		mediaType: "text/csv",
+		listOnly:  true,
+		encode:    encodeCSV,
+	}
+	msgpackFormat = &format{
+		name:      "msgpack",
+		mediaType: "application/msgpack",
+		aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+		encode: func(w io.Writer, v any) error {
+			enc := msgpack.NewEncoder(w)
+			// имена полей те же, что в JSON
+			enc.SetCustomStructTag("json")
+			return enc.Encode(v)
+		},
+		decode: decodeMsgpack,
+	}
+
+	formats = []*format{jsonFormat, xmlFormat, csvFormat, msgpackFormat}
+)
+
+func (f *format) matches(mediaType string) bool {
+	if mediaType == f.mediaType {
+		return true
+	}
+	for _, alias := range f.aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiate выбирает формат ответа по ?format= или Accept. list — ответ
+// является списком (только тогда доступен CSV). При неудаче отвечает 406.
+func (api *api) negotiate(w http.ResponseWriter, r *http.Request, list bool) (*format, bool) {
+	// ответ зависит от Accept: кэши должны хранить представления раздельно
+	w.Header().Add("Vary", "Accept")
+
+	if name := r.URL.Query().Get("format"); name != "" {
+		if f := formatByName(name, list); f != nil {
+			return f, true
+		}
+		writeProblem(w, r, http.StatusNotAcceptable, fmt.Sprintf("format %q is not available here; use %s", name, formatNames(list)))
+		return nil, false
+	}
+
+	if f := acceptable(r.Header.Values("Accept"), list); f != nil {
+		return f, true
+	}
+	writeProblem(w, r, http.StatusNotAcceptable, "none of the accepted media types can be produced; use "+mediaTypes(list))
+	return nil, false
+}
+
+// negotiated — формат, который выберет negotiate, или nil, если подходящего нет.
+func negotiated(r *http.Request, list bool) *format {
+	if name := r.URL.Query().Get("format"); name != "" {
+		return formatByName(name, list)
+	}
+	return acceptable(r.Header.Values("Accept"), list)
+}
+
+func formatByName(name string, list bool) *format {
+	for _, f := range formats {
+		if f.name == name && (list || !f.listOnly) {
+			return f
+		}
+	}
+	return nil
+}
+
+// acceptable разбирает Accept (RFC 9110, 12.5.1) и возвращает самый
+// предпочтительный доступный формат. Без Accept — JSON.
+func acceptable(header []string, list bool) *format {
+	type ranged struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []ranged
+	for _, part := range strings.Split(strings.Join(header, ","), ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, ranged{mediaType: mediaType, q: q})
+		}
+	}
+	if len(header) == 0 {
+		return jsonFormat
+	}
+	// при равном q порядок в заголовке сохраняется
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, rg := range ranges {
+		switch {
+		case rg.mediaType == "*/*", rg.mediaType == "application/*":
+			return jsonFormat
+		case rg.mediaType == "text/*" && list:
+			return csvFormat
+		}
+		for _, f := range formats {
+			if f.matches(rg.mediaType) && (list || !f.listOnly) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func formatNames(list bool) string {
+	var names []string
+	for _, f := range formats {
+		if list || !f.listOnly {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func mediaTypes(list bool) string {
+	var types []string
+	for _, f := range formats {
+		if list || !f.listOnly {
+			types = append(types, f.mediaType)
+		}
+	}
+	return strings.Join(types, ", ")
+}
+
+// write отвечает v в формате f, выбранном negotiate.
+func (api *api) write(w http.ResponseWriter, r *http.Request, f *format, status int, v any) {
+	contentType := f.mediaType
+	if f == csvFormat || f == xmlFormat {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if err := f.encode(w, v); err != nil {
+		api.logger.ErrorContext(r.Context(), "Failed to encode response", "format", f.name, "error", err)
+	}
+}
+
+// requestFormat определяет формат тела по Content-Type; без заголовка — JSON.
+func requestFormat(r *http.Request) (*format, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return jsonFormat, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	if jsonFormat.matches(mediaType) {
+		return jsonFormat, true
+	}
+	for _, f := range formats {
+		if f.decode != nil && f.matches(mediaType) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func requestMediaTypes() string {
+	types := []string{jsonFormat.mediaType}
+	for _, f := range formats {
+		if f.decode != nil {
+			types = append(types, f.mediaType)
+		}
+	}
+	return strings.Join(types, ", ")
+}
+
+// encodeXML оборачивает список в <items>: документ XML должен иметь один корень.
+// Элементы называются по XMLName своего типа.
+func encodeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if reflect.ValueOf(v).Kind() != reflect.Slice {
+		return enc.Encode(v)
+	}
+	items := xml.StartElement{Name: xml.Name{Local: "items"}}
+	if err := enc.EncodeToken(items); err != nil {
+		return err
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(items.End()); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// decodeXML читает XML так же строго, как decodeJSON JSON: элемент, которому
+// нет поля в dst, и данные после корневого элемента отклоняются. encoding/xml
+// такие элементы молча пропускает, поэтому документ сначала проверяется отдельно.
+func decodeXML(r io.Reader, dst any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := checkXML(data, xmlElements(reflect.TypeOf(dst))); err != nil {
+		return err
+	}
+	return xml.NewDecoder(bytes.NewReader(data)).Decode(dst)
+}
+
+// decodeMsgpack, как decodeJSON и decodeXML, принимает ровно одно значение:
+// лишнее поле или данные после значения — ошибка.
+func decodeMsgpack(r io.Reader, dst any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	switch err := dec.Skip(); {
+	case errors.Is(err, io.EOF):
+		return nil
+	case err != nil:
+		return err
+	default:
+		return errors.New("unexpected data after the first value")
+	}
+}
+
+// checkXML проверяет, что в документе один корневой элемент и у него нет
+// дочерних элементов, кроме known.
+func checkXML(data []byte, known map[string]bool) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth, roots := 0, 0
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) && roots > 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case depth == 0 && roots > 0:
+				return errors.New("unexpected data after the root element")
+			case depth == 0:
+				roots++
+			case depth == 1 && !known[t.Name.Local]:
+				return fmt.Errorf("unknown element %q", t.Name.Local)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return errors.New("unexpected data after the root element")
+			}
+		}
+	}
+}
+
+// xmlElements — имена дочерних элементов, в которые encoding/xml раскладывает
+// структуру t (XMLName, атрибуты и текст элементами не являются).
+func xmlElements(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := map[string]bool{}
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+		if field.Anonymous && tag == "" {
+			for name := range xmlElements(field.Type) {
+				names[name] = true
+			}
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if !field.IsExported() || field.Name == "XMLName" || name == "-" || !xmlElementField(opts) {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ">")
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+func xmlElementField(opts string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "attr", "chardata", "cdata", "innerxml", "comment":
+			return false
+		}
+	}
+	return true
+}
+
+// encodeCSV пишет список структур таблицей: колонки — поля с их JSON-именами.
+func encodeCSV(w io.Writer, v any) error {
+	rows := reflect.ValueOf(v)
+	elem := rows.Type().Elem()
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("csv: %s is not a list of structs", rows.Type())
+	}
+
+	var (
+		header []string
+		fields []int
+	)
+	for i := range elem.NumField() {
+		name, _, _ := strings.Cut(elem.Field(i).Tag.Get("json"), ",")
+		if name == "-" || !elem.Field(i).IsExported() {
+			continue
+		}
+		if name == "" {
+			name = elem.Field(i).Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(fields))
+	for i := range rows.Len() {
+		row := rows.Index(i)
+		for j, field := range fields {
+			record[j] = csvValue(row.Field(field))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue возвращает значение ячейки. Таблицу открывают в Excel и подобных
+// программах, а те выполняют ячейку, начинающуюся с =, +, -, @, табуляции или
+// CR, как формулу (CSV injection), поэтому такому значению предшествует '.
+func csvValue(v reflect.Value) string {
+	s := csvText(v)
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func csvText(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// formatETag различает представления одного ресурса: ETag JSON-ответа не
+// меняется, к остальным добавляется имя формата.
+func formatETag(etag string, f *format) string {
+	if f == jsonFormat {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + f.name + `"`
+}
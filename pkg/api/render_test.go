@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"leti/pkg/api/dto"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestRender_NegotiatesFormat(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	_, err := repo.NewAuthor(context.Background(), models.Author{Author: "Толстой"})
+	require.NoError(t, err)
+	_, err = repo.NewAuthor(context.Background(), models.Author{Author: "Салтыков, Щедрин"})
+	require.NoError(t, err)
+	r := newTestAPI(service.NewService(repo))
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, accept := range []string{"", "*/*", "application/json", "text/html, application/*;q=0.5"} {
+		w := get("/api/authors", accept)
+		require.Equal(t, http.StatusOK, w.Code, accept)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"), accept)
+		require.Equal(t, "Accept", w.Header().Get("Vary"))
+		require.Equal(t, `"v2"`, w.Header().Get("ETag"), "ETag JSON-ответа не зависит от согласования")
+	}
+
+	w := get("/api/authors", "application/xml")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Equal(t, `"v2-xml"`, w.Header().Get("ETag"))
+	var items struct {
+		Authors []dto.AuthorResponse `xml:"author"`
+	}
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(t, items.Authors, 2)
+	require.Equal(t, "Толстой", items.Authors[0].Name)
+
+	// q-значения важнее порядка
+	w = get("/api/authors", "application/json;q=0.5, text/csv")
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"id", "author"}, {"1", "Толстой"}, {"2", "Салтыков, Щедрин"}}, rows)
+
+	// ?format= важнее Accept
+	w = get("/api/authors?format=msgpack", "application/json")
+	require.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+	var authors []map[string]any
+	require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &authors))
+	require.Equal(t, "Толстой", authors[0]["author"])
+
+	// разные представления не подменяют друг друга в условных запросах
+	req := httptest.NewRequest(http.MethodGet, "/api/authors", nil)
+	req.Header.Set("If-None-Match", `"v2"`)
+	req.Header.Set("Accept", "application/xml")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestEncodeCSV_EscapesFormulas(t *testing.T) {
+	type row struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	}
+	var buf bytes.Buffer
+	require.NoError(t, encodeCSV(&buf, []row{
+		{`=HYPERLINK("http://evil.example")`, 1},
+		{"+7 999", 2},
+		{"-1", -3},
+		{"@SUM(A1)", 4},
+		{"\tx", 5},
+		{"\rx", 6},
+		{"Толстой = классик", 7},
+	}))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"name", "price"},
+		{`'=HYPERLINK("http://evil.example")`, "1"},
+		{"'+7 999", "2"},
+		{"'-1", "'-3"},
+		{"'@SUM(A1)", "4"},
+		{"'\tx", "5"},
+		{"'\rx", "6"},
+		{"Толстой = классик", "7"},
+	}, rows)
+}
+
+func TestRender_NotAcceptable(t *testing.T) {
+	r := newTestAPI(service.NewService(&fake.FakeRepo{}))
+
+	for _, tc := range []struct{ path, accept string }{
+		{"/api/genres", "image/png"},
+		{"/api/genres", "application/json;q=0"},
+		{"/api/genres?format=yaml", ""},
+		// CSV — только для списков
+		{"/api/book?id=1", "text/csv"},
+		{"/api/book?id=1&format=csv", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotAcceptable, w.Code, tc)
+		require.Equal(t, problemContentType, w.Header().Get("Content-Type"), tc)
+	}
+}
+
+func TestDecodeBody_Formats(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	r := newTestAPI(service.NewService(repo))
+
+	post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/genres", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("application/xml", []byte(`<genre><genre>Роман</genre></genre>`))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created dto.CreatedResponse
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &created))
+	require.Equal(t, 1, created.ID)
+
+	body, err := msgpack.Marshal(map[string]string{"genre": "Повесть"})
+	require.NoError(t, err)
+	w = post("application/msgpack", body)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var id map[string]int
+	require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &id))
+	require.Equal(t, 2, id["id"])
+
+	w = post("application/json; charset=utf-8", []byte(`{"genre":"Рассказ"}`))
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	require.Equal(t, 3, created.ID)
+
+	// тело проверяется одинаково во всех форматах
+	require.Equal(t, http.StatusUnprocessableEntity, post("application/xml", []byte(`<genre><genre> </genre></genre>`)).Code)
+	body, err = msgpack.Marshal(map[string]string{"genre": "Поэма", "extra": "x"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, post("application/msgpack", body).Code)
+	require.Equal(t, http.StatusBadRequest, post("application/xml", []byte(`<author><author>Гоголь</author></author>`)).Code)
+
+	// MessagePack — ровно одно значение
+	body, err = msgpack.Marshal(map[string]string{"genre": "Поэма"})
+	require.NoError(t, err)
+	w = post("application/msgpack", append(body, body...))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Contains(t, p.Detail, "after the first value")
+	require.Equal(t, http.StatusBadRequest, post("application/msgpack", append(body, 0xc1)).Code)
+
+	// XML разбирается так же строго, как JSON
+	w = post("application/xml", []byte(`<genre><genre>Поэма</genre><born>1799</born></genre>`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Contains(t, p.Detail, `unknown element "born"`)
+	w = post("application/xml", []byte(`<genre><genre>Поэма</genre></genre><genre><genre>Сказка</genre></genre>`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Contains(t, p.Detail, "after the root element")
+	require.Equal(t, http.StatusBadRequest, post("application/xml", []byte(`<genre><genre>Поэма</genre></genre> trailing`)).Code)
+	require.Equal(t, http.StatusBadRequest, post("application/xml", []byte(`<genre><genre>Поэма</genre>`)).Code)
+	require.Equal(t, http.StatusBadRequest, post("application/xml", nil).Code)
+	require.Equal(t, http.StatusCreated, post("application/xml", []byte(xml.Header+"<!-- импорт -->\n<genre>\n  <genre>Очерк</genre>\n</genre>\n")).Code)
+
+	for _, contentType := range []string{"text/csv", "application/x-www-form-urlencoded", "not a media type"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/genres", bytes.NewBufferString(`genre=Сказка`))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusUnsupportedMediaType, w.Code, contentType)
+	}
+
+	genres, err := repo.GetAllGenres(context.Background())
+	require.NoError(t, err)
+	require.Len(t, genres, 4)
+}
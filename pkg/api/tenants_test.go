@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testTenantDomain = "library.test"
+
+func TestTenants_CatalogIsolation(t *testing.T) {
+	repo := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("password")
+	require.NoError(t, err)
+	platformAdminID := repo.AddUser(models.User{Username: "root", Password: hash, Role: auth.RoleAdmin})
+	ts := httptest.NewServer(newTestAPI(service.NewService(repo), WithTenantDomain(testTenantDomain)))
+	t.Cleanup(ts.Close)
+
+	platformToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: platformAdminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+
+	// onHost отправляет запрос на поддомен библиотеки; пустой slug — основной домен
+	onHost := func(slug, method, path, token string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data = marshal(t, body)
+		}
+		req := newRequestWithAuth(t, method, ts.URL+path, token, data)
+		if slug != "" {
+			req.Host = slug + "." + testTenantDomain
+		}
+		return doRequest(t, req)
+	}
+	status := func(resp *http.Response) int {
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	resp := onHost("", http.MethodPost, "/api/admin/tenants", platformToken,
+		dto.CreateTenantRequest{Slug: "lib2", Name: "Городская библиотека"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var lib2 dto.TenantResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&lib2))
+	resp.Body.Close()
+	require.Equal(t, "lib2", lib2.Slug)
+
+	require.Equal(t, http.StatusConflict, status(onHost("", http.MethodPost, "/api/admin/tenants", platformToken,
+		dto.CreateTenantRequest{Slug: "lib2", Name: "Дубликат"})))
+	require.Equal(t, http.StatusUnprocessableEntity, status(onHost("", http.MethodPost, "/api/admin/tenants", platformToken,
+		dto.CreateTenantRequest{Slug: "Not_A_Host", Name: "x"})))
+
+	repo.AddUser(models.User{Username: "librarian", Password: hash, Role: auth.RoleAdmin, TenantID: lib2.ID})
+	resp = onHost("lib2", http.MethodPost, "/api/auth/login", "", auth.LoginRequest{Username: "librarian", Password: "password"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var login auth.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+	resp.Body.Close()
+	lib2Token := login.AccessToken
+
+	// на чужом поддомене учётной записи нет
+	require.Equal(t, http.StatusUnauthorized, status(onHost("lib2", http.MethodPost, "/api/auth/login", "",
+		auth.LoginRequest{Username: "root", Password: "password"})))
+
+	require.Equal(t, http.StatusCreated, status(onHost("lib2", http.MethodPost, "/api/authors", "", dto.CreateAuthorRequest{Name: "Чехов"})))
+	require.Equal(t, http.StatusCreated, status(onHost("lib2", http.MethodPost, "/api/genres", "", dto.CreateGenreRequest{Name: "Рассказ"})))
+
+	// книга попадает в библиотеку из токена, даже без поддомена
+	resp = onHost("", http.MethodPost, "/api/books", lib2Token, dto.CreateBookRequest{Name: "Только для lib2", AuthorID: 1, GenreID: 1, Price: 10})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created map[string]int
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+
+	listBooks := func(slug string) []dto.BookResponse {
+		resp := onHost(slug, http.MethodGet, "/api/books", "", nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var books []dto.BookResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&books))
+		return books
+	}
+	require.Empty(t, listBooks(""))
+	require.Len(t, listBooks("lib2"), 1)
+	require.NotEqual(t, http.StatusOK, status(onHost("", http.MethodGet, "/api/book?id=1", "", nil)))
+	// чужая книга для этой библиотеки не существует: удаление ничего не делает, как для любого неизвестного id
+	require.Equal(t, http.StatusNoContent, status(onHost("", http.MethodDelete, "/api/books?id=1", platformToken, nil)))
+	require.Len(t, listBooks("lib2"), 1)
+
+	// токен lib2 не действует на поддомене другой библиотеки
+	require.Equal(t, http.StatusForbidden, status(onHost("default", http.MethodGet, "/api/me", lib2Token, nil)))
+	require.Equal(t, http.StatusNotFound, status(onHost("nowhere", http.MethodGet, "/api/books", "", nil)))
+
+	// администратор библиотеки видит только своих пользователей и не управляет платформой
+	resp = onHost("lib2", http.MethodGet, "/api/admin/users", lib2Token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var users dto.UserListResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&users))
+	resp.Body.Close()
+	require.Equal(t, 1, users.Total)
+	require.Equal(t, "librarian", users.Users[0].Username)
+	require.Equal(t, http.StatusNotFound, status(onHost("lib2", http.MethodPost, "/api/admin/users/disable?id=1", lib2Token, nil)))
+	require.Equal(t, http.StatusForbidden, status(onHost("lib2", http.MethodGet, "/api/admin/tenants", lib2Token, nil)))
+
+	// API-ключи — тоже в пределах библиотеки
+	createKey := func(slug, token string) dto.CreateAPIKeyResponse {
+		resp := onHost(slug, http.MethodPost, "/api/admin/api-keys", token,
+			dto.CreateAPIKeyRequest{Name: "import", Scopes: []string{auth.ScopeBooksWrite}})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var key dto.CreateAPIKeyResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&key))
+		return key
+	}
+	listKeys := func(slug, token string) []dto.APIKeyResponse {
+		resp := onHost(slug, http.MethodGet, "/api/admin/api-keys", token, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var keys []dto.APIKeyResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+		return keys
+	}
+	platformKey := createKey("", platformToken)
+	lib2Key := createKey("lib2", lib2Token)
+	keys := listKeys("lib2", lib2Token)
+	require.Len(t, keys, 1)
+	require.Equal(t, lib2Key.ID, keys[0].ID)
+	keys = listKeys("", platformToken)
+	require.Len(t, keys, 1)
+	require.Equal(t, platformKey.ID, keys[0].ID)
+	require.Equal(t, http.StatusNotFound, status(onHost("lib2", http.MethodDelete, "/api/admin/api-keys?id="+strconv.Itoa(platformKey.ID), lib2Token, nil)))
+	require.Equal(t, http.StatusNotFound, status(onHost("", http.MethodDelete, "/api/admin/api-keys?id="+strconv.Itoa(lib2Key.ID), platformToken, nil)))
+	require.Equal(t, http.StatusNoContent, status(onHost("lib2", http.MethodDelete, "/api/admin/api-keys?id="+strconv.Itoa(lib2Key.ID), lib2Token, nil)))
+
+	resp = onHost("", http.MethodGet, "/api/admin/tenants", platformToken, nil)
+	var tenants []dto.TenantResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tenants))
+	resp.Body.Close()
+	require.Len(t, tenants, 2)
+}
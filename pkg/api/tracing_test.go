@@ -0,0 +1,63 @@
+package api
+
+import (
+	"leti/pkg/repository/fake"
+	"leti/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_ServerSpanFromTraceparent(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	srv := service.NewService(&fake.FakeRepo{}, service.WithTracerProvider(tp))
+	r, logs := newLoggedTestAPI(t, srv, WithTracerProvider(tp))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/book?id=7", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+
+	require.Equal(t, "GET /api/book", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, traceID, server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	require.True(t, server.Parent().IsRemote())
+	require.Contains(t, server.Attributes(), attribute.String("http.route", "/api/book"))
+	require.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+
+	require.Equal(t, "Service.GetBookByID", child.Name())
+	require.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+
+	// по trace_id запись access-лога находится рядом с трассой
+	require.Contains(t, logs.String(), "trace_id="+traceID)
+}
+
+func TestTracing_ErrorStatusOn5xx(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	r, _ := newLoggedTestAPI(t, service.NewService(&fake.FakeRepo{}), WithTracerProvider(tp))
+	r.HandleFunc("/api/panic", func(http.ResponseWriter, *http.Request) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"leti/pkg/api/dto"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUsers(t *testing.T) {
+	ts, repo := newMFATestServer(t, auth.RoleUser) // Den, id 1
+	adminID := repo.AddUser(models.User{Username: "admin", Role: auth.RoleAdmin})
+	for i := 0; i < 3; i++ {
+		repo.AddUser(models.User{Username: fmt.Sprintf("reader%d", i), Role: auth.RoleUser, Email: fmt.Sprintf("r%d@example.com", i)})
+	}
+	adminToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID, Role: auth.RoleAdmin})
+	require.NoError(t, err)
+	admin := func(method, path string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data = marshal(t, body)
+		}
+		return doRequest(t, newRequestWithAuth(t, method, ts.URL+path, adminToken, data))
+	}
+
+	resp := admin(http.MethodGet, "/api/admin/users?q=reader&per_page=2&page=2", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list dto.UserListResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	resp.Body.Close()
+	require.Equal(t, 3, list.Total)
+	require.Len(t, list.Users, 1)
+	require.Equal(t, "reader2", list.Users[0].Username)
+
+	resp = admin(http.MethodGet, "/api/admin/users?id=1", nil)
+	var den dto.UserResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&den))
+	resp.Body.Close()
+	require.Equal(t, "Den", den.Username)
+	require.False(t, den.Disabled)
+
+	userToken := loginAs(t, ts, "Den", "password").AccessToken
+	getMe := func(token string) *http.Response {
+		return doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/me", token, nil))
+	}
+
+	// блокировка действует и на уже выданный токен, и на новый вход
+	resp = admin(http.MethodPost, "/api/admin/users/disable?id=1", nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = getMe(userToken)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Equal(t, http.StatusForbidden, attemptLogin(t, ts, "Den", "password").StatusCode)
+
+	resp = admin(http.MethodPost, "/api/admin/users/enable?id=1", nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = getMe(userToken)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// смена роли отзывает токены со старой ролью
+	resp = admin(http.MethodPut, "/api/admin/users/role?id=1", dto.ChangeRoleRequest{Role: "root"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	resp = admin(http.MethodPut, "/api/admin/users/role?id=1", dto.ChangeRoleRequest{Role: auth.RoleAdmin})
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = getMe(userToken)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = admin(http.MethodPost, "/api/admin/users/reset-password?id=1", nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, http.StatusUnauthorized, attemptLogin(t, ts, "Den", "password").StatusCode)
+
+	// себя администратор не блокирует и не удаляет
+	resp = admin(http.MethodPost, "/api/admin/users/disable?id="+strconv.Itoa(adminID), nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = admin(http.MethodDelete, "/api/admin/users?id="+strconv.Itoa(adminID), nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = admin(http.MethodDelete, "/api/admin/users?id=1", nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = admin(http.MethodGet, "/api/admin/users?id=1", nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// обычному пользователю админка недоступна
+	readerToken, err := auth.NewJWTService(testJWTSecret).GenerateAccessToken(auth.Subject{UserID: adminID + 1, Role: auth.RoleUser})
+	require.NoError(t, err)
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/admin/users", readerToken, nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestMe(t *testing.T) {
+	ts, _ := newMFATestServer(t, auth.RoleUser)
+	token := loginAs(t, ts, "Den", "password").AccessToken
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodGet, ts.URL+"/api/me", token, nil))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var me dto.MeResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&me))
+	resp.Body.Close()
+	require.Equal(t, "Den", me.Username)
+	require.Equal(t, auth.RoleUser, me.Role)
+	require.Equal(t, auth.AuthTypeJWT, me.AuthType)
+	require.Contains(t, me.Scopes, auth.ScopeBooksWrite)
+
+	resp = doRequest(t, newRequest(t, http.MethodGet, ts.URL+"/api/me", nil))
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"leti/pkg/api/dto"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidation_ReportsAllFieldErrors(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/books", adminToken,
+		[]byte(`{"name": "  ", "author_id": 0, "genre_id": 0, "price": -1, "isbn": "978-5-17-090630-8"}`)))
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	p := decodeProblem(t, resp)
+	require.Equal(t, []apperr.FieldError{
+		{Field: "name", Message: "must not be blank"},
+		{Field: "author_id", Message: "is required"},
+		{Field: "genre_id", Message: "is required"},
+		{Field: "price", Message: "must be at least 0"},
+		{Field: "isbn", Message: "must be a valid ISBN-10 or ISBN-13"},
+	}, p.Errors)
+}
+
+func TestValidation_StrictDecoding(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+	post := func(body []byte) Problem {
+		resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/authors", adminToken, body))
+		return decodeProblem(t, resp)
+	}
+
+	p := post([]byte(`{"author": "Пушкин", "born": 1799}`))
+	require.Equal(t, http.StatusBadRequest, p.Status)
+	require.Contains(t, p.Detail, `unknown field "born"`)
+
+	p = post([]byte(`{"author": "Пушкин"} {"author": "Гоголь"}`))
+	require.Equal(t, http.StatusBadRequest, p.Status)
+
+	p = post([]byte(`{"author": "Пушкин"`))
+	require.Equal(t, http.StatusBadRequest, p.Status)
+
+	p = post(nil)
+	require.Equal(t, http.StatusBadRequest, p.Status)
+
+	huge := append([]byte(`{"author": "`), bytes.Repeat([]byte("a"), maxBodyBytes)...)
+	p = post(append(huge, `"}`...))
+	require.Equal(t, http.StatusRequestEntityTooLarge, p.Status)
+
+	require.Empty(t, getAuthors(t, ts.URL))
+}
+
+func TestValidation_LoginRequiresPassword(t *testing.T) {
+	ts, _ := newOAuthTestServer(t)
+
+	resp := doRequest(t, newRequest(t, http.MethodPost, ts.URL+"/api/auth/login",
+		marshal(t, auth.LoginRequest{Username: "Den", Password: " "})))
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	p := decodeProblem(t, resp)
+	require.Equal(t, []apperr.FieldError{{Field: "password", Message: "must not be blank"}}, p.Errors)
+}
+
+func TestValidation_BookISBN(t *testing.T) {
+	ts, adminToken := newOAuthTestServer(t)
+	authorID := createAuthor(t, ts.URL, "Достоевский")
+	genreID := createGenre(t, ts.URL, "Роман")
+
+	id := createBook(t, ts.URL, adminToken, dto.CreateBookRequest{
+		Name: "Идиот", AuthorID: authorID, GenreID: genreID, Price: 100, ISBN: "978-5-17-090630-7",
+	})
+	require.Equal(t, "9785170906307", getBookById(t, ts.URL, "1").ISBN)
+
+	// тот же ISBN в другой записи — это та же книга
+	resp := doRequest(t, newRequestWithAuth(t, http.MethodPost, ts.URL+"/api/books", adminToken,
+		marshal(t, dto.CreateBookRequest{Name: "Идиот", AuthorID: authorID, GenreID: genreID, ISBN: "9785170906307"})))
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+	decodeProblem(t, resp)
+
+	resp = doRequest(t, newRequestWithAuth(t, http.MethodPatch, ts.URL+"/api/books?id=1", adminToken,
+		marshal(t, dto.UpdateBookRequest{ISBN: ptr("")})))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var book dto.BookResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&book))
+	resp.Body.Close()
+	require.Equal(t, id, book.ID)
+	require.Empty(t, book.ISBN)
+}
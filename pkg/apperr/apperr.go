@@ -0,0 +1,109 @@
+// Package apperr — типизированные ошибки приложения. Репозиторий и сервис
+// возвращают их, а API по виду ошибки выбирает HTTP-статус, не разбирая текст.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Виды ошибок. Проверяются через errors.Is(err, apperr.ErrNotFound).
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+)
+
+// FieldError — ошибка в конкретном поле запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error — ошибка определённого вида. Msg можно показывать клиенту,
+// Err (причина, например ошибка драйвера БД) — только в логах.
+type Error struct {
+	Kind   error
+	Msg    string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New создаёт ошибку вида kind. Удобно для сигнальных ошибок сервиса:
+// var ErrX = apperr.New(apperr.ErrConflict, "...") проверяется и как ErrX, и как ErrConflict.
+func New(kind error, msg string) *Error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+func NotFound(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Msg: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Msg: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Validation сообщает о невалидных данных; fields уточняют, какие поля не так.
+func Validation(msg string, fields ...FieldError) error {
+	return &Error{Kind: ErrValidation, Msg: msg, Fields: fields}
+}
+
+// Field — ошибка валидации одного поля.
+func Field(field, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return &Error{Kind: ErrValidation, Msg: field + ": " + msg, Fields: []FieldError{{Field: field, Message: msg}}}
+}
+
+// Wrap сохраняет причину err, но для клиента ошибка выглядит как msg вида kind.
+func Wrap(kind, err error, msg string) error {
+	return &Error{Kind: kind, Msg: msg, Err: err}
+}
+
+// Message возвращает текст для клиента: Msg ближайшей *Error в цепочке.
+func Message(err error) (string, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Msg, true
+	}
+	return "", false
+}
+
+// Kind возвращает вид ошибки (ErrNotFound и т.д.) из цепочки err или nil.
+// Текст вида годится клиенту, когда в цепочке нет *Error со своим Msg.
+func Kind(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrForbidden} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// Fields возвращает ошибки полей из цепочки err.
+func Fields(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestError_KindAndMessage(t *testing.T) {
+	cause := errors.New("pq: insert or update violates foreign key")
+	err := fmt.Errorf("create book: %w", Wrap(ErrValidation, cause, "author does not exist"))
+
+	require.ErrorIs(t, err, ErrValidation)
+	require.ErrorIs(t, err, cause)
+	require.NotErrorIs(t, err, ErrNotFound)
+
+	msg, ok := Message(err)
+	require.True(t, ok)
+	require.Equal(t, "author does not exist", msg)
+
+	_, ok = Message(cause)
+	require.False(t, ok)
+}
+
+func TestSentinel(t *testing.T) {
+	errTaken := New(ErrConflict, "name taken")
+	err := fmt.Errorf("register: %w", errTaken)
+	require.ErrorIs(t, err, errTaken)
+	require.ErrorIs(t, err, ErrConflict)
+}
+
+func TestField(t *testing.T) {
+	err := Field("price", "must be non-negative")
+	require.ErrorIs(t, err, ErrValidation)
+	require.Equal(t, []FieldError{{Field: "price", Message: "must be non-negative"}}, Fields(err))
+}
+
+func TestKind(t *testing.T) {
+	require.Equal(t, ErrNotFound, Kind(fmt.Errorf("get book 7 from pq: %w", ErrNotFound)))
+	require.Equal(t, ErrConflict, Kind(fmt.Errorf("register: %w", New(ErrConflict, "name taken"))))
+	require.Nil(t, Kind(errors.New("connection reset")))
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Формат ключа: lib_<prefix>_<secret>.
+// Префикс хранится открыто и служит для поиска ключа и отображения в списках,
+// в БД кладём только sha256 от всего ключа.
+const (
+	apiKeyTag          = "lib"
+	apiKeyPrefixBytes  = 4
+	apiKeySecretBytes  = 24
+	APIKeyHeaderScheme = "ApiKey "
+)
+
+var ErrMalformedAPIKey = errors.New("malformed api key")
+
+// GenerateAPIKey создаёт новый ключ и возвращает его целиком (показывается один раз)
+// и видимый префикс.
+func GenerateAPIKey() (key, prefix string, err error) {
+	prefixRaw := make([]byte, apiKeyPrefixBytes)
+	secretRaw := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(prefixRaw); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secretRaw); err != nil {
+		return "", "", err
+	}
+	prefix = apiKeyTag + "_" + hex.EncodeToString(prefixRaw)
+	key = prefix + "_" + hex.EncodeToString(secretRaw)
+	return key, prefix, nil
+}
+
+// ParseAPIKeyPrefix достаёт видимый префикс из ключа, присланного клиентом.
+func ParseAPIKeyPrefix(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || parts[1] == "" || parts[2] == "" {
+		return "", ErrMalformedAPIKey
+	}
+	return parts[0] + "_" + parts[1], nil
+}
+
+// HashAPIKey — ключ случайный и длинный, поэтому медленный KDF не нужен.
+func HashAPIKey(key string) string {
+	return HashSecret(key)
+}
+
+func CheckAPIKey(hash, key string) bool {
+	return CheckSecret(hash, key)
+}
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -9,6 +12,15 @@ import (
 
 const (
 	accessTokenTTL = 15 * time.Minute
+	oidcFlowTTL    = 10 * time.Minute
+	mfaTokenTTL    = 5 * time.Minute
+)
+
+// Служебные токены подписываются ключами, производными от секрета, — так их
+// нельзя предъявить вместо access-токена и наоборот.
+const (
+	purposeOIDCFlow     = "oidc-flow"
+	purposeMFAChallenge = "mfa-challenge"
 )
 
 type JWTService struct {
@@ -19,10 +31,12 @@ func NewJWTService(secret string) *JWTService {
 	return &JWTService{secretKey: []byte(secret)}
 }
 
-func (s *JWTService) GenerateAccessToken(userID int, role string) (string, error) {
+func (s *JWTService) GenerateAccessToken(sub Subject) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:       sub.UserID,
+		Role:         sub.Role,
+		TokenVersion: sub.TokenVersion,
+		TenantID:     sub.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			// срок действия нашего токена с(issued) - до(expires)
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
@@ -34,6 +48,54 @@ func (s *JWTService) GenerateAccessToken(userID int, role string) (string, error
 	return token.SignedString(s.secretKey)
 }
 
+// GenerateOAuthAccessToken выпускает токен для OAuth-клиента. sub.UserID == 0 означает
+// client credentials: токен выдан самому клиенту, а не от имени пользователя.
+func (s *JWTService) GenerateOAuthAccessToken(sub Subject, clientID string, scopes []string) (string, error) {
+	subject := clientID
+	if sub.UserID != 0 {
+		subject = strconv.Itoa(sub.UserID)
+	}
+	claims := Claims{
+		UserID:       sub.UserID,
+		Role:         sub.Role,
+		Scope:        FormatScope(scopes),
+		ClientID:     clientID,
+		TokenVersion: sub.TokenVersion,
+		TenantID:     sub.TenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secretKey)
+}
+
+// GenerateMFAEnrollmentToken выдаёт короткоживущий токен, который позволяет только
+// подключить 2FA. Нужен, когда роль требует 2FA, а пользователь его ещё не настроил.
+func (s *JWTService) GenerateMFAEnrollmentToken(sub Subject) (string, error) {
+	claims := Claims{
+		UserID:       sub.UserID,
+		Role:         sub.Role,
+		Scope:        ScopeMFAEnroll,
+		TokenVersion: sub.TokenVersion,
+		TenantID:     sub.TenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secretKey)
+}
+
+func (s *JWTService) AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
 func (s *JWTService) ParseToken(tokenStr string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return s.secretKey, nil
@@ -51,3 +113,107 @@ func (s *JWTService) ParseToken(tokenStr string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// Introspect отвечает на вопрос resource server-а «жив ли токен» (RFC 7662).
+// Причину невалидности не раскрываем — только active=false.
+func (s *JWTService) Introspect(tokenStr string) IntrospectionResponse {
+	claims, err := s.ParseToken(tokenStr)
+	if err != nil {
+		return IntrospectionResponse{Active: false}
+	}
+	resp := IntrospectionResponse{
+		Active:    true,
+		Scope:     FormatScope(NewPrincipalFromClaims(claims).Scopes),
+		ClientID:  claims.ClientID,
+		Subject:   claims.Subject,
+		TokenType: "Bearer",
+	}
+	if resp.Subject == "" {
+		resp.Subject = strconv.Itoa(claims.UserID)
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return resp
+}
+
+// OIDCFlowState — состояние незавершённого входа через OIDC, живёт в cookie.
+type OIDCFlowState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.RegisteredClaims
+}
+
+func (s *JWTService) GenerateOIDCFlowToken(state, nonce, codeVerifier string) (string, error) {
+	claims := OIDCFlowState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcFlowTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.purposeKey(purposeOIDCFlow))
+}
+
+func (s *JWTService) ParseOIDCFlowToken(tokenStr string) (*OIDCFlowState, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &OIDCFlowState{}, func(token *jwt.Token) (interface{}, error) {
+		return s.purposeKey(purposeOIDCFlow), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := token.Claims.(*OIDCFlowState); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func (s *JWTService) purposeKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// MFAChallenge — первый шаг входа с 2FA пройден (пароль верный), ждём второй фактор.
+type MFAChallenge struct {
+	UserID int `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+func (s *JWTService) GenerateMFAChallengeToken(userID int) (string, error) {
+	// jti нужен, чтобы погасить challenge после серии неверных кодов (см. lockout.Guard)
+	id, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	claims := MFAChallenge{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.purposeKey(purposeMFAChallenge))
+}
+
+func (s *JWTService) ParseMFAChallengeToken(tokenStr string) (*MFAChallenge, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &MFAChallenge{}, func(token *jwt.Token) (interface{}, error) {
+		return s.purposeKey(purposeMFAChallenge), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := token.Claims.(*MFAChallenge); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
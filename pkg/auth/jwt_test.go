@@ -10,7 +10,7 @@ import (
 func TestJWTService(t *testing.T) {
 	service := NewJWTService("test-secret-key")
 
-	token, err := service.GenerateAccessToken(123, "admin")
+	token, err := service.GenerateAccessToken(Subject{UserID: 123, Role: "admin", TenantID: 2})
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -18,6 +18,8 @@ func TestJWTService(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 123, claims.UserID)
 	require.Equal(t, "admin", claims.Role)
+	require.Equal(t, 2, claims.TenantID)
+	require.Equal(t, 2, NewPrincipalFromClaims(claims).TenantID)
 
 	require.True(t, claims.ExpiresAt.Time.After(time.Now()))
 }
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+// Минимальный сервер авторизации OAuth2 (RFC 6749): authorization code с PKCE
+// (RFC 7636), client credentials и интроспекция токенов (RFC 7662).
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+
+	ResponseTypeCode = "code"
+	PKCEMethodS256   = "S256"
+)
+
+// Коды ошибок из RFC 6749, раздел 5.2.
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrAccessDenied            = "access_denied"
+)
+
+// OAuthError — ошибка протокола, отдаётся клиенту как есть.
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// TokenResponse — ответ token endpoint.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse — ответ по RFC 7662. Для невалидного токена только active=false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// AuthorizeRequest — параметры запроса к authorization endpoint.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// GenerateClientCredentials создаёт client_id и секрет для конфиденциального клиента.
+func GenerateClientCredentials() (clientID, secret string, err error) {
+	clientID, err = randomToken(12)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return "cl_" + clientID, secret, nil
+}
+
+// GenerateAuthorizationCode возвращает одноразовый код; в хранилище кладётся его хэш.
+func GenerateAuthorizationCode() (string, error) {
+	return randomToken(32)
+}
+
+// VerifyPKCE сверяет code_verifier с сохранённым code_challenge. Поддерживаем только S256.
+func VerifyPKCE(challenge, method, verifier string) bool {
+	if method != PKCEMethodS256 || challenge == "" {
+		return false
+	}
+	// RFC 7636, 4.1: 43..128 символов
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(PKCEChallengeS256(verifier)), []byte(challenge)) == 1
+}
+
+func PKCEChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ParseScope разбирает scope из запроса (через пробел, RFC 6749 3.3).
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// пример из RFC 7636, приложение B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	require.Equal(t, challenge, PKCEChallengeS256(verifier))
+
+	require.True(t, VerifyPKCE(challenge, PKCEMethodS256, verifier))
+	require.False(t, VerifyPKCE(challenge, "plain", verifier))
+	require.False(t, VerifyPKCE(challenge, PKCEMethodS256, strings.ToUpper(verifier)))
+	require.False(t, VerifyPKCE(challenge, PKCEMethodS256, "short"))
+}
+
+func TestJWTService_Introspect(t *testing.T) {
+	service := NewJWTService("test-secret-key")
+
+	token, err := service.GenerateOAuthAccessToken(Subject{}, "cl_partner", []string{ScopeBooksWrite})
+	require.NoError(t, err)
+
+	resp := service.Introspect(token)
+	require.True(t, resp.Active)
+	require.Equal(t, "cl_partner", resp.ClientID)
+	require.Equal(t, "cl_partner", resp.Subject)
+	require.Equal(t, ScopeBooksWrite, resp.Scope)
+
+	require.False(t, NewJWTService("other-secret").Introspect(token).Active)
+	require.Equal(t, IntrospectionResponse{}, service.Introspect("garbage"))
+}
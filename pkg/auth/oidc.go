@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Вход через внешний OpenID Connect провайдер (корпоративный IdP):
+// discovery, authorization code (с PKCE) и проверка ID-токена по JWKS провайдера.
+
+// OIDCConfig — настройки клиента OIDC.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// GroupRoles сопоставляет группы IdP локальным ролям.
+	GroupRoles map[string]string
+	// DefaultRole выдаётся, если ни одна группа не сопоставлена. Пустая строка — вход запрещён.
+	DefaultRole string
+	HTTPClient  *http.Client
+}
+
+// OIDCIdentity — проверенная личность из ID-токена с уже сопоставленной ролью.
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Role     string
+}
+
+// IDTokenClaims — поля ID-токена, которые нам нужны.
+type IDTokenClaims struct {
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	PreferredUsername string   `json:"preferred_username"`
+	Groups            []string `json:"groups"`
+	jwt.RegisteredClaims
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+var (
+	ErrOIDCNoRole       = errors.New("no local role for identity provider groups")
+	ErrOIDCInvalidToken = errors.New("invalid id token")
+)
+
+// OIDCProvider — клиент внешнего провайдера. Discovery выполняется лениво при
+// первом входе, чтобы недоступность IdP при старте не мешала входу по паролю.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCProvider{cfg: cfg, client: client}
+}
+
+// AuthCodeURL строит адрес authorization endpoint провайдера.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", ResponseTypeCode)
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", "openid profile email groups")
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", PKCEChallengeS256(codeVerifier))
+	q.Set("code_challenge_method", PKCEMethodS256)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange меняет код на токены и возвращает проверенную личность пользователя.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*OIDCIdentity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token endpoint returned %d", resp.StatusCode)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("oidc token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("oidc token response has no id_token")
+	}
+
+	claims, err := p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	role := MapGroupsToRole(claims.Groups, p.cfg.GroupRoles, p.cfg.DefaultRole)
+	if role == "" {
+		return nil, ErrOIDCNoRole
+	}
+	return &OIDCIdentity{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Username: claims.PreferredUsername,
+		Email:    claims.Email,
+		Role:     role,
+	}, nil
+}
+
+// VerifyIDToken проверяет подпись (RS256, ключ из JWKS), iss, aud, exp и nonce.
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDTokenClaims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	claims := &IDTokenClaims{}
+	_, err = parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, d.JWKSURI, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, err)
+	}
+	if !claims.VerifyIssuer(d.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrOIDCInvalidToken, claims.Issuer)
+	}
+	if !claims.VerifyAudience(p.cfg.ClientID, true) {
+		return nil, fmt.Errorf("%w: token is not issued for this client", ErrOIDCInvalidToken)
+	}
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: missing exp", ErrOIDCInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrOIDCInvalidToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCInvalidToken)
+	}
+	return claims, nil
+}
+
+// MapGroupsToRole выбирает самую привилегированную из сопоставленных ролей.
+func MapGroupsToRole(groups []string, mapping map[string]string, defaultRole string) string {
+	role := ""
+	for _, g := range groups {
+		mapped, ok := mapping[g]
+		if !ok {
+			continue
+		}
+		if mapped == RoleAdmin {
+			return RoleAdmin
+		}
+		role = mapped
+	}
+	if role == "" {
+		return defaultRole
+	}
+	return role
+}
+
+// ParseGroupRoles разбирает строку вида "library-admins=admin,staff=user".
+func ParseGroupRoles(s string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || group == "" || (role != RoleAdmin && role != RoleUser) {
+			return nil, fmt.Errorf("invalid group mapping %q", pair)
+		}
+		mapping[group] = role
+	}
+	return mapping, nil
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// OIDC Discovery 1.0, 4.3: issuer в документе обязан совпадать с настроенным
+	if d.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q != %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery: incomplete provider metadata")
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// key ищет ключ по kid; неизвестный kid — повод перечитать JWKS (ротация ключей).
+func (p *OIDCProvider) key(ctx context.Context, jwksURI, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("oidc jwks: no key for kid %q", kid)
+}
+
+// lookupKey без kid допустим, только если ключ у провайдера ровно один.
+func (p *OIDCProvider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid != "" {
+		k, ok := p.keys[kid]
+		return k, ok
+	}
+	if len(p.keys) != 1 {
+		return nil, false
+	}
+	for _, k := range p.keys {
+		return k, true
+	}
+	return nil, false
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"leti/pkg/auth"
+	"leti/pkg/auth/oidctest"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCProvider_VerifyIDToken(t *testing.T) {
+	idp := oidctest.NewProvider("library", "secret")
+	defer idp.Close()
+	provider := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:   idp.Issuer,
+		ClientID: "library",
+	})
+
+	valid := func() auth.IDTokenClaims {
+		return auth.IDTokenClaims{
+			Nonce: "n-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    idp.Issuer,
+				Subject:   "staff-42",
+				Audience:  jwt.ClaimStrings{"library"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+	}
+
+	claims, err := provider.VerifyIDToken(context.Background(), idp.SignIDToken(valid()), "n-1")
+	require.NoError(t, err)
+	require.Equal(t, "staff-42", claims.Subject)
+
+	testCases := []struct {
+		name   string
+		mutate func(c *auth.IDTokenClaims)
+		nonce  string
+	}{
+		{"wrong nonce", func(c *auth.IDTokenClaims) {}, "n-2"},
+		{"wrong audience", func(c *auth.IDTokenClaims) { c.Audience = jwt.ClaimStrings{"other-app"} }, "n-1"},
+		{"wrong issuer", func(c *auth.IDTokenClaims) { c.Issuer = "https://evil.example" }, "n-1"},
+		{"expired", func(c *auth.IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }, "n-1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := valid()
+			tc.mutate(&c)
+			_, err := provider.VerifyIDToken(context.Background(), idp.SignIDToken(c), tc.nonce)
+			require.ErrorIs(t, err, auth.ErrOIDCInvalidToken)
+		})
+	}
+
+	// HS256-токен, подписанный «публичным» секретом, не принимается
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("library"))
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(context.Background(), forged, "n-1")
+	require.ErrorIs(t, err, auth.ErrOIDCInvalidToken)
+}
+
+func TestMapGroupsToRole(t *testing.T) {
+	mapping, err := auth.ParseGroupRoles("library-staff=user, library-admins=admin")
+	require.NoError(t, err)
+
+	require.Equal(t, auth.RoleAdmin, auth.MapGroupsToRole([]string{"library-staff", "library-admins"}, mapping, ""))
+	require.Equal(t, auth.RoleUser, auth.MapGroupsToRole([]string{"library-staff"}, mapping, ""))
+	require.Equal(t, "", auth.MapGroupsToRole([]string{"accounting"}, mapping, ""))
+
+	_, err = auth.ParseGroupRoles("library-admins=root")
+	require.Error(t, err)
+}
@@ -0,0 +1,197 @@
+// Package oidctest содержит поддельный OpenID Connect провайдер поверх httptest
+// для тестов входа через OIDC без внешнего IdP.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"leti/pkg/auth"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const keyID = "test-key"
+
+// User — учётная запись, под которой «входит» пользователь на странице провайдера.
+type User struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+type pendingCode struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+	user        User
+}
+
+// Provider реализует discovery, JWKS, authorization и token endpoints.
+// Authorization endpoint сразу «логинит» текущего User без страницы входа.
+type Provider struct {
+	Server       *httptest.Server
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  User
+	codes map[string]pendingCode
+}
+
+func NewProvider(clientID, clientSecret string) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        map[string]pendingCode{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	p.Issuer = p.Server.URL
+	return p
+}
+
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// SetUser задаёт пользователя для следующих входов.
+func (p *Provider) SetUser(u User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = u
+}
+
+// SignIDToken подписывает произвольные claims ключом провайдера.
+func (p *Provider) SignIDToken(claims auth.IDTokenClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer,
+		"authorization_endpoint": p.Issuer + "/authorize",
+		"token_endpoint":         p.Issuer + "/token",
+		"jwks_uri":               p.Issuer + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("code_challenge_method") != auth.PKCEMethodS256 {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+	code, _ := auth.RandomString()
+
+	p.mu.Lock()
+	p.codes[code] = pendingCode{
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+		user:        p.user,
+	}
+	p.mu.Unlock()
+
+	u, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := u.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	u.RawQuery = params.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != p.ClientID || secret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	pending, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !found || pending.redirectURI != r.PostForm.Get("redirect_uri") ||
+		!auth.VerifyPKCE(pending.challenge, auth.PKCEMethodS256, r.PostForm.Get("code_verifier")) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := p.SignIDToken(auth.IDTokenClaims{
+		Nonce:             pending.nonce,
+		Email:             pending.user.Email,
+		PreferredUsername: pending.user.Username,
+		Groups:            pending.user.Groups,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.Issuer,
+			Subject:   pending.user.Subject,
+			Audience:  jwt.ClaimStrings{p.ClientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "opaque-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
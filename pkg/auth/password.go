@@ -1,19 +1,136 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 
-const passwordCost = 10
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Пароли хэшируются argon2id в формате PHC:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<соль base64>$<хэш base64>
+//
+// Алгоритм и параметры хранятся в самом хэше, поэтому их можно менять:
+// старые хэши (в том числе bcrypt) по-прежнему проверяются, а при входе
+// пересчитываются с текущими параметрами (см. NeedsRehash).
+
+var (
+	ErrPasswordMismatch  = errors.New("password does not match")
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+)
+
+// Argon2Params — параметры argon2id. Memory задаётся в КиБ.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params — рекомендация OWASP (19 МиБ, 2 прохода, 1 поток).
+var DefaultArgon2Params = Argon2Params{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+var passwordParams atomic.Pointer[Argon2Params]
+
+func init() {
+	p := DefaultArgon2Params
+	passwordParams.Store(&p)
+}
+
+// SetArgon2Params меняет параметры для новых хэшей.
+func SetArgon2Params(p Argon2Params) error {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 {
+		return fmt.Errorf("invalid argon2 params: m=%d t=%d p=%d", p.Memory, p.Iterations, p.Parallelism)
+	}
+	if p.SaltLength < 16 || p.KeyLength < 16 {
+		return fmt.Errorf("argon2 salt and key must be at least 16 bytes")
+	}
+	passwordParams.Store(&p)
+	return nil
+}
 
 // хэшируем
 func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
-	if err != nil {
+	p := *passwordParams.Load()
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashed), nil
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
 }
 
-// проверяем
+// проверяем: argon2id или старый bcrypt
 func CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	switch {
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		p, salt, key, err := parseArgon2Hash(hashedPassword)
+		if err != nil {
+			return err
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	case isBcryptHash(hashedPassword):
+		if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+			return ErrPasswordMismatch
+		}
+		return nil
+	default:
+		return ErrUnknownHashFormat
+	}
+}
+
+// NeedsRehash сообщает, что хэш сделан устаревшим алгоритмом или с другими параметрами.
+func NeedsRehash(hashedPassword string) bool {
+	p, salt, key, err := parseArgon2Hash(hashedPassword)
+	if err != nil {
+		return true
+	}
+	current := *passwordParams.Load()
+	return p.Memory != current.Memory || p.Iterations != current.Iterations ||
+		p.Parallelism != current.Parallelism || uint32(len(salt)) != current.SaltLength ||
+		uint32(len(key)) != current.KeyLength
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func parseArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", соль, хэш
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil ||
+		p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
 }
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword_Argon2id(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+
+	require.NoError(t, CheckPassword(hash, "correct horse"))
+	require.ErrorIs(t, CheckPassword(hash, "wrong horse"), ErrPasswordMismatch)
+	require.False(t, NeedsRehash(hash))
+
+	// соль случайная
+	again, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, again)
+}
+
+func TestCheckPassword_LegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	require.NoError(t, CheckPassword(string(legacy), "password"))
+	require.ErrorIs(t, CheckPassword(string(legacy), "nope"), ErrPasswordMismatch)
+	require.True(t, NeedsRehash(string(legacy)))
+}
+
+func TestNeedsRehash_ParamsChanged(t *testing.T) {
+	old, err := HashPassword("password")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, SetArgon2Params(DefaultArgon2Params)) })
+	stronger := DefaultArgon2Params
+	stronger.Iterations = 3
+	require.NoError(t, SetArgon2Params(stronger))
+
+	require.True(t, NeedsRehash(old))
+	// старый хэш проверяется по своим параметрам
+	require.NoError(t, CheckPassword(old, "password"))
+
+	fresh, err := HashPassword("password")
+	require.NoError(t, err)
+	require.Contains(t, fresh, "t=3")
+	require.False(t, NeedsRehash(fresh))
+}
+
+func TestCheckPassword_Malformed(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+	} {
+		require.Error(t, CheckPassword(hash, "password"), hash)
+	}
+	require.Error(t, SetArgon2Params(Argon2Params{Memory: 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}))
+}
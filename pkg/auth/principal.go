@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"leti/pkg/tenant"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Области доступа (scopes). API-ключ получает только явно перечисленные,
+// JWT пользователя — все, что положены его роли.
+const (
+	ScopeBooksWrite  = "books:write"
+	ScopeProfileRead = "profile:read"
+	ScopeAdmin       = "admin"
+	// ScopeMFAEnroll и ScopeAccount не выдаются ключам и клиентам: только самому пользователю
+	ScopeMFAEnroll = "mfa:enroll"
+	ScopeAccount   = "account"
+)
+
+const (
+	AuthTypeJWT    = "jwt"
+	AuthTypeAPIKey = "api_key"
+	AuthTypeOAuth  = "oauth"
+)
+
+var knownScopes = map[string]bool{
+	ScopeBooksWrite:  true,
+	ScopeProfileRead: true,
+	ScopeAdmin:       true,
+}
+
+// IsKnownScope сообщает, существует ли такая область доступа.
+func IsKnownScope(scope string) bool {
+	return knownScopes[scope]
+}
+
+// ScopesForRole возвращает максимальный набор областей для роли.
+func ScopesForRole(role string) []string {
+	switch role {
+	case RoleAdmin:
+		return []string{ScopeBooksWrite, ScopeProfileRead, ScopeMFAEnroll, ScopeAccount, ScopeAdmin}
+	case RoleUser:
+		return []string{ScopeBooksWrite, ScopeProfileRead, ScopeMFAEnroll, ScopeAccount}
+	default:
+		return nil
+	}
+}
+
+// Principal — аутентифицированный клиент запроса. Одинаков для Bearer и ApiKey,
+// поэтому хендлерам не важно, каким способом пришёл клиент.
+type Principal struct {
+	UserID   int
+	Role     string
+	Scopes   []string
+	AuthType string
+	APIKeyID int    // 0, если клиент пришёл не с API-ключом
+	ClientID string // OAuth-клиент, от имени которого выпущен токен
+	TenantID int    // библиотека клиента; данные других библиотек ему недоступны
+}
+
+// NewPrincipalFromClaims строит Principal из проверенного access-токена.
+// Токен с явным scope (выпущенный OAuth-клиенту или ограниченный, как токен
+// подключения 2FA) несёт только свои области, а не все права роли.
+func NewPrincipalFromClaims(claims *Claims) *Principal {
+	p := &Principal{
+		UserID:   claims.UserID,
+		Role:     claims.Role,
+		Scopes:   ScopesForRole(claims.Role),
+		AuthType: AuthTypeJWT,
+		TenantID: claims.TenantID,
+	}
+	if p.TenantID == 0 {
+		p.TenantID = tenant.DefaultID
+	}
+	if claims.Scope != "" {
+		p.Scopes = ParseScope(claims.Scope)
+	}
+	if claims.ClientID != "" {
+		p.AuthType = AuthTypeOAuth
+		p.ClientID = claims.ClientID
+	}
+	return p
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalCtxKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey{}).(*Principal)
+	return p, ok
+}
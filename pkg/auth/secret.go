@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// HashSecret хэширует случайные высокоэнтропийные секреты (API-ключи, коды,
+// секреты клиентов). Для паролей пользователей — только HashPassword.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func CheckSecret(hash, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashSecret(secret))) == 1
+}
+
+func randomToken(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// RandomString — случайная строка для state, nonce и PKCE verifier.
+func RandomString() (string, error) {
+	return randomToken(32)
+}
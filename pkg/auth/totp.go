@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TOTP по RFC 6238: HMAC-SHA1, 6 цифр, шаг 30 секунд — параметры, которые
+// понимают все приложения-аутентификаторы.
+const (
+	totpDigits     = 6
+	totpPeriod     = 30
+	totpSkew       = 1 // допускаем ±1 шаг на расхождение часов
+	totpSecretSize = 20
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret возвращает новый секрет в base32.
+func GenerateTOTPSecret() (string, error) {
+	raw := make([]byte, totpSecretSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(raw), nil
+}
+
+// TOTPStep — номер 30-секундного интервала для момента t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// TOTPCode вычисляет код для заданного шага (RFC 4226, 5.3).
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// ValidateTOTP ищет шаг, для которого код совпадает, с учётом допуска на часы.
+// Возвращает найденный шаг, чтобы вызывающий мог запретить повторное использование кода.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := TOTPStep(t)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI строит otpauth:// URI для приложений-аутентификаторов (формат Google Authenticator).
+func TOTPURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// TOTPQRCode рисует QR-код с otpauth:// URI в PNG.
+func TOTPQRCode(uri string) ([]byte, error) {
+	return qrcode.Encode(uri, qrcode.Medium, 256)
+}
+
+// recoveryAlphabet — 32 символа без похожих друг на друга (i/l/1, o): деление без перекоса.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+// GenerateRecoveryCodes создаёт одноразовые коды восстановления вида xxxxx-xxxxx.
+// В них 50 случайных бит, поэтому хранить их достаточно через HashSecret.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for j, c := range raw {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(recoveryAlphabet[c&31])
+		}
+		codes[i] = b.String()
+	}
+	return codes, nil
+}
+
+// IsRecoveryCode сообщает, что ввод имеет вид кода из GenerateRecoveryCodes
+// (регистр не важен). Коды другого вида незачем искать среди сохранённых.
+func IsRecoveryCode(code string) bool {
+	if len(code) != 11 || code[5] != '-' {
+		return false
+	}
+	for i, c := range strings.ToLower(code) {
+		if i != 5 && !strings.ContainsRune(recoveryAlphabet, c) {
+			return false
+		}
+	}
+	return true
+}
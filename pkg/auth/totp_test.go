@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// векторы SHA1 из RFC 6238, приложение B (последние 6 цифр)
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, want, code, "t=%d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	step := TOTPStep(now)
+
+	for _, s := range []int64{step - 1, step, step + 1} {
+		code, err := TOTPCode(secret, s)
+		require.NoError(t, err)
+		got, ok := ValidateTOTP(secret, code, now)
+		require.True(t, ok)
+		require.Equal(t, s, got)
+	}
+
+	code, err := TOTPCode(secret, step+2)
+	require.NoError(t, err)
+	_, ok := ValidateTOTP(secret, code, now)
+	require.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now)
+	require.False(t, ok)
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	require.NoError(t, err)
+	seen := map[string]bool{}
+	for _, c := range codes {
+		require.Len(t, c, 11)
+		require.Equal(t, "-", c[5:6])
+		require.False(t, strings.ContainsAny(c, "ilo1"))
+		require.True(t, IsRecoveryCode(c))
+		require.True(t, IsRecoveryCode(strings.ToUpper(c)))
+		seen[c] = true
+	}
+	require.Len(t, seen, len(codes))
+}
+
+func TestIsRecoveryCode(t *testing.T) {
+	require.True(t, IsRecoveryCode("abcde-23456"))
+	require.False(t, IsRecoveryCode("123456"), "TOTP-код")
+	require.False(t, IsRecoveryCode("abcde23456"))
+	require.False(t, IsRecoveryCode("abcde-2345"))
+	require.False(t, IsRecoveryCode("abcd1-23456"), "1 нет в алфавите")
+	require.False(t, IsRecoveryCode("abcde_23456"))
+	require.False(t, IsRecoveryCode("абвгд-23456"))
+}
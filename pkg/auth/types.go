@@ -3,17 +3,70 @@ package auth
 import "github.com/golang-jwt/jwt/v4"
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Role   string `json:"role"`
+	UserID   int    `json:"user_id"`
+	Role     string `json:"role"`
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	// TokenVersion сверяется с users.token_version: смена пароля отзывает все токены
+	TokenVersion int `json:"tv,omitempty"`
+	// TenantID — библиотека, в которой выпущен токен; 0 в старых токенах означает арендатора по умолчанию
+	TenantID int `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Subject — пользователь, для которого выпускается токен.
+type Subject struct {
+	UserID       int
+	Role         string
+	TenantID     int
+	TokenVersion int
+}
+
+// TokenSubject возвращает пользователя, которому выдан токен.
+func (c *Claims) TokenSubject() Subject {
+	return Subject{
+		UserID:       c.UserID,
+		Role:         c.Role,
+		TenantID:     c.TenantID,
+		TokenVersion: c.TokenVersion,
+	}
+}
+
 type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Username string `json:"username" validate:"required,notblank"`
+	Password string `json:"password" validate:"required,notblank"`
 }
 
+// LoginResponse — либо access-токен, либо (при 2FA) токен второго шага.
+// Если роль требует 2FA, а она не настроена, access_token выдаётся только
+// с областью mfa:enroll.
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token,omitempty"`
+	AccessToken           string `json:"access_token,omitempty"`
+	RefreshToken          string `json:"refresh_token,omitempty"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	ChallengeToken        string `json:"challenge_token,omitempty"`
+}
+
+// MFALoginRequest — второй шаг входа: TOTP-код или код восстановления.
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,notblank"`
+}
+
+// ChangePasswordRequest — смена пароля авторизованным пользователем.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// PasswordResetRequest — запрос ссылки для сброса забытого пароля.
+type PasswordResetRequest struct {
+	Username string `json:"username" validate:"required,notblank"`
+}
+
+// PasswordResetConfirmRequest — установка нового пароля по токену из письма.
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
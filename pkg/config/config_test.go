@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, args []string, env map[string]string) (*Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args, func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(t, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, Default(), *cfg)
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, "app.yaml", `
+http:
+  addr: ":8000"
+  read_timeout: 7s
+database:
+  max_conns: 20
+  query_timeout: 2s
+cache:
+  control:
+    books: "public, max-age=60"
+`)
+	cfg, err := load(t,
+		[]string{"--config", file, "--database.max-conns", "40"},
+		map[string]string{"HTTP_ADDR": ":8001", "DB_MAX_CONNS": "30", "CACHE_TTL": "5m", "LOG_LEVEL": ""},
+	)
+	require.NoError(t, err)
+	require.Equal(t, ":8001", cfg.HTTP.Addr, "окружение важнее файла")
+	require.Equal(t, 7*time.Second, cfg.HTTP.ReadTimeout, "из файла")
+	require.Equal(t, 40, cfg.Database.MaxConns, "флаг важнее окружения")
+	require.Equal(t, 2*time.Second, cfg.Database.QueryTimeout)
+	require.Equal(t, 5*time.Minute, cfg.Cache.TTL)
+	require.Equal(t, "public, max-age=60", cfg.Cache.Control.Books)
+	require.Equal(t, "info", cfg.Log.Level, "пустая переменная не затирает значение")
+	require.Equal(t, 10*time.Second, cfg.HTTP.WriteTimeout, "остальное — по умолчанию")
+}
+
+func TestLoad_TOMLFromEnv(t *testing.T) {
+	file := writeFile(t, "app.toml", `
+env = "production"
+
+[auth]
+jwt_secret = "a-production-secret-of-at-least-32-bytes"
+
+[auth.argon2]
+memory_kib = 65536
+
+[idempotency]
+store = "postgres"
+window = "1h"
+`)
+	cfg, err := load(t, nil, map[string]string{"CONFIG_FILE": file})
+	require.NoError(t, err)
+	require.Equal(t, file, cfg.File)
+	require.Equal(t, EnvProduction, cfg.Env)
+	require.Equal(t, uint32(65536), cfg.Auth.Argon2.MemoryKiB)
+	require.Equal(t, "postgres", cfg.Idempotency.Store)
+	require.Equal(t, time.Hour, cfg.Idempotency.Window)
+}
+
+func TestLoad_RejectsMalformedInput(t *testing.T) {
+	_, err := load(t, []string{"--config", writeFile(t, "app.yaml", "http:\n  adr: \":8000\"\n")}, nil)
+	require.ErrorContains(t, err, "adr", "опечатка в ключе не должна пройти молча")
+
+	_, err = load(t, []string{"--config", writeFile(t, "app.toml", "[cache]\nsise = 10\n")}, nil)
+	require.ErrorContains(t, err, "cache.sise")
+
+	_, err = load(t, []string{"--config", writeFile(t, "app.json", "{}")}, nil)
+	require.ErrorContains(t, err, "unsupported format")
+
+	_, err = load(t, nil, map[string]string{"CACHE_TTL": "soon", "DB_MAX_CONNS": "many"})
+	require.ErrorContains(t, err, "CACHE_TTL")
+	require.ErrorContains(t, err, "DB_MAX_CONNS")
+
+	_, err = load(t, []string{"--auth.argon2.parallelism", "300"}, nil)
+	require.ErrorContains(t, err, "out of range")
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Env = "staging"
+	cfg.Admin.Addr = cfg.HTTP.Addr
+	cfg.HTTP.RequestTimeout = cfg.HTTP.WriteTimeout
+	cfg.Database.MinConns = cfg.Database.MaxConns + 1
+	cfg.Notifier.Kind = "smtp"
+	cfg.Log.SampleInitial = 10
+	cfg.RateLimit.Store = "redis"
+
+	err := cfg.Validate()
+	for _, key := range []string{"env", "admin.addr", "http.request_timeout", "database.min_conns", "notifier.smtp.host", "notifier.smtp.from", "log.sample_initial", "rate_limit.store"} {
+		require.ErrorContains(t, err, key+":")
+	}
+}
+
+func TestValidate_ProductionRefusesDevSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Env = EnvProduction
+	err := cfg.Validate()
+	require.ErrorContains(t, err, "auth.jwt_secret: development secret")
+	require.ErrorContains(t, err, "database.url: development credentials")
+
+	cfg.Auth.JWTSecret = "short"
+	require.ErrorContains(t, cfg.Validate(), "at least 32 bytes")
+
+	cfg.Auth.JWTSecret = "a-production-secret-of-at-least-32-bytes"
+	cfg.Database.URL = "postgresql://library:s3cr3t@db:5432/library"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Storage(t *testing.T) {
+	cfg := Default()
+	cfg.Storage = "sqlite"
+	cfg.SQLite.Path = ""
+	cfg.Database.URL = ""
+	cfg.RateLimit.Store = "postgres"
+	cfg.Idempotency.Store = "postgres"
+	err := cfg.Validate()
+	require.ErrorContains(t, err, "sqlite.path: is required")
+	require.ErrorContains(t, err, "rate_limit.store: postgres requires postgres storage")
+	require.ErrorContains(t, err, "idempotency.store: postgres requires postgres storage")
+	require.NotContains(t, err.Error(), "database.url", "sqlite storage does not need postgres")
+
+	cfg = Default()
+	cfg.Storage = "memory"
+	require.NoError(t, cfg.Validate())
+	cfg.Env = EnvProduction
+	cfg.Auth.JWTSecret = "a-production-secret-of-at-least-32-bytes"
+	require.ErrorContains(t, cfg.Validate(), "storage: memory storage")
+
+	cfg.Storage = "mysql"
+	require.ErrorContains(t, cfg.Validate(), "storage: must be one of")
+}
+
+func TestPrint_MasksSecretsAndRoundTrips(t *testing.T) {
+	cfg, err := load(t, nil, map[string]string{
+		"JWT_SECRET":           "top-secret-value",
+		"DB_CONNECTION_STRING": "postgresql://library:s3cr3t@db:5432/library",
+		"TENANT_BASE_DOMAIN":   "library.example.com",
+	})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+	require.NotContains(t, out.String(), "top-secret-value")
+	require.NotContains(t, out.String(), "s3cr3t")
+	require.Contains(t, out.String(), "jwt_secret: '******'")
+	require.Contains(t, out.String(), `client_secret: ""`, "незаданный секрет видно")
+
+	// напечатанное читается обратно как файл конфигурации
+	reloaded, err := load(t, []string{"--config", writeFile(t, "printed.yaml", out.String())}, nil)
+	require.NoError(t, err)
+	require.Equal(t, masked, reloaded.Auth.JWTSecret)
+	reloaded.Auth.JWTSecret, reloaded.Database.URL = cfg.Auth.JWTSecret, cfg.Database.URL
+	reloaded.File = ""
+	require.Equal(t, cfg, reloaded)
+}
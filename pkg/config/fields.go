@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field — одна настройка: лист дерева Config.
+type field struct {
+	// path — ключи от корня: "database.max_conns"
+	path   string
+	flag   string
+	env    string
+	help   string
+	secret bool
+	// reload — значение можно поменять без перезапуска
+	reload bool
+	value  reflect.Value
+}
+
+func (f field) usage() string {
+	if f.env == "" {
+		return f.help
+	}
+	return fmt.Sprintf("%s (env %s)", f.help, f.env)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldsOf перечисляет настройки cfg в порядке объявления.
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			key := sf.Tag.Get("yaml")
+			if key == "-" {
+				continue
+			}
+			path := prefix + key
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path+".")
+				continue
+			}
+			fields = append(fields, field{
+				path:   path,
+				flag:   strings.ReplaceAll(path, "_", "-"),
+				env:    sf.Tag.Get("env"),
+				help:   sf.Tag.Get("usage"),
+				secret: sf.Tag.Get("secret") == "true",
+				reload: sf.Tag.Get("reload") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// set разбирает строку из окружения или флага в значение поля.
+func (f field) set(s string) error {
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.CanInt():
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		v.SetInt(n)
+	case v.CanUint():
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// numError убирает из ошибки strconv повтор самого значения и имени функции.
+func numError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return fmt.Errorf("%q: %w", ne.Num, ne.Err)
+	}
+	return err
+}
+
+// String — значение для печати и подсказки флага.
+func (f field) String() string {
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// flagValue проверяет значение флага при разборе, но применяет его позже —
+// поверх файла и окружения (см. Load).
+type flagValue struct {
+	field field
+	set   map[string]string
+}
+
+func (v flagValue) String() string {
+	// flag вызывает String у нулевого значения, чтобы узнать, задан ли default
+	if !v.field.value.IsValid() {
+		return ""
+	}
+	if v.field.secret {
+		return ""
+	}
+	return v.field.String()
+}
+
+func (v flagValue) Set(s string) error {
+	// разбор во временную копию: поле пока хранит значение по умолчанию
+	tmp := v.field
+	tmp.value = reflect.New(v.field.value.Type()).Elem()
+	if err := tmp.set(s); err != nil {
+		return err
+	}
+	v.set[v.field.flag] = s
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// masked заменяет заданные секреты при печати; пустой секрет печатается как
+// есть, чтобы было видно, что он не задан.
+const masked = "******"
+
+// Print пишет итоговую конфигурацию в формате YAML, пригодном для --config.
+// Секреты маскируются.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fieldsOf(c) {
+		value := f.String()
+		if f.secret && value != "" {
+			value = masked
+		}
+		parent := root
+		keys := strings.Split(f.path, ".")
+		for _, key := range keys[:len(keys)-1] {
+			parent = child(parent, key)
+		}
+		parent.Content = append(parent.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: keys[len(keys)-1]},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value, Tag: scalarTag(f)},
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// child возвращает вложенный раздел key, создавая его при первом обращении.
+func child(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	return node
+}
+
+// scalarTag помечает строки явно: тогда кодировщик сам возьмёт в кавычки
+// значения вроде ":8080" или "", которые YAML прочитал бы не как строку.
+func scalarTag(f field) string {
+	if f.value.Kind() == reflect.String {
+		return "!!str"
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Update переносит в c изменённые настройки next, которые можно применить на
+// ходу (тег reload:"true"). applied — перенесённые ключи, restart — изменённые,
+// но вступающие в силу только после перезапуска; их c сохраняет прежними, чтобы
+// отражать то, с чем сервис работает. next должен пройти Validate.
+func (c *Config) Update(next *Config) (applied, restart []string) {
+	nextFields := fieldsOf(next)
+	for i, f := range fieldsOf(c) {
+		nf := nextFields[i]
+		if reflect.DeepEqual(f.value.Interface(), nf.value.Interface()) {
+			continue
+		}
+		if !f.reload {
+			restart = append(restart, f.path)
+			continue
+		}
+		f.value.Set(nf.value)
+		applied = append(applied, f.path)
+	}
+	return applied, restart
+}
+
+// Watch вызывает changed, когда у файла path меняется время изменения или
+// размер, проверяя его раз в interval. Ошибки чтения (файл заменяют
+// переименованием, и его на миг нет) пропускаются до следующей проверки.
+func Watch(ctx context.Context, path string, interval time.Duration, changed func()) {
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size() {
+			last = info
+			changed()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate_AppliesOnlyReloadableSettings(t *testing.T) {
+	running := Default()
+	next := Default()
+	next.Log.Level = "debug"
+	next.RateLimit.Requests = 100
+	next.Cache.Control.Books = "public, max-age=60"
+	next.HTTP.Addr = ":8000"
+	next.Database.MaxConns = 50
+
+	applied, restart := running.Update(&next)
+	require.Equal(t, []string{"log.level", "rate_limit.requests", "cache.control.books"}, applied)
+	require.Equal(t, []string{"http.addr", "database.max_conns"}, restart)
+
+	require.Equal(t, "debug", running.Log.Level)
+	require.Equal(t, 100, running.RateLimit.Requests)
+	require.Equal(t, "public, max-age=60", running.Cache.Control.Books)
+	// до перезапуска сервис работает со старыми значениями, и running их отражает
+	require.Equal(t, ":8080", running.HTTP.Addr)
+	require.Equal(t, 10, running.Database.MaxConns)
+
+	applied, restart = running.Update(&next)
+	require.Empty(t, applied, "применённое второй раз не применяется")
+	require.Equal(t, []string{"http.addr", "database.max_conns"}, restart)
+}
+
+func TestWatch_CallsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: info\n"), 0o600))
+
+	changed := make(chan struct{}, 10)
+	go Watch(t.Context(), path, 5*time.Millisecond, func() { changed <- struct{}{} })
+
+	select {
+	case <-changed:
+		t.Fatal("файл не менялся")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: debug\n"), 0o600))
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("изменение файла не замечено")
+	}
+}
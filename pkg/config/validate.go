@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"leti/pkg/logging"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// minProductionSecret — длина ключа HMAC-SHA256, меньше которой подпись JWT
+// подбирается заметно проще.
+const minProductionSecret = 32
+
+// devSecrets — ключи, опубликованные в репозитории (значение по умолчанию и
+// пример из .env.example): их знает любой, кто видел исходники.
+var devSecrets = []string{DevJWTSecret, "my-super-secret-jwt-key-32-characters-long"}
+
+// Validate проверяет все настройки и возвращает все найденные ошибки сразу,
+// чтобы не чинить конфигурацию по одной строке за запуск.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), key, "must be one of %v, got %q", allowed, value)
+	}
+	positive := func(key string, d time.Duration) {
+		check(d > 0, key, "must be positive")
+	}
+
+	oneOf("env", c.Env, EnvDevelopment, EnvProduction)
+
+	check(c.HTTP.Addr != "", "http.addr", "is required")
+	positive("http.read_timeout", c.HTTP.ReadTimeout)
+	positive("http.write_timeout", c.HTTP.WriteTimeout)
+	positive("http.idle_timeout", c.HTTP.IdleTimeout)
+	positive("http.request_timeout", c.HTTP.RequestTimeout)
+	// иначе сервер оборвёт соединение раньше, чем клиент получит 503
+	check(c.HTTP.RequestTimeout < c.HTTP.WriteTimeout, "http.request_timeout", "must be shorter than http.write_timeout")
+	check(c.Admin.Addr != "", "admin.addr", "is required")
+	check(c.Admin.Addr != c.HTTP.Addr, "admin.addr", "must differ from http.addr: metrics must not be public")
+	check(c.Shutdown.DrainDelay >= 0, "shutdown.drain_delay", "must not be negative")
+	positive("shutdown.timeout", c.Shutdown.Timeout)
+
+	oneOf("storage", c.Storage, "postgres", "sqlite", "memory")
+	switch c.Storage {
+	case "postgres":
+		check(c.Database.URL != "", "database.url", "is required for postgres storage")
+	case "sqlite":
+		check(c.SQLite.Path != "", "sqlite.path", "is required for sqlite storage")
+	}
+	positive("database.query_timeout", c.Database.QueryTimeout)
+	check(c.Database.MaxConns >= 1, "database.max_conns", "must be at least 1")
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns, "database.min_conns", "must be between 0 and database.max_conns")
+	positive("database.max_conn_lifetime", c.Database.MaxConnLifetime)
+	positive("database.max_conn_idle_time", c.Database.MaxConnIdleTime)
+	positive("database.health_check_period", c.Database.HealthCheckPeriod)
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret", "is required")
+	check(c.Auth.Argon2.MemoryKiB > 0, "auth.argon2.memory_kib", "must be positive")
+	check(c.Auth.Argon2.Iterations > 0, "auth.argon2.iterations", "must be positive")
+	check(c.Auth.Argon2.Parallelism > 0, "auth.argon2.parallelism", "must be positive")
+
+	if c.OIDC.Issuer != "" {
+		check(c.OIDC.ClientID != "", "oidc.client_id", "is required when oidc.issuer is set")
+		check(c.OIDC.RedirectURL != "", "oidc.redirect_url", "is required when oidc.issuer is set")
+	}
+
+	oneOf("notifier.kind", c.Notifier.Kind, "log", "file", "smtp")
+	switch c.Notifier.Kind {
+	case "file":
+		check(c.Notifier.File != "", "notifier.file", "is required for the file notifier")
+	case "smtp":
+		check(c.Notifier.SMTP.Host != "", "notifier.smtp.host", "is required for the smtp notifier")
+		check(c.Notifier.SMTP.From != "", "notifier.smtp.from", "is required for the smtp notifier")
+		check(c.Notifier.SMTP.Port > 0 && c.Notifier.SMTP.Port < 1<<16, "notifier.smtp.port", "must be a TCP port")
+	}
+
+	oneOf("log.format", c.Log.Format, "text", "json")
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	check(c.Log.SampleInitial >= 0 && c.Log.SampleThereafter >= 0, "log.sample_initial", "sampling must not be negative")
+	check((c.Log.SampleInitial == 0) == (c.Log.SampleThereafter == 0), "log.sample_initial", "log.sample_initial and log.sample_thereafter must be set together")
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "stdout", "otlp")
+	oneOf("rate_limit.store", c.RateLimit.Store, "memory", "postgres", "off")
+	check(c.RateLimit.Store != "postgres" || c.Storage == "postgres", "rate_limit.store", "postgres requires postgres storage")
+	check(c.RateLimit.Requests >= 1, "rate_limit.requests", "must be at least 1")
+	positive("rate_limit.period", c.RateLimit.Period)
+	check(c.RateLimit.Burst >= 0, "rate_limit.burst", "must not be negative")
+	check(c.Cache.Size >= 0, "cache.size", "must not be negative")
+	positive("cache.ttl", c.Cache.TTL)
+	oneOf("idempotency.store", c.Idempotency.Store, "memory", "postgres", "off")
+	check(c.Idempotency.Store != "postgres" || c.Storage == "postgres", "idempotency.store", "postgres requires postgres storage")
+	positive("idempotency.window", c.Idempotency.Window)
+	check(c.Reload.WatchInterval >= 0, "reload.watch_interval", "must not be negative")
+
+	if c.Env == EnvProduction {
+		errs = append(errs, c.productionErrors()...)
+	}
+	return errors.Join(errs...)
+}
+
+// productionErrors не даёт выкатить сервис с секретами из репозитория или с
+// данными только в памяти.
+func (c *Config) productionErrors() []error {
+	var errs []error
+	switch {
+	case slices.Contains(devSecrets, c.Auth.JWTSecret):
+		errs = append(errs, errors.New("auth.jwt_secret: development secret is not allowed in production"))
+	case len(c.Auth.JWTSecret) < minProductionSecret:
+		errs = append(errs, fmt.Errorf("auth.jwt_secret: must be at least %d bytes in production", minProductionSecret))
+	}
+	if c.Storage == "memory" {
+		errs = append(errs, errors.New("storage: memory storage loses all data on restart, not allowed in production"))
+	}
+	if u, err := url.Parse(c.Database.URL); c.Storage == "postgres" && err == nil && u.User != nil {
+		if password, _ := u.User.Password(); password == "postgres" {
+			errs = append(errs, errors.New("database.url: development credentials are not allowed in production"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,150 @@
+// Package health отвечает на пробы оркестратора: /healthz — процесс жив,
+// /readyz — зависимости доступны и экземпляр готов принимать трафик.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCheckTimeout меньше типичного таймаута пробы (1–5 с), чтобы ответить
+// "не готов", а не заставить оркестратор ждать.
+const defaultCheckTimeout = time.Second
+
+// Check проверяет одну зависимость; nil — всё в порядке.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Checker struct {
+	checks   []namedCheck
+	timeout  time.Duration
+	logger   *slog.Logger
+	draining atomic.Bool
+}
+
+// Option настраивает Checker.
+type Option func(*Checker)
+
+// WithCheck добавляет проверку готовности под именем name.
+func WithCheck(name string, check Check) Option {
+	return func(c *Checker) { c.checks = append(c.checks, namedCheck{name: name, check: check}) }
+}
+
+// WithTimeout ограничивает время всех проверок одного запроса /readyz.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Checker) { c.timeout = d }
+}
+
+// WithLogger задаёт журнал, куда пишутся ошибки проверок.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Checker) { c.logger = l }
+}
+
+func New(opts ...Option) *Checker {
+	c := &Checker{timeout: defaultCheckTimeout, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Drain переводит /readyz в "не готов", чтобы балансировщик перестал слать
+// запросы до остановки сервера. /healthz при этом продолжает отвечать 200.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// CheckResult — итог одной проверки. Текста ошибки в нём нет: /readyz
+// открыт всем, а ошибка драйвера БД раскрывает адреса и имена; она пишется в журнал.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+}
+
+// Report — тело ответа /readyz.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// Ready выполняет все проверки параллельно.
+func (c *Checker) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: statusOK, Checks: make(map[string]CheckResult, len(c.checks)+1)}
+	if c.draining.Load() {
+		report.Status = statusFail
+		report.Checks["shutdown"] = CheckResult{Status: statusFail, Duration: "0s"}
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := nc.check(ctx)
+			res := CheckResult{Status: statusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+			if err != nil {
+				res.Status = statusFail
+				c.logger.WarnContext(ctx, "Readiness check failed", "check", nc.name, "error", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = res
+			if err != nil {
+				report.Status = statusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// LivenessHandler отвечает 200, пока процесс способен обслуживать HTTP.
+// Зависимости здесь не проверяются: недоступная БД — повод не слать трафик,
+// а не перезапускать процесс.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": statusOK})
+	})
+}
+
+// ReadinessHandler отвечает 200 или 503 с разбивкой по проверкам.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context())
+		status := http.StatusOK
+		if report.Status != statusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	// пробы не должны оседать в кэшах прокси
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,85 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var report Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	return w.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	dbErr := errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	var dbDown bool
+	var logs bytes.Buffer
+	c := New(
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithCheck("database", func(context.Context) error {
+			if dbDown {
+				return dbErr
+			}
+			return nil
+		}),
+		WithCheck("migrations", func(context.Context) error { return nil }),
+	)
+
+	code, report := probe(t, c.ReadinessHandler())
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok", report.Status)
+	require.Equal(t, "ok", report.Checks["database"].Status)
+	require.Equal(t, "ok", report.Checks["migrations"].Status)
+
+	dbDown = true
+	w := httptest.NewRecorder()
+	c.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.NotContains(t, w.Body.String(), "10.0.0.5", "подробности ошибки не уходят наружу")
+	code, report = probe(t, c.ReadinessHandler())
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "fail", report.Status)
+	require.Equal(t, "fail", report.Checks["database"].Status)
+	require.Equal(t, "ok", report.Checks["migrations"].Status)
+	require.Contains(t, logs.String(), "check=database")
+	require.Contains(t, logs.String(), "10.0.0.5:5432: connection refused")
+}
+
+func TestReadiness_Timeout(t *testing.T) {
+	c := New(WithTimeout(10*time.Millisecond), WithLogger(slog.New(slog.DiscardHandler)), WithCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	code, report := probe(t, c.ReadinessHandler())
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "fail", report.Checks["slow"].Status)
+}
+
+func TestDrain(t *testing.T) {
+	c := New(WithCheck("database", func(context.Context) error { return nil }))
+	c.Drain()
+
+	code, report := probe(t, c.ReadinessHandler())
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "fail", report.Checks["shutdown"].Status)
+	require.Equal(t, "ok", report.Checks["database"].Status)
+
+	// живость не зависит от остановки
+	w := httptest.NewRecorder()
+	c.LivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
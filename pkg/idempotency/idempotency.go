@@ -0,0 +1,88 @@
+// Package idempotency запоминает ответы на запросы с заголовком Idempotency-Key,
+// чтобы повтор запроса (например, после обрыва сети) вернул тот же ответ, а не
+// создал запись второй раз.
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultWindow      = 24 * time.Hour
+	defaultLockTimeout = time.Minute
+)
+
+// Response — сохранённый ответ.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Record — состояние ключа.
+type Record struct {
+	// Fingerprint — отпечаток первого запроса: тот же ключ с другим телом — ошибка клиента.
+	Fingerprint string
+	// Response — nil, пока первый запрос ещё выполняется.
+	Response *Response
+}
+
+// Store хранит ключи. MemoryStore подходит для одного экземпляра; при нескольких
+// нужен общий (postgres.PGRepo), иначе повтор, попавший на другую реплику, выполнится заново.
+type Store interface {
+	// ReserveIdempotencyKey занимает key до lockedUntil. Если key занят
+	// действующей (не истёкшей к now) записью, возвращает её и false.
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, lockedUntil, now time.Time) (Record, bool, error)
+	// SaveIdempotentResponse сохраняет ответ занятого ключа; запись живёт до expiresAt.
+	SaveIdempotentResponse(ctx context.Context, key string, resp Response, expiresAt time.Time) error
+	// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+type Guard struct {
+	store       Store
+	window      time.Duration
+	lockTimeout time.Duration
+	now         func() time.Time
+}
+
+// Option настраивает Guard.
+type Option func(*Guard)
+
+// WithWindow задаёт, сколько хранится ответ (по умолчанию сутки).
+func WithWindow(d time.Duration) Option {
+	return func(g *Guard) { g.window = d }
+}
+
+// WithLockTimeout задаёт, через сколько незавершённый запрос считается брошенным
+// (экземпляр упал, не дописав ответ) и ключ можно занять снова. Должен быть
+// больше таймаута запроса.
+func WithLockTimeout(d time.Duration) Option {
+	return func(g *Guard) { g.lockTimeout = d }
+}
+
+func New(store Store, opts ...Option) *Guard {
+	g := &Guard{store: store, window: defaultWindow, lockTimeout: defaultLockTimeout, now: time.Now}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Begin занимает key для нового запроса. false — ключ уже использован, и Record
+// описывает первый запрос.
+func (g *Guard) Begin(ctx context.Context, key, fingerprint string) (Record, bool, error) {
+	now := g.now()
+	return g.store.ReserveIdempotencyKey(ctx, key, fingerprint, now.Add(g.lockTimeout), now)
+}
+
+// Complete сохраняет ответ на время окна.
+func (g *Guard) Complete(ctx context.Context, key string, resp Response) error {
+	return g.store.SaveIdempotentResponse(ctx, key, resp, g.now().Add(g.window))
+}
+
+// Abort освобождает ключ: ответ не сохраняется, повтор выполнится заново.
+func (g *Guard) Abort(ctx context.Context, key string) error {
+	return g.store.ReleaseIdempotencyKey(ctx, key)
+}
@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard_MemoryStore(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	g := New(NewMemoryStore(), WithWindow(time.Hour), WithLockTimeout(time.Minute))
+	g.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, ok, err := g.Begin(ctx, "k1", "fp")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// второй запрос, пока первый выполняется
+	rec, ok, err := g.Begin(ctx, "k1", "fp")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Nil(t, rec.Response)
+
+	resp := Response{Status: 201, ContentType: "application/json", Body: []byte(`{"id":1}`)}
+	require.NoError(t, g.Complete(ctx, "k1", resp))
+	rec, ok, err = g.Begin(ctx, "k1", "other")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, "fp", rec.Fingerprint)
+	require.Equal(t, &resp, rec.Response)
+
+	// окно прошло — ключ снова свободен
+	now = now.Add(time.Hour)
+	_, ok, err = g.Begin(ctx, "k1", "fp")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestGuard_AbandonedAndAborted(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	g := New(NewMemoryStore(), WithLockTimeout(time.Minute))
+	g.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, ok, err := g.Begin(ctx, "crashed", "fp")
+	require.NoError(t, err)
+	require.True(t, ok)
+	now = now.Add(time.Minute)
+	_, ok, err = g.Begin(ctx, "crashed", "fp")
+	require.NoError(t, err)
+	require.True(t, ok, "брошенный запрос не держит ключ дольше lockTimeout")
+
+	_, ok, err = g.Begin(ctx, "failed", "fp")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, g.Abort(ctx, "failed"))
+	_, ok, err = g.Begin(ctx, "failed", "fp")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто MemoryStore выбрасывает истёкшие записи.
+const sweepInterval = time.Minute
+
+type memoryRecord struct {
+	Record
+	expiresAt time.Time
+}
+
+// MemoryStore хранит ключи в памяти процесса.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+func (s *MemoryStore) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, lockedUntil, now time.Time) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, rec := range s.records {
+			if !now.Before(rec.expiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if rec, ok := s.records[key]; ok && now.Before(rec.expiresAt) {
+		return rec.Record, false, nil
+	}
+	s.records[key] = memoryRecord{Record: Record{Fingerprint: fingerprint}, expiresAt: lockedUntil}
+	return Record{Fingerprint: fingerprint}, true, nil
+}
+
+func (s *MemoryStore) SaveIdempotentResponse(ctx context.Context, key string, resp Response, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		// блокировка истекла и запись выброшена; lockTimeout больше таймаута запроса, так что сюда не попадаем
+		return nil
+	}
+	rec.Response = &resp
+	rec.expiresAt = expiresAt
+	s.records[key] = rec
+	return nil
+}
+
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package lockout защищает вход от перебора: считает неудачные попытки по
+// имени пользователя (для второго фактора — по id) и по IP и временно
+// блокирует вход с экспоненциально растущей задержкой.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"leti/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store хранит счётчики. Для одного экземпляра хватает MemoryStore,
+// при нескольких экземплярах нужен общий (postgres.PGRepo).
+type Store interface {
+	// GetLoginAttempts возвращает состояние ключа; для неизвестного ключа — нулевое.
+	GetLoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error)
+	// RecordLoginFailure атомарно увеличивает счётчик и возвращает новое значение.
+	// Неудачи старше since не учитываются: счётчик начинается заново.
+	RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+// Policy — правила блокировки для одного вида ключа.
+type Policy struct {
+	// Threshold — сколько неудач подряд допускается без задержки.
+	Threshold int
+	// BaseDelay — блокировка после Threshold-й неудачи; дальше удваивается с каждой неудачей.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window — через сколько после последней неудачи счётчик обнуляется.
+	Window time.Duration
+}
+
+// Delay возвращает длительность блокировки после failures неудач.
+func (p Policy) Delay(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+var (
+	// DefaultUserPolicy: 5 ошибок, затем 30с, 1м, 2м... до 15 минут.
+	DefaultUserPolicy = Policy{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: 15 * time.Minute, Window: time.Hour}
+	// DefaultIPPolicy мягче: за одним IP может быть целый офис или NAT.
+	DefaultIPPolicy = Policy{Threshold: 20, BaseDelay: 30 * time.Second, MaxDelay: 15 * time.Minute, Window: time.Hour}
+)
+
+// LockedError — вход временно заблокирован.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// ErrChallengeSpent — challenge второго шага погашен после серии неверных кодов:
+// вход нужно начинать заново с пароля.
+var ErrChallengeSpent = errors.New("mfa challenge spent")
+
+type Guard struct {
+	store      Store
+	userPolicy Policy
+	ipPolicy   Policy
+	now        func() time.Time
+}
+
+func NewGuard(store Store, userPolicy, ipPolicy Policy) *Guard {
+	return &Guard{store: store, userPolicy: userPolicy, ipPolicy: ipPolicy, now: time.Now}
+}
+
+func userKey(username string) string { return "user:" + strings.ToLower(username) }
+func ipKey(ip string) string         { return "ip:" + ip }
+func mfaKey(userID int) string       { return "mfa:" + strconv.Itoa(userID) }
+func challengeKey(id string) string  { return "challenge:" + id }
+
+// Check возвращает *LockedError, если вход заблокирован по имени или по IP.
+func (g *Guard) Check(ctx context.Context, username, ip string) error {
+	return g.check(ctx, g.keys(username, ip))
+}
+
+// Fail учитывает неудачную попытку и при необходимости блокирует ключ.
+func (g *Guard) Fail(ctx context.Context, username, ip string) error {
+	return g.fail(ctx, g.keys(username, ip))
+}
+
+// Succeed сбрасывает счётчик пользователя. Счётчик IP не сбрасываем: иначе
+// атакующий со своей учётной записью обнулял бы его после каждой попытки.
+func (g *Guard) Succeed(ctx context.Context, username string) error {
+	return g.store.ResetLoginAttempts(ctx, userKey(username))
+}
+
+// Unlock снимает блокировку (администратором). Пустые значения пропускаются.
+func (g *Guard) Unlock(ctx context.Context, username, ip string) error {
+	for _, key := range g.keys(username, ip) {
+		if err := g.store.ResetLoginAttempts(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckSecondFactor — Check для второго шага входа: ErrChallengeSpent для
+// погашенного challenge, *LockedError — если коды пользователя или с этого IP
+// временно не принимаются.
+func (g *Guard) CheckSecondFactor(ctx context.Context, userID int, challengeID, ip string) error {
+	// без id challenge не погасить, а токены без него живут не дольше пяти минут
+	if challengeID == "" {
+		return ErrChallengeSpent
+	}
+	state, err := g.store.GetLoginAttempts(ctx, challengeKey(challengeID))
+	if err != nil {
+		return err
+	}
+	if state.LockedUntil.After(g.now()) {
+		return ErrChallengeSpent
+	}
+	return g.check(ctx, g.secondFactorKeys(userID, ip))
+}
+
+// FailSecondFactor учитывает неверный код. Когда срабатывает блокировка,
+// challenge гасится до expiresAt, чтобы с ним нельзя было продолжить перебор
+// после паузы, и возвращается *LockedError — как на шаге пароля.
+func (g *Guard) FailSecondFactor(ctx context.Context, userID int, challengeID, ip string, expiresAt time.Time) error {
+	keys := g.secondFactorKeys(userID, ip)
+	if err := g.fail(ctx, keys); err != nil {
+		return err
+	}
+	err := g.check(ctx, keys)
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		if lockErr := g.store.LockLogin(ctx, challengeKey(challengeID), expiresAt); lockErr != nil {
+			return lockErr
+		}
+	}
+	return err
+}
+
+// SucceedSecondFactor сбрасывает счётчик кодов пользователя; счётчик IP — нет, как в Succeed.
+func (g *Guard) SucceedSecondFactor(ctx context.Context, userID int) error {
+	return g.store.ResetLoginAttempts(ctx, mfaKey(userID))
+}
+
+func (g *Guard) check(ctx context.Context, keys []string) error {
+	now := g.now()
+	var wait time.Duration
+	for _, key := range keys {
+		state, err := g.store.GetLoginAttempts(ctx, key)
+		if err != nil {
+			return err
+		}
+		if d := state.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (g *Guard) fail(ctx context.Context, keys []string) error {
+	now := g.now()
+	for _, key := range keys {
+		policy := g.userPolicy
+		if strings.HasPrefix(key, "ip:") {
+			policy = g.ipPolicy
+		}
+		failures, err := g.store.RecordLoginFailure(ctx, key, now, now.Add(-policy.Window))
+		if err != nil {
+			return err
+		}
+		if delay := policy.Delay(failures); delay > 0 {
+			if err := g.store.LockLogin(ctx, key, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Guard) keys(username, ip string) []string {
+	var keys []string
+	if username != "" {
+		keys = append(keys, userKey(username))
+	}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+// secondFactorKeys считает коды по id: имя пользователя на втором шаге не передаётся.
+func (g *Guard) secondFactorKeys(userID int, ip string) []string {
+	keys := []string{mfaKey(userID)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
@@ -0,0 +1,97 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{Threshold: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	require.Zero(t, p.Delay(2))
+	require.Equal(t, time.Second, p.Delay(3))
+	require.Equal(t, 2*time.Second, p.Delay(4))
+	require.Equal(t, 8*time.Second, p.Delay(6))
+	require.Equal(t, 10*time.Second, p.Delay(7))
+	require.Equal(t, 10*time.Second, p.Delay(100))
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	g := NewGuard(NewMemoryStore(),
+		Policy{Threshold: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour},
+		Policy{Threshold: 5, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+	g.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, g.Fail(ctx, "Den", "10.0.0.1"))
+	}
+	require.NoError(t, g.Check(ctx, "Den", "10.0.0.1"))
+
+	require.NoError(t, g.Fail(ctx, "den", "10.0.0.1"))
+	var locked *LockedError
+	require.True(t, errors.As(g.Check(ctx, "DEN", "10.0.0.2"), &locked), "username key is case-insensitive")
+	require.Equal(t, time.Minute, locked.RetryAfter)
+	// другой пользователь с того же IP пока не заблокирован
+	require.NoError(t, g.Check(ctx, "alice", "10.0.0.1"))
+
+	// после окончания блокировки следующая ошибка удваивает задержку
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, g.Check(ctx, "Den", ""))
+	require.NoError(t, g.Fail(ctx, "Den", "10.0.0.1"))
+	require.True(t, errors.As(g.Check(ctx, "Den", ""), &locked))
+	require.Equal(t, 2*time.Minute, locked.RetryAfter)
+
+	// пятая ошибка с IP блокирует IP для всех имён
+	require.NoError(t, g.Fail(ctx, "alice", "10.0.0.1"))
+	require.Error(t, g.Check(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, g.Check(ctx, "alice", "10.0.0.2"))
+
+	require.NoError(t, g.Unlock(ctx, "Den", "10.0.0.1"))
+	require.NoError(t, g.Check(ctx, "Den", "10.0.0.1"))
+
+	// счётчик обнуляется по истечении окна
+	require.NoError(t, g.Fail(ctx, "bob", ""))
+	require.NoError(t, g.Fail(ctx, "bob", ""))
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, g.Fail(ctx, "bob", ""))
+	require.NoError(t, g.Check(ctx, "bob", ""))
+}
+
+func TestGuard_SecondFactor(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	g := NewGuard(NewMemoryStore(),
+		Policy{Threshold: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour},
+		Policy{Threshold: 10, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+	g.now = func() time.Time { return now }
+	expires := now.Add(5 * time.Minute)
+
+	require.NoError(t, g.CheckSecondFactor(ctx, 7, "c1", "10.0.0.1"))
+	require.NoError(t, g.FailSecondFactor(ctx, 7, "c1", "10.0.0.1", expires))
+	require.NoError(t, g.FailSecondFactor(ctx, 7, "c1", "10.0.0.2", expires))
+
+	// третья ошибка блокирует пользователя с любого IP и гасит challenge
+	var locked *LockedError
+	require.True(t, errors.As(g.FailSecondFactor(ctx, 7, "c1", "10.0.0.3", expires), &locked))
+	require.Equal(t, time.Minute, locked.RetryAfter)
+	require.True(t, errors.As(g.CheckSecondFactor(ctx, 7, "c2", "10.0.0.4"), &locked))
+	// другой пользователь с того же IP не заблокирован
+	require.NoError(t, g.CheckSecondFactor(ctx, 8, "c3", "10.0.0.3"))
+	// счётчик пароля второй шаг не трогает
+	require.NoError(t, g.Check(ctx, "Den", "10.0.0.5"))
+
+	// после паузы погашенный challenge так и не принимается, новый — да
+	now = now.Add(2 * time.Minute)
+	require.ErrorIs(t, g.CheckSecondFactor(ctx, 7, "c1", "10.0.0.1"), ErrChallengeSpent)
+	require.NoError(t, g.CheckSecondFactor(ctx, 7, "c2", "10.0.0.1"))
+	require.ErrorIs(t, g.CheckSecondFactor(ctx, 7, "", "10.0.0.1"), ErrChallengeSpent)
+
+	require.NoError(t, g.SucceedSecondFactor(ctx, 7))
+	require.NoError(t, g.FailSecondFactor(ctx, 7, "c2", "10.0.0.1", expires))
+	require.NoError(t, g.CheckSecondFactor(ctx, 7, "c2", "10.0.0.1"), "счётчик сброшен успешным входом")
+}
@@ -0,0 +1,63 @@
+package lockout
+
+import (
+	"context"
+	"leti/pkg/models"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит счётчики в памяти процесса.
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempts
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: make(map[string]models.LoginAttempts)}
+}
+
+func (s *MemoryStore) GetLoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.attempts[key]
+	if !ok {
+		return models.LoginAttempts{Key: key}, nil
+	}
+	return state, nil
+}
+
+func (s *MemoryStore) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.attempts[key]
+	if state.LastFailureAt.Before(since) {
+		state.Failures = 0
+	}
+	state.Key = key
+	state.Failures++
+	state.LastFailureAt = now
+	s.attempts[key] = state
+	return state.Failures, nil
+}
+
+func (s *MemoryStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.attempts[key]
+	state.Key = key
+	state.LockedUntil = until
+	s.attempts[key] = state
+	return nil
+}
+
+func (s *MemoryStore) ResetLoginAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package logging настраивает slog для сервиса. Атрибуты запроса (request_id и т.п.)
+// кладутся в context, и любой вызов logger.XxxContext(ctx, ...) получает их сам —
+// передавать их по слоям руками не нужно.
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// WithAttrs добавляет атрибуты ко всем записям, сделанным с этим контекстом.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := attrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler дописывает к записи атрибуты из контекста (см. WithAttrs).
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler оборачивает h.
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+		rec = rec.Clone()
+		rec.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
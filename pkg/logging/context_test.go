@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextHandler_AddsAttrsFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewContextHandler(slog.NewTextHandler(&buf, nil))).With("component", "test")
+
+	ctx := WithAttrs(context.Background(), slog.String("request_id", "abc"))
+	ctx = WithAttrs(ctx, slog.Int("tenant_id", 2))
+	logger.InfoContext(ctx, "hello")
+	require.Contains(t, buf.String(), "component=test")
+	require.Contains(t, buf.String(), "request_id=abc tenant_id=2")
+
+	// родительский контекст не меняется
+	buf.Reset()
+	logger.InfoContext(WithAttrs(context.Background(), slog.String("request_id", "xyz")), "hello")
+	require.Contains(t, buf.String(), "request_id=xyz")
+	require.NotContains(t, buf.String(), "tenant_id")
+
+	buf.Reset()
+	logger.Info("no context")
+	require.NotContains(t, buf.String(), "request_id")
+}
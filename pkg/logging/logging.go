@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Config — настройки журнала сервиса.
+type Config struct {
+	// Format — "text" (по умолчанию, удобно читать глазами) или "json" (для сборщиков логов).
+	Format string
+	// Level — минимальный уровень. *slog.LevelVar позволяет менять его на ходу.
+	Level slog.Leveler
+	// Sampling прореживает Debug-записи; nil — без прореживания.
+	Sampling *Sampling
+}
+
+// New собирает логгер: атрибуты из контекста -> сэмплирование -> вычистка
+// секретов -> вывод. Секреты вычищаются последними, поэтому туда же попадают
+// и атрибуты контекста, и атрибуты, добавленные через With.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", cfg.Format)
+	}
+
+	h = NewRedactHandler(h)
+	if cfg.Sampling != nil {
+		h = NewSamplingHandler(h, *cfg.Sampling)
+	}
+	return slog.New(NewContextHandler(h)), nil
+}
+
+// ParseLevel разбирает уровень: debug, info, warn, error (и смещения вида "debug-4").
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: %w", s, err)
+	}
+	return level, nil
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Redacted заменяет значение секретного атрибута.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeyParts — атрибут с ключом, содержащим одну из этих подстрок, не
+// попадает в лог целиком: "password", "new_password", "access_token", "Authorization"...
+var sensitiveKeyParts = []string{"password", "token", "secret", "authorization", "cookie", "api_key", "apikey"}
+
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// secretPatterns ловят секреты, попавшие в значения под безобидными ключами
+// (текст ошибки, URL, заголовок целиком).
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// схема авторизации целиком, до того как части значения разберут правила ниже
+	{regexp.MustCompile(`(?i)\b(bearer|basic|apikey)\s+[A-Za-z0-9._~+/=-]+`), "$1 " + Redacted},
+	// JWT: три base64url-части, заголовок всегда начинается с {"
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Redacted},
+	// API-ключ lib_<prefix>_<secret>: префикс оставляем, по нему ключ можно найти
+	{regexp.MustCompile(`\b(lib_[0-9a-f]{8})_[0-9a-f]+`), "${1}_" + Redacted},
+	// параметры query: ссылка сброса пароля, OAuth-код
+	{regexp.MustCompile(`(?i)([?&](?:token|code|access_token|refresh_token|client_secret|password)=)[^&\s"]+`), "${1}" + Redacted},
+}
+
+// RedactHandler вычищает секреты из записей до того, как их увидит следующий
+// обработчик: по имени атрибута (пароли, токены, заголовки авторизации) и по
+// виду значения (JWT, API-ключи, "Bearer ..."). Разбираются и вложенные группы.
+type RedactHandler struct {
+	next slog.Handler
+}
+
+// NewRedactHandler оборачивает next.
+func NewRedactHandler(next slog.Handler) *RedactHandler {
+	return &RedactHandler{next: next}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, RedactString(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &RedactHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name)}
+}
+
+// RedactString вырезает из строки то, что похоже на токены и ключи.
+func RedactString(s string) string {
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if sensitiveKey(a.Key) && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		return slog.Attr{Key: a.Key, Value: redactAny(a.Value.Any())}
+	default:
+		return a
+	}
+}
+
+func redactText(v any, s string) slog.Value {
+	if redacted := RedactString(s); redacted != s {
+		return slog.StringValue(redacted)
+	}
+	return slog.AnyValue(v)
+}
+
+func redactAny(v any) slog.Value {
+	switch v := v.(type) {
+	case http.Header:
+		h := v.Clone()
+		for _, name := range sensitiveHeaders {
+			if h.Get(name) != "" {
+				h.Set(name, Redacted)
+			}
+		}
+		return slog.AnyValue(h)
+	case error:
+		// тип ошибки для вывода не важен, а текст может содержать токен
+		return redactText(v, v.Error())
+	case fmt.Stringer:
+		// например, *url.URL с токеном в query
+		return redactText(v, v.String())
+	default:
+		return slog.AnyValue(v)
+	}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sampling ограничивает поток отладочных записей: в каждом интервале записи с
+// одинаковым сообщением проходят первые Initial раз, дальше — каждая Thereafter-я.
+// Записи уровня Info и выше не сэмплируются никогда.
+type Sampling struct {
+	Initial    int
+	Thereafter int
+	Interval   time.Duration
+}
+
+// SamplingHandler реализует Sampling поверх next.
+type SamplingHandler struct {
+	next    slog.Handler
+	cfg     Sampling
+	counter *sampleCounter
+}
+
+type sampleCounter struct {
+	mu       sync.Mutex
+	resetAt  time.Time
+	counts   map[string]int
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewSamplingHandler оборачивает next. Нулевой Interval означает секунду.
+func NewSamplingHandler(next slog.Handler, cfg Sampling) *SamplingHandler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &SamplingHandler{
+		next: next,
+		cfg:  cfg,
+		counter: &sampleCounter{
+			counts:   make(map[string]int),
+			interval: cfg.Interval,
+			now:      time.Now,
+		},
+	}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level < slog.LevelInfo && !h.counter.allow(rec.Message, h.cfg) {
+		return nil
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), cfg: h.cfg, counter: h.counter}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), cfg: h.cfg, counter: h.counter}
+}
+
+func (c *sampleCounter) allow(msg string, cfg Sampling) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now := c.now(); now.After(c.resetAt) {
+		clear(c.counts)
+		c.resetAt = now.Add(c.interval)
+	}
+	c.counts[msg]++
+	n := c.counts[msg]
+	if n <= cfg.Initial {
+		return true
+	}
+	return cfg.Thereafter > 0 && (n-cfg.Initial)%cfg.Thereafter == 0
+}
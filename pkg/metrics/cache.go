@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"leti/pkg/repository/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// cacheCollector снимает cache.Stats в момент сбора.
+type cacheCollector struct {
+	stats func() cache.Stats
+
+	hits          *prometheus.Desc
+	misses        *prometheus.Desc
+	evictions     *prometheus.Desc
+	invalidations *prometheus.Desc
+	entries       *prometheus.Desc
+}
+
+func newCacheCollector(stats func() cache.Stats) *cacheCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", name), help, nil, nil)
+	}
+	return &cacheCollector{
+		stats:         stats,
+		hits:          desc("hits_total", "Catalog reads served from the cache."),
+		misses:        desc("misses_total", "Catalog reads that went to the database."),
+		evictions:     desc("evictions_total", "Entries evicted to stay within the size limit."),
+		invalidations: desc("invalidations_total", "Collection invalidations, local and received from other instances."),
+		entries:       desc("entries", "Entries currently cached."),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.hits, c.misses, c.evictions, c.invalidations, c.entries} {
+		ch <- d
+	}
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	counter := func(d *prometheus.Desc, v uint64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v))
+	}
+	counter(c.hits, s.Hits)
+	counter(c.misses, s.Misses)
+	counter(c.evictions, s.Evictions)
+	counter(c.invalidations, s.Invalidations)
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(s.Entries))
+}
@@ -0,0 +1,352 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"time"
+)
+
+// InstrumentDB оборачивает репозиторий так, что время каждого вызова попадает
+// в library_db_query_duration_seconds с именем метода. Исход "rejected" —
+// ожидаемая ошибка предметной области (нет записи, конфликт), "error" — сбой.
+func (m *Metrics) InstrumentDB(db repository.DataBase) repository.DataBase {
+	if m == nil {
+		return db
+	}
+	return &instrumentedDB{next: db, m: m}
+}
+
+type instrumentedDB struct {
+	next repository.DataBase
+	m    *Metrics
+}
+
+func queryOutcome(err error) string {
+	var appErr *apperr.Error
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &appErr):
+		return "rejected"
+	default:
+		return "error"
+	}
+}
+
+func (d *instrumentedDB) GetBooks(ctx context.Context) ([]models.Book, error) {
+	start := time.Now()
+	res, err := d.next.GetBooks(ctx)
+	d.m.observeQuery("GetBooks", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewBook(ctx context.Context, book models.Book) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewBook(ctx, book)
+	d.m.observeQuery("NewBook", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetBookByID(ctx context.Context, id int) (models.Book, error) {
+	start := time.Now()
+	res, err := d.next.GetBookByID(ctx, id)
+	d.m.observeQuery("GetBookByID", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) DeleteBookById(ctx context.Context, id int) error {
+	start := time.Now()
+	err := d.next.DeleteBookById(ctx, id)
+	d.m.observeQuery("DeleteBookById", start, err)
+	return err
+}
+
+func (d *instrumentedDB) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuthor, error) {
+	start := time.Now()
+	res, err := d.next.GetAllWithAuthors(ctx)
+	d.m.observeQuery("GetAllWithAuthors", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) UpdateBook(ctx context.Context, id int, update models.BookUpdate) error {
+	start := time.Now()
+	err := d.next.UpdateBook(ctx, id, update)
+	d.m.observeQuery("UpdateBook", start, err)
+	return err
+}
+
+func (d *instrumentedDB) CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error) {
+	start := time.Now()
+	res, err := d.next.CatalogVersion(ctx, collection)
+	d.m.observeQuery("CatalogVersion", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetAllGenres(ctx context.Context) ([]models.Genre, error) {
+	start := time.Now()
+	res, err := d.next.GetAllGenres(ctx)
+	d.m.observeQuery("GetAllGenres", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewGenre(ctx context.Context, genre models.Genre) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewGenre(ctx, genre)
+	d.m.observeQuery("NewGenre", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetAllAuthors(ctx context.Context) ([]models.Author, error) {
+	start := time.Now()
+	res, err := d.next.GetAllAuthors(ctx)
+	d.m.observeQuery("GetAllAuthors", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewAuthor(ctx context.Context, author models.Author) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewAuthor(ctx, author)
+	d.m.observeQuery("NewAuthor", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	start := time.Now()
+	res, err := d.next.GetUserByUsername(ctx, username)
+	d.m.observeQuery("GetUserByUsername", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	start := time.Now()
+	res, err := d.next.GetUserByID(ctx, id)
+	d.m.observeQuery("GetUserByID", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) UpdateUserRole(ctx context.Context, id int, role string) error {
+	start := time.Now()
+	err := d.next.UpdateUserRole(ctx, id, role)
+	d.m.observeQuery("UpdateUserRole", start, err)
+	return err
+}
+
+func (d *instrumentedDB) UpdateUserPassword(ctx context.Context, id int, passwordHash string) error {
+	start := time.Now()
+	err := d.next.UpdateUserPassword(ctx, id, passwordHash)
+	d.m.observeQuery("UpdateUserPassword", start, err)
+	return err
+}
+
+func (d *instrumentedDB) UpgradePasswordHash(ctx context.Context, id int, oldHash string, newHash string) error {
+	start := time.Now()
+	err := d.next.UpgradePasswordHash(ctx, id, oldHash, newHash)
+	d.m.observeQuery("UpgradePasswordHash", start, err)
+	return err
+}
+
+func (d *instrumentedDB) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	start := time.Now()
+	users, total, err := d.next.ListUsers(ctx, filter)
+	d.m.observeQuery("ListUsers", start, err)
+	return users, total, err
+}
+
+func (d *instrumentedDB) SetUserDisabled(ctx context.Context, id int, disabledAt *time.Time) error {
+	start := time.Now()
+	err := d.next.SetUserDisabled(ctx, id, disabledAt)
+	d.m.observeQuery("SetUserDisabled", start, err)
+	return err
+}
+
+func (d *instrumentedDB) DeleteUser(ctx context.Context, id int) error {
+	start := time.Now()
+	err := d.next.DeleteUser(ctx, id)
+	d.m.observeQuery("DeleteUser", start, err)
+	return err
+}
+
+func (d *instrumentedDB) GetUserByIdentity(ctx context.Context, issuer string, subject string) (*models.User, error) {
+	start := time.Now()
+	res, err := d.next.GetUserByIdentity(ctx, issuer, subject)
+	d.m.observeQuery("GetUserByIdentity", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewUserWithIdentity(ctx context.Context, user models.User, issuer string, subject string) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewUserWithIdentity(ctx, user, issuer, subject)
+	d.m.observeQuery("NewUserWithIdentity", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewAPIKey(ctx context.Context, key models.APIKey) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewAPIKey(ctx, key)
+	d.m.observeQuery("NewAPIKey", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	start := time.Now()
+	res, err := d.next.GetAPIKeyByPrefix(ctx, prefix)
+	d.m.observeQuery("GetAPIKeyByPrefix", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) ListAPIKeys(ctx context.Context, tenantID int) ([]models.APIKey, error) {
+	start := time.Now()
+	res, err := d.next.ListAPIKeys(ctx, tenantID)
+	d.m.observeQuery("ListAPIKeys", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) RevokeAPIKey(ctx context.Context, tenantID, id int) error {
+	start := time.Now()
+	err := d.next.RevokeAPIKey(ctx, tenantID, id)
+	d.m.observeQuery("RevokeAPIKey", start, err)
+	return err
+}
+
+func (d *instrumentedDB) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	start := time.Now()
+	err := d.next.TouchAPIKey(ctx, id, at)
+	d.m.observeQuery("TouchAPIKey", start, err)
+	return err
+}
+
+func (d *instrumentedDB) NewOAuthClient(ctx context.Context, client models.OAuthClient) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewOAuthClient(ctx, client)
+	d.m.observeQuery("NewOAuthClient", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	start := time.Now()
+	res, err := d.next.GetOAuthClient(ctx, clientID)
+	d.m.observeQuery("GetOAuthClient", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	start := time.Now()
+	res, err := d.next.ListOAuthClients(ctx)
+	d.m.observeQuery("ListOAuthClients", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error {
+	start := time.Now()
+	err := d.next.NewAuthorizationCode(ctx, code)
+	d.m.observeQuery("NewAuthorizationCode", start, err)
+	return err
+}
+
+func (d *instrumentedDB) ConsumeAuthorizationCode(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+	start := time.Now()
+	res, err := d.next.ConsumeAuthorizationCode(ctx, code)
+	d.m.observeQuery("ConsumeAuthorizationCode", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetTOTP(ctx context.Context, id int) (*models.TOTP, error) {
+	start := time.Now()
+	res, err := d.next.GetTOTP(ctx, id)
+	d.m.observeQuery("GetTOTP", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	start := time.Now()
+	err := d.next.SetTOTPSecret(ctx, userID, secret)
+	d.m.observeQuery("SetTOTPSecret", start, err)
+	return err
+}
+
+func (d *instrumentedDB) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	start := time.Now()
+	err := d.next.EnableTOTP(ctx, userID, recoveryCodeHashes)
+	d.m.observeQuery("EnableTOTP", start, err)
+	return err
+}
+
+func (d *instrumentedDB) DisableTOTP(ctx context.Context, id int) error {
+	start := time.Now()
+	err := d.next.DisableTOTP(ctx, id)
+	d.m.observeQuery("DisableTOTP", start, err)
+	return err
+}
+
+func (d *instrumentedDB) AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	start := time.Now()
+	res, err := d.next.AdvanceTOTPStep(ctx, userID, step)
+	d.m.observeQuery("AdvanceTOTPStep", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) ListRecoveryCodes(ctx context.Context, id int) ([]models.RecoveryCode, error) {
+	start := time.Now()
+	res, err := d.next.ListRecoveryCodes(ctx, id)
+	d.m.observeQuery("ListRecoveryCodes", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) UseRecoveryCode(ctx context.Context, id int) (bool, error) {
+	start := time.Now()
+	res, err := d.next.UseRecoveryCode(ctx, id)
+	d.m.observeQuery("UseRecoveryCode", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetMFARequiredRoles(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	res, err := d.next.GetMFARequiredRoles(ctx)
+	d.m.observeQuery("GetMFARequiredRoles", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) SetMFARequiredRoles(ctx context.Context, roles []string) error {
+	start := time.Now()
+	err := d.next.SetMFARequiredRoles(ctx, roles)
+	d.m.observeQuery("SetMFARequiredRoles", start, err)
+	return err
+}
+
+func (d *instrumentedDB) NewPasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	start := time.Now()
+	err := d.next.NewPasswordResetToken(ctx, token)
+	d.m.observeQuery("NewPasswordResetToken", start, err)
+	return err
+}
+
+func (d *instrumentedDB) ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	start := time.Now()
+	res, err := d.next.ConsumePasswordResetToken(ctx, tokenHash, now)
+	d.m.observeQuery("ConsumePasswordResetToken", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) NewTenant(ctx context.Context, tenant models.Tenant, admin *models.User) (int, error) {
+	start := time.Now()
+	res, err := d.next.NewTenant(ctx, tenant, admin)
+	d.m.observeQuery("NewTenant", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	start := time.Now()
+	res, err := d.next.ListTenants(ctx)
+	d.m.observeQuery("ListTenants", start, err)
+	return res, err
+}
+
+func (d *instrumentedDB) GetTenantBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	start := time.Now()
+	res, err := d.next.GetTenantBySlug(ctx, slug)
+	d.m.observeQuery("GetTenantBySlug", start, err)
+	return res, err
+}
@@ -0,0 +1,122 @@
+// Package metrics собирает метрики сервиса в формате Prometheus: HTTP-запросы,
+// пул соединений и запросы к БД, доменные события. Методы *Metrics безопасно
+// вызывать на nil — так слоям не нужно проверять, включены ли метрики.
+package metrics
+
+import (
+	"leti/pkg/repository/cache"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "library"
+
+// unmatchedRoute — метка маршрута для запросов, не попавших ни в один маршрут.
+// Сырой путь в метку не идёт: иначе число рядов растёт с каждым сканером.
+const unmatchedRoute = "unmatched"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	logins       *prometheus.CounterVec
+	booksCreated prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Repository call latency by method and outcome (ok, rejected, error).",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 3},
+		}, []string{"method", "outcome"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Password logins by result (success, failure).",
+		}, []string{"result"}),
+		booksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "books_created_total",
+			Help:      "Books added to the catalog.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.dbDuration, m.logins, m.booksCreated,
+	)
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterPool публикует статистику пула соединений; stat вызывается при каждом сборе.
+func (m *Metrics) RegisterPool(stat func() *pgxpool.Stat) {
+	m.registry.MustRegister(newPoolCollector(stat))
+}
+
+// RegisterCache публикует счётчики кэша каталога (см. cache.DB.Stats).
+func (m *Metrics) RegisterCache(stats func() cache.Stats) {
+	m.registry.MustRegister(newCacheCollector(stats))
+}
+
+// ObserveHTTP учитывает обработанный запрос. route — шаблон маршрута mux, "" — маршрут не найден.
+func (m *Metrics) ObserveHTTP(method, route string, status int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = unmatchedRoute
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(latency.Seconds())
+}
+
+// ObserveLogin учитывает попытку входа по паролю.
+func (m *Metrics) ObserveLogin(ok bool) {
+	if m == nil {
+		return
+	}
+	result := "failure"
+	if ok {
+		result = "success"
+	}
+	m.logins.WithLabelValues(result).Inc()
+}
+
+// BookCreated учитывает добавленную книгу.
+func (m *Metrics) BookCreated() {
+	if m == nil {
+		return
+	}
+	m.booksCreated.Inc()
+}
+
+func (m *Metrics) observeQuery(method string, start time.Time, err error) {
+	m.dbDuration.WithLabelValues(method, queryOutcome(err)).Observe(time.Since(start).Seconds())
+}
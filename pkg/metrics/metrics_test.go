@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"leti/pkg/apperr"
+	"leti/pkg/repository/cache"
+	"leti/pkg/repository/fake"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	repo := &fake.FakeRepo{}
+	require.Same(t, repo, m.InstrumentDB(repo))
+	m.ObserveHTTP(http.MethodGet, "/api/books", http.StatusOK, time.Millisecond)
+	m.ObserveLogin(true)
+	m.BookCreated()
+}
+
+func TestMetrics_Counters(t *testing.T) {
+	m := New()
+	m.BookCreated()
+	m.BookCreated()
+	m.ObserveHTTP(http.MethodPost, "", http.StatusNotFound, time.Millisecond)
+
+	out := scrape(t, m)
+	require.Contains(t, out, "library_books_created_total 2")
+	require.Contains(t, out, `library_http_requests_total{method="POST",route="unmatched",status="404"} 1`)
+	require.Contains(t, out, "go_goroutines")
+}
+
+func TestMetrics_Cache(t *testing.T) {
+	m := New()
+	m.RegisterCache(func() cache.Stats { return cache.Stats{Hits: 5, Misses: 2, Entries: 2} })
+
+	out := scrape(t, m)
+	require.Contains(t, out, "library_cache_hits_total 5")
+	require.Contains(t, out, "library_cache_misses_total 2")
+	require.Contains(t, out, "library_cache_entries 2")
+}
+
+func TestQueryOutcome(t *testing.T) {
+	require.Equal(t, "ok", queryOutcome(nil))
+	require.Equal(t, "rejected", queryOutcome(apperr.NotFound("book %d not found", 1)))
+	require.Equal(t, "error", queryOutcome(context.DeadlineExceeded))
+	require.Equal(t, "error", queryOutcome(errors.New("connection refused")))
+}
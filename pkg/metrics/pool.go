@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector снимает pgxpool.Stat в момент сбора, а не по таймеру.
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+}
+
+func newPoolCollector(stat func() *pgxpool.Stat) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		stat:              stat,
+		acquiredConns:     desc("acquired_conns", "Connections currently in use."),
+		idleConns:         desc("idle_conns", "Idle connections in the pool."),
+		constructingConns: desc("constructing_conns", "Connections being established."),
+		totalConns:        desc("total_conns", "Total connections in the pool."),
+		maxConns:          desc("max_conns", "Maximum size of the pool."),
+		acquires:          desc("acquires_total", "Successful connection acquires."),
+		acquireDuration:   desc("acquire_duration_seconds_total", "Total time spent waiting for a connection."),
+		emptyAcquires:     desc("empty_acquires_total", "Acquires that had to wait because the pool was empty."),
+		canceledAcquires:  desc("canceled_acquires_total", "Acquires canceled by context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.acquiredConns, c.idleConns, c.constructingConns, c.totalConns, c.maxConns,
+		c.acquires, c.acquireDuration, c.emptyAcquires, c.canceledAcquires,
+	} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(c.acquiredConns, float64(s.AcquiredConns()))
+	gauge(c.idleConns, float64(s.IdleConns()))
+	gauge(c.constructingConns, float64(s.ConstructingConns()))
+	gauge(c.totalConns, float64(s.TotalConns()))
+	gauge(c.maxConns, float64(s.MaxConns()))
+	counter(c.acquires, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.emptyAcquires, float64(s.EmptyAcquireCount()))
+	counter(c.canceledAcquires, float64(s.CanceledAcquireCount()))
+}
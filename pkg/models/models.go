@@ -1,27 +1,72 @@
 package models
 
+import "time"
+
 type User struct {
-	ID       int    `db:"id" json:"id"`
-	Username string `db:"username" json:"username"`
-	Password string `db:"password" json:"-"` // never to API!
-	Role     string `db:"role" json:"role"`
+	ID           int        `db:"id" json:"id"`
+	Username     string     `db:"username" json:"username"`
+	Password     string     `db:"password" json:"-"` // never to API!
+	Role         string     `db:"role" json:"role"`
+	Email        string     `db:"email" json:"email,omitempty"`
+	TokenVersion int        `db:"token_version" json:"-"` // растёт при смене пароля
+	DisabledAt   *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+	TenantID     int        `db:"tenant_id" json:"tenant_id"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
+
+// UserFilter — параметры постраничного списка пользователей.
+type UserFilter struct {
+	TenantID int    // 0 — пользователи всех библиотек
+	Search   string // подстрока имени или email, без учёта регистра
+	Limit    int
+	Offset   int
 }
+
+// Tenant — отдельная библиотека со своим каталогом и пользователями.
+type Tenant struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"` // поддомен: <slug>.<базовый домен>
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Book struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	Price     int    `json:"price"`
 	Author_id int    `json:"author_id"`
 	Genre_id  int    `json:"genre_id"`
+	ISBN      string `json:"isbn,omitempty"`
+	TenantID  int    `json:"-"`
+	// UpdatedAt — время последнего изменения, из него строится ETag книги.
+	UpdatedAt time.Time `json:"-"`
+}
+
+// Коллекции каталога, у которых есть версия (см. CatalogVersion).
+const (
+	CollectionAuthors = "authors"
+	CollectionGenres  = "genres"
+	CollectionBooks   = "books"
+)
+
+// CatalogVersion — версия коллекции каталога библиотеки. Version растёт при
+// каждом изменении (в том числе удалении) строк коллекции; 0 — коллекцию ещё
+// не меняли. UpdatedAt — время последнего изменения.
+type CatalogVersion struct {
+	Version   int64
+	UpdatedAt time.Time
 }
 
 type Genre struct {
-	ID    int    `json:"id"`
-	Genre string `json:"genre"`
+	ID       int    `json:"id"`
+	Genre    string `json:"genre"`
+	TenantID int    `json:"-"`
 }
 
 type Author struct {
-	ID     int    `json:"id"`
-	Author string `json:"author"`
+	ID       int    `json:"id"`
+	Author   string `json:"author"`
+	TenantID int    `json:"-"`
 }
 
 type BookWithAuthor struct {
@@ -36,4 +81,81 @@ type BookWithAuthor struct {
 type BookUpdate struct {
 	Name  *string `json:"name,omitempty"`
 	Price *int    `json:"price,omitempty"`
+	ISBN  *string `json:"isbn,omitempty"`
+}
+
+// APIKey — ключ доступа для машинных клиентов (скрипты импорта, партнёры).
+// Сам секрет не хранится: только его хэш и видимый префикс.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// OAuthClient — стороннее приложение, зарегистрированное в сервере авторизации.
+// У публичных клиентов (SPA, мобильные) секрета нет, SecretHash пустой.
+type OAuthClient struct {
+	ID           int       `json:"id"`
+	ClientID     string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (c OAuthClient) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// AuthorizationCode — одноразовый код из authorization code flow.
+type AuthorizationCode struct {
+	CodeHash            string
+	ClientID            string
+	UserID              int
+	RedirectURI         string
+	Scopes              []string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+}
+
+// TOTP — второй фактор пользователя. Пока Enabled=false, секрет ждёт подтверждения.
+type TOTP struct {
+	UserID   int
+	Secret   string
+	Enabled  bool
+	LastStep int64 // последний принятый шаг: код нельзя использовать повторно
+}
+
+type RecoveryCode struct {
+	ID       int
+	UserID   int
+	CodeHash string
+}
+
+// PasswordResetToken — одноразовый токен сброса пароля. Хранится только хэш.
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// LoginAttempts — счётчик неудачных входов по ключу (имя пользователя или IP).
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time // нулевое значение — не заблокирован
 }
@@ -0,0 +1,126 @@
+// Package notify доставляет пользователям служебные сообщения
+// (например, ссылку для сброса пароля).
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoRecipient — у пользователя нет адреса, на который можно отправить сообщение.
+var ErrNoRecipient = errors.New("notify: recipient has no address")
+
+type Message struct {
+	To       string // email получателя
+	Username string
+	Subject  string
+	Body     string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// LogNotifier отмечает в логе, что сообщение отправлено. Тело письма не пишется:
+// в нём одноразовые ссылки и коды. Прочитать письмо локально — FileNotifier.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	n.logger.InfoContext(ctx, "Notification", "to", msg.To, "username", msg.Username,
+		"subject", msg.Subject)
+	return nil
+}
+
+// FileNotifier дописывает сообщения в файл, по одному JSON на строку.
+// Удобно для локальной разработки и e2e-тестов.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPNotifier отправляет письма через SMTP-сервер (STARTTLS, если сервер его поддерживает).
+type SMTPNotifier struct {
+	cfg  SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg, send: smtp.SendMail}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+	// Защита от подстановки заголовков через адрес или тему
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("notify: invalid header value")
+	}
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	return n.send(addr, auth, n.cfg.From, []string{msg.To}, []byte(b.String()))
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileNotifier_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	n := NewFileNotifier(path)
+	require.NoError(t, n.Notify(context.Background(), Message{To: "a@example.com", Subject: "one", Body: "1"}))
+	require.NoError(t, n.Notify(context.Background(), Message{To: "b@example.com", Subject: "two", Body: "2"}))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var subjects []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		subjects = append(subjects, msg.Subject)
+	}
+	require.Equal(t, []string{"one", "two"}, subjects)
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	n := NewSMTPNotifier(SMTPConfig{Host: "smtp.example.com", Port: 587, From: "library@example.com"})
+	var gotAddr string
+	var gotMsg []byte
+	n.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotMsg = addr, msg
+		return nil
+	}
+
+	require.ErrorIs(t, n.Notify(context.Background(), Message{Subject: "x"}), ErrNoRecipient)
+	require.Error(t, n.Notify(context.Background(), Message{To: "a@example.com", Subject: "x\r\nBcc: evil@example.com"}))
+
+	require.NoError(t, n.Notify(context.Background(), Message{To: "a@example.com", Subject: "Сброс пароля", Body: "line1\nline2"}))
+	require.Equal(t, "smtp.example.com:587", gotAddr)
+	require.True(t, strings.HasPrefix(string(gotMsg), "From: library@example.com\r\nTo: a@example.com\r\n"))
+	require.Contains(t, string(gotMsg), "\r\n\r\nline1\r\nline2")
+}
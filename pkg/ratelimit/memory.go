@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто MemoryStore выбрасывает полные корзины: их
+// состояние не отличается от отсутствующего.
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// MemoryStore хранит корзины в памяти процесса.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]memoryBucket)}
+}
+
+func (s *MemoryStore) TakeToken(ctx context.Context, key string, bucket Bucket, now time.Time) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.fullAt) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	tokens := float64(bucket.Capacity)
+	if b, ok := s.buckets[key]; ok {
+		tokens = bucket.Refill(b.tokens, now.Sub(b.updatedAt))
+	}
+	ok := tokens >= 1
+	if ok {
+		tokens--
+	}
+	missing := float64(bucket.Capacity) - tokens
+	s.buckets[key] = memoryBucket{
+		tokens:    tokens,
+		updatedAt: now,
+		fullAt:    now.Add(secondsToDuration(missing / bucket.RatePerSecond)),
+	}
+	return tokens, ok, nil
+}
@@ -0,0 +1,129 @@
+// Package ratelimit ограничивает частоту запросов по алгоритму token bucket:
+// у каждого клиента корзина на Burst токенов, которая пополняется со скоростью
+// Requests за Period; запрос забирает один токен.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Store хранит корзины. Для одного экземпляра хватает MemoryStore, при
+// нескольких экземплярах нужен общий (postgres.PGRepo), иначе каждая реплика
+// выдаёт свой бюджет.
+type Store interface {
+	// TakeToken пополняет корзину key на время с прошлого обращения и, если в ней
+	// есть целый токен, забирает его. Возвращает остаток токенов и забран ли токен.
+	TakeToken(ctx context.Context, key string, bucket Bucket, now time.Time) (tokens float64, ok bool, err error)
+}
+
+// Limit — правило для маршрута: Requests запросов за Period с запасом Burst.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	// Burst — ёмкость корзины; 0 — равна Requests.
+	Burst int
+}
+
+// Bucket — параметры корзины в виде, удобном хранилищу.
+type Bucket struct {
+	Capacity int
+	// RatePerSecond — скорость пополнения, токенов в секунду.
+	RatePerSecond float64
+}
+
+// Capacity — ёмкость корзины с учётом значения Burst по умолчанию.
+func (l Limit) Capacity() int {
+	if l.Burst <= 0 {
+		return l.Requests
+	}
+	return l.Burst
+}
+
+func (l Limit) bucket() Bucket {
+	return Bucket{Capacity: l.Capacity(), RatePerSecond: float64(l.Requests) / l.Period.Seconds()}
+}
+
+// Refill — сколько токенов будет в корзине через elapsed. Общая арифметика
+// для хранилищ; PGRepo повторяет её в SQL, чтобы обновление было атомарным.
+func (b Bucket) Refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(b.Capacity), tokens+elapsed.Seconds()*b.RatePerSecond)
+}
+
+// Result — решение по запросу и данные для заголовков RateLimit-*.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining — целых токенов осталось.
+	Remaining int
+	// Reset — через сколько корзина наполнится полностью.
+	Reset time.Duration
+	// RetryAfter — через сколько появится токен; 0, если запрос пропущен.
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	store Store
+	// defaultLimit меняется на ходу (SetDefaultLimit), поэтому атомарный
+	defaultLimit atomic.Pointer[Limit]
+	routes       map[string]Limit
+	now          func() time.Time
+}
+
+// Option настраивает Limiter.
+type Option func(*Limiter)
+
+// WithRoute задаёт маршруту (шаблону пути mux) отдельный лимит с отдельной корзиной.
+func WithRoute(route string, limit Limit) Option {
+	return func(l *Limiter) { l.routes[route] = limit }
+}
+
+// New создаёт ограничитель; defaultLimit действует на все маршруты без
+// собственного лимита, и бюджет у них общий.
+func New(store Store, defaultLimit Limit, opts ...Option) *Limiter {
+	l := &Limiter{store: store, routes: make(map[string]Limit), now: time.Now}
+	l.SetDefaultLimit(defaultLimit)
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// SetDefaultLimit меняет общий лимит без перезапуска. Накопленные токены
+// сохраняются; если новая корзина меньше, лишние отбрасываются при следующем запросе.
+func (l *Limiter) SetDefaultLimit(limit Limit) {
+	l.defaultLimit.Store(&limit)
+}
+
+// Allow списывает запрос клиента client (например "ip:10.0.0.1" или "user:7") к маршруту route.
+func (l *Limiter) Allow(ctx context.Context, route, client string) (Result, error) {
+	limit, key := *l.defaultLimit.Load(), client
+	if routeLimit, ok := l.routes[route]; ok {
+		limit, key = routeLimit, route+"|"+client
+	}
+	bucket := limit.bucket()
+
+	tokens, ok, err := l.store.TakeToken(ctx, key, bucket, l.now())
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{
+		Allowed:   ok,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(bucket.Capacity) - tokens) / bucket.RatePerSecond),
+	}
+	if !ok {
+		res.RetryAfter = secondsToDuration((1 - tokens) / bucket.RatePerSecond)
+	}
+	return res, nil
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(opts ...Option) (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(NewMemoryStore(), Limit{Requests: 60, Period: time.Minute, Burst: 3}, opts...)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	l, now := newTestLimiter()
+	ctx := context.Background()
+
+	for want := 2; want >= 0; want-- {
+		res, err := l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, want, res.Remaining)
+	}
+
+	res, err := l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, time.Second, res.RetryAfter)
+	require.Equal(t, 3*time.Second, res.Reset)
+
+	// у другого клиента своя корзина
+	res, err = l.Allow(ctx, "/api/books", "ip:10.0.0.2")
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	// 60 в минуту — токен в секунду
+	*now = now.Add(1500 * time.Millisecond)
+	res, err = l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+
+	// корзина не переполняется
+	*now = now.Add(time.Hour)
+	res, err = l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Remaining)
+}
+
+func TestLimiter_RouteLimitsHaveOwnBuckets(t *testing.T) {
+	login := Limit{Requests: 1, Period: time.Minute}
+	l, _ := newTestLimiter(WithRoute("/api/auth/login", login))
+	ctx := context.Background()
+
+	res, err := l.Allow(ctx, "/api/auth/login", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, login, res.Limit)
+
+	res, err = l.Allow(ctx, "/api/auth/login", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, time.Minute, res.RetryAfter)
+
+	// остальные маршруты делят общий бюджет и не зависят от лимита входа
+	for _, route := range []string{"/api/books", "/api/authors", "/api/genres"} {
+		res, err = l.Allow(ctx, route, "ip:10.0.0.1")
+		require.NoError(t, err)
+		require.True(t, res.Allowed, route)
+	}
+	res, err = l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+}
+
+func TestLimiter_SetDefaultLimit(t *testing.T) {
+	l, _ := newTestLimiter()
+	ctx := context.Background()
+
+	res, err := l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Remaining)
+
+	// корзина уменьшилась: лишние токены пропадают, бюджет клиента не обнуляется
+	l.SetDefaultLimit(Limit{Requests: 60, Period: time.Minute, Burst: 2})
+	res, err = l.Allow(ctx, "/api/books", "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 1, res.Remaining)
+	require.Equal(t, 2, res.Limit.Capacity())
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	b := Bucket{Capacity: 2, RatePerSecond: 1}
+	start := time.Now()
+	_, _, err := s.TakeToken(context.Background(), "a", b, start)
+	require.NoError(t, err)
+	_, _, err = s.TakeToken(context.Background(), "b", b, start.Add(sweepInterval))
+	require.NoError(t, err)
+	require.Len(t, s.buckets, 1)
+	require.Contains(t, s.buckets, "b")
+}
@@ -0,0 +1,244 @@
+// Package cache — кэширующий декоратор repository.DataBase для чтения каталога.
+//
+// Кэшируются только книги, авторы, жанры и версии коллекций: они меняются
+// редко, а читаются постоянно. Пользователи, ключи и токены всегда читаются из
+// базы — устаревшая роль или отозванный ключ в кэше были бы дырой в безопасности.
+// Запись через декоратор сбрасывает затронутые коллекции библиотеки и рассылает
+// сброс остальным экземплярам (см. PubSub).
+package cache
+
+import (
+	"context"
+	"fmt"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/tenant"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSize = 1000
+	defaultTTL  = time.Minute
+	// listenRetry — пауза перед повторной подпиской после обрыва.
+	listenRetry = time.Second
+
+	// Channel — канал LISTEN/NOTIFY, по которому экземпляры рассылают сбросы.
+	Channel = "library_cache_invalidation"
+)
+
+// groupBooksWithAuthors — книги с именами авторов зависят от обеих коллекций.
+const groupBooksWithAuthors = "books_with_authors"
+
+// dependents — какие группы устаревают при изменении коллекции.
+var dependents = map[string][]string{
+	models.CollectionBooks:   {models.CollectionBooks, groupBooksWithAuthors},
+	models.CollectionAuthors: {models.CollectionAuthors, groupBooksWithAuthors},
+	models.CollectionGenres:  {models.CollectionGenres},
+}
+
+// PubSub доставляет сбросы кэша другим экземплярам (postgres.PGRepo — через LISTEN/NOTIFY).
+type PubSub interface {
+	Notify(ctx context.Context, channel, payload string) error
+	// Listen вызывает subscribed, когда подписка начала действовать, и handle
+	// на каждое сообщение. Возвращает управление при отмене ctx или обрыве соединения.
+	Listen(ctx context.Context, channel string, subscribed func(), handle func(payload string)) error
+}
+
+// Stats — счётчики кэша с момента запуска (Entries — текущее число записей).
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// DB кэширует чтение каталога; остальные методы идут в next напрямую.
+type DB struct {
+	repository.DataBase
+
+	lru    *lru
+	ps     PubSub
+	logger *slog.Logger
+	// live — подписка на сбросы действует. Без неё кэш не используется:
+	// пропущенный сброс означал бы устаревшие данные до истечения TTL.
+	live atomic.Bool
+}
+
+// Option настраивает DB.
+type Option func(*DB)
+
+// WithSize ограничивает число записей (по умолчанию 1000).
+func WithSize(n int) Option {
+	return func(d *DB) { d.lru.size = n }
+}
+
+// WithTTL задаёт время жизни записи (по умолчанию минута). Это верхняя граница
+// устаревания, если сброс от другого экземпляра всё-таки потерялся.
+func WithTTL(ttl time.Duration) Option {
+	return func(d *DB) { d.lru.ttl = ttl }
+}
+
+// WithPubSub включает обмен сбросами между экземплярами. Пока Listen не
+// подписался, кэш пропускает все запросы в базу.
+func WithPubSub(ps PubSub) Option {
+	return func(d *DB) { d.ps = ps }
+}
+
+// WithLogger задаёт журнал для ошибок рассылки и подписки.
+func WithLogger(l *slog.Logger) Option {
+	return func(d *DB) { d.logger = l }
+}
+
+func New(next repository.DataBase, opts ...Option) *DB {
+	d := &DB{DataBase: next, lru: newLRU(defaultSize, defaultTTL), logger: slog.Default()}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.live.Store(d.ps == nil)
+	return d
+}
+
+// Stats возвращает счётчики попаданий и промахов (для метрик).
+func (d *DB) Stats() Stats {
+	return d.lru.snapshot()
+}
+
+// Listen держит подписку на сбросы от других экземпляров до отмены ctx,
+// переподключаясь после обрыва. Без WithPubSub сразу возвращает nil.
+func (d *DB) Listen(ctx context.Context) error {
+	if d.ps == nil {
+		return nil
+	}
+	for {
+		err := d.ps.Listen(ctx, Channel, d.subscribed, d.handle)
+		// сбросы, пришедшие без подписки, потеряны — забываем всё
+		d.live.Store(false)
+		d.lru.purge()
+		if ctx.Err() != nil {
+			return nil
+		}
+		d.logger.Warn("Cache invalidation listener disconnected", "error", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenRetry):
+		}
+	}
+}
+
+func (d *DB) subscribed() {
+	d.lru.purge()
+	d.live.Store(true)
+}
+
+// handle применяет сброс "<арендатор>:<коллекция>" от другого экземпляра (или свой же — это безвредно).
+func (d *DB) handle(payload string) {
+	tenantStr, collection, ok := strings.Cut(payload, ":")
+	tenantID, err := strconv.Atoi(tenantStr)
+	if !ok || err != nil {
+		d.logger.Warn("Malformed cache invalidation", "payload", payload)
+		return
+	}
+	d.invalidateLocal(tenantID, collection)
+}
+
+func (d *DB) invalidateLocal(tenantID int, collection string) {
+	for _, group := range dependents[collection] {
+		d.lru.invalidate(groupKey{tenantID: tenantID, group: group})
+	}
+}
+
+// invalidate сбрасывает коллекцию у себя и у остальных экземпляров. Вызывается
+// и при ошибке записи: неизвестно, успела ли она примениться.
+func (d *DB) invalidate(ctx context.Context, collection string) {
+	tenantID := tenant.FromContext(ctx)
+	d.invalidateLocal(tenantID, collection)
+	if d.ps == nil {
+		return
+	}
+	// запись уже сделана, так что отмена запроса не должна оставить реплики со старыми данными
+	payload := fmt.Sprintf("%d:%s", tenantID, collection)
+	if err := d.ps.Notify(context.WithoutCancel(ctx), Channel, payload); err != nil {
+		d.logger.WarnContext(ctx, "Failed to broadcast cache invalidation", "collection", collection, "error", err)
+	}
+}
+
+// read — чтение через кэш. clone защищает закэшированное значение от
+// изменения вызывающим.
+func read[T any](ctx context.Context, d *DB, group, id string, clone func(T) T, load func(context.Context) (T, error)) (T, error) {
+	if !d.live.Load() {
+		return load(ctx)
+	}
+	key := entryKey{groupKey: groupKey{tenantID: tenant.FromContext(ctx), group: group}, id: id}
+	cached, t, ok := d.lru.get(key)
+	if ok {
+		return clone(cached.(T)), nil
+	}
+	v, err := load(ctx)
+	if err != nil {
+		return v, err
+	}
+	d.lru.add(key, clone(v), t)
+	return v, nil
+}
+
+func same[T any](v T) T { return v }
+
+func (d *DB) GetBooks(ctx context.Context) ([]models.Book, error) {
+	return read(ctx, d, models.CollectionBooks, "", slices.Clone, d.DataBase.GetBooks)
+}
+
+func (d *DB) GetBookByID(ctx context.Context, id int) (models.Book, error) {
+	return read(ctx, d, models.CollectionBooks, strconv.Itoa(id), same, func(ctx context.Context) (models.Book, error) {
+		return d.DataBase.GetBookByID(ctx, id)
+	})
+}
+
+func (d *DB) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuthor, error) {
+	return read(ctx, d, groupBooksWithAuthors, "", slices.Clone, d.DataBase.GetAllWithAuthors)
+}
+
+func (d *DB) GetAllAuthors(ctx context.Context) ([]models.Author, error) {
+	return read(ctx, d, models.CollectionAuthors, "", slices.Clone, d.DataBase.GetAllAuthors)
+}
+
+func (d *DB) GetAllGenres(ctx context.Context) ([]models.Genre, error) {
+	return read(ctx, d, models.CollectionGenres, "", slices.Clone, d.DataBase.GetAllGenres)
+}
+
+func (d *DB) CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error) {
+	return read(ctx, d, collection, "version", same, func(ctx context.Context) (models.CatalogVersion, error) {
+		return d.DataBase.CatalogVersion(ctx, collection)
+	})
+}
+
+func (d *DB) NewBook(ctx context.Context, book models.Book) (int, error) {
+	defer d.invalidate(ctx, models.CollectionBooks)
+	return d.DataBase.NewBook(ctx, book)
+}
+
+func (d *DB) UpdateBook(ctx context.Context, id int, update models.BookUpdate) error {
+	defer d.invalidate(ctx, models.CollectionBooks)
+	return d.DataBase.UpdateBook(ctx, id, update)
+}
+
+func (d *DB) DeleteBookById(ctx context.Context, id int) error {
+	defer d.invalidate(ctx, models.CollectionBooks)
+	return d.DataBase.DeleteBookById(ctx, id)
+}
+
+func (d *DB) NewAuthor(ctx context.Context, author models.Author) (int, error) {
+	defer d.invalidate(ctx, models.CollectionAuthors)
+	return d.DataBase.NewAuthor(ctx, author)
+}
+
+func (d *DB) NewGenre(ctx context.Context, genre models.Genre) (int, error) {
+	defer d.invalidate(ctx, models.CollectionGenres)
+	return d.DataBase.NewGenre(ctx, genre)
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"leti/pkg/tenant"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepo считает обращения к базе за списком книг.
+type countingRepo struct {
+	*fake.FakeRepo
+	getBooks atomic.Int32
+}
+
+func (r *countingRepo) GetBooks(ctx context.Context) ([]models.Book, error) {
+	r.getBooks.Add(1)
+	return r.FakeRepo.GetBooks(ctx)
+}
+
+func seedCatalog(t *testing.T, ctx context.Context, db *DB) (authorID, genreID int) {
+	t.Helper()
+	authorID, err := db.NewAuthor(ctx, models.Author{Author: "Пушкин"})
+	require.NoError(t, err)
+	genreID, err = db.NewGenre(ctx, models.Genre{Genre: "Поэзия"})
+	require.NoError(t, err)
+	return authorID, genreID
+}
+
+func TestDB_ReadThroughAndInvalidate(t *testing.T) {
+	repo := &countingRepo{FakeRepo: &fake.FakeRepo{}}
+	db := New(repo)
+	ctx := context.Background()
+	authorID, genreID := seedCatalog(t, ctx, db)
+
+	_, err := db.NewBook(ctx, models.Book{Name: "Руслан и Людмила", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+
+	books, err := db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	books[0].Name = "испорчено вызывающим"
+
+	books, err = db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "Руслан и Людмила", books[0].Name)
+	require.EqualValues(t, 1, repo.getBooks.Load(), "второе чтение — из кэша")
+
+	// у другой библиотеки свои ключи
+	other := tenant.WithID(ctx, 2)
+	books, err = db.GetBooks(other)
+	require.NoError(t, err)
+	require.Empty(t, books)
+	require.EqualValues(t, 2, repo.getBooks.Load())
+
+	_, err = db.NewBook(ctx, models.Book{Name: "Евгений Онегин", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	books, err = db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 2, "запись сбрасывает коллекцию")
+	require.EqualValues(t, 3, repo.getBooks.Load())
+
+	// запись в одной библиотеке не трогает кэш другой
+	_, err = db.GetBooks(other)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, repo.getBooks.Load())
+
+	s := db.Stats()
+	require.EqualValues(t, 2, s.Hits)
+	require.EqualValues(t, 3, s.Misses)
+	require.Equal(t, 2, s.Entries)
+}
+
+func TestDB_AuthorChangeInvalidatesBooksWithAuthors(t *testing.T) {
+	db := New(&fake.FakeRepo{})
+	ctx := context.Background()
+	authorID, genreID := seedCatalog(t, ctx, db)
+	_, err := db.NewBook(ctx, models.Book{Name: "Полтава", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+
+	_, err = db.GetAllWithAuthors(ctx)
+	require.NoError(t, err)
+	version, err := db.CatalogVersion(ctx, models.CollectionAuthors)
+	require.NoError(t, err)
+
+	_, err = db.NewAuthor(ctx, models.Author{Author: "Лермонтов"})
+	require.NoError(t, err)
+	require.Equal(t, 0, db.Stats().Entries)
+
+	newVersion, err := db.CatalogVersion(ctx, models.CollectionAuthors)
+	require.NoError(t, err)
+	require.Greater(t, newVersion.Version, version.Version)
+}
+
+func TestLRU_EvictionAndTTL(t *testing.T) {
+	c := newLRU(2, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	key := func(id string) entryKey { return entryKey{groupKey: groupKey{1, "books"}, id: id} }
+
+	for _, id := range []string{"1", "2"} {
+		_, tok, _ := c.get(key(id))
+		c.add(key(id), id, tok)
+	}
+	_, _, ok := c.get(key("1")) // "1" становится свежее "2"
+	require.True(t, ok)
+	_, tok, _ := c.get(key("3"))
+	c.add(key("3"), "3", tok)
+
+	_, _, ok = c.get(key("2"))
+	require.False(t, ok, "вытеснена давно не читанная запись")
+	require.EqualValues(t, 1, c.snapshot().Evictions)
+
+	now = now.Add(time.Minute)
+	_, _, ok = c.get(key("1"))
+	require.False(t, ok, "запись истекла")
+}
+
+func TestLRU_StaleLoadIsNotStored(t *testing.T) {
+	c := newLRU(10, time.Minute)
+	k := entryKey{groupKey: groupKey{1, "books"}}
+
+	// промах, затем сброс, пока данные читаются из базы
+	_, tok, _ := c.get(k)
+	c.invalidate(k.groupKey)
+	c.add(k, "old", tok)
+	_, _, ok := c.get(k)
+	require.False(t, ok)
+
+	_, tok, _ = c.get(k)
+	c.purge()
+	c.add(k, "old", tok)
+	_, _, ok = c.get(k)
+	require.False(t, ok)
+}
+
+// memoryPubSub — шина в памяти, как LISTEN/NOTIFY для нескольких экземпляров.
+type memoryPubSub struct {
+	mu        sync.Mutex
+	listeners []func(string)
+}
+
+func (p *memoryPubSub) Notify(_ context.Context, _, payload string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, l := range p.listeners {
+		l(payload)
+	}
+	return nil
+}
+
+func (p *memoryPubSub) Listen(ctx context.Context, _ string, subscribed func(), handle func(string)) error {
+	p.mu.Lock()
+	p.listeners = append(p.listeners, handle)
+	p.mu.Unlock()
+	subscribed()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDB_BroadcastInvalidation(t *testing.T) {
+	repo := &countingRepo{FakeRepo: &fake.FakeRepo{}}
+	bus := &memoryPubSub{}
+	first, second := New(repo, WithPubSub(bus)), New(repo, WithPubSub(bus))
+	ctx := context.Background()
+
+	// пока подписки нет, кэш не используется
+	_, err := second.GetBooks(ctx)
+	require.NoError(t, err)
+	_, err = second.GetBooks(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, repo.getBooks.Load())
+
+	listenCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, db := range []*DB{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, db.Listen(listenCtx))
+		}()
+	}
+	require.Eventually(t, func() bool { return first.live.Load() && second.live.Load() }, time.Second, time.Millisecond)
+
+	_, err = second.GetBooks(ctx)
+	require.NoError(t, err)
+	_, err = second.GetBooks(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, repo.getBooks.Load())
+
+	// запись через первый экземпляр сбрасывает кэш второго
+	authorID, genreID := seedCatalog(t, ctx, first)
+	_, err = first.NewBook(ctx, models.Book{Name: "Медный всадник", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	books, err := second.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.EqualValues(t, 4, repo.getBooks.Load())
+
+	cancel()
+	wg.Wait()
+	require.False(t, second.live.Load())
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// groupKey — набор записей, которые сбрасываются вместе: данные одной
+// коллекции одной библиотеки.
+type groupKey struct {
+	tenantID int
+	group    string
+}
+
+type entryKey struct {
+	groupKey
+	// id различает записи группы ("" — вся коллекция)
+	id string
+}
+
+type entry struct {
+	key     entryKey
+	value   any
+	expires time.Time
+}
+
+// token запоминает состояние группы на момент промаха. Если группу успели
+// сбросить, пока данные читались из базы, прочитанное уже может быть устаревшим
+// и в кэш не попадает.
+type token struct {
+	epoch uint64
+	gen   uint64
+}
+
+// lru — кэш с вытеснением давно не читанных записей и временем жизни.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	now   func() time.Time
+	ll    *list.List // от свежих к старым
+	items map[entryKey]*list.Element
+	gens  map[groupKey]uint64
+	// epoch растёт при полном сбросе (purge)
+	epoch uint64
+	stats Stats
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		ll:    list.New(),
+		items: make(map[entryKey]*list.Element),
+		gens:  make(map[groupKey]uint64),
+	}
+}
+
+// get возвращает значение или, при промахе, token для последующего add.
+func (c *lru) get(k entryKey) (any, token, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[k]; ok {
+		e := el.Value.(*entry)
+		if c.now().Before(e.expires) {
+			c.ll.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, token{}, true
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	return nil, token{epoch: c.epoch, gen: c.gens[k.groupKey]}, false
+}
+
+func (c *lru) add(k entryKey, v any, t token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t.epoch != c.epoch || t.gen != c.gens[k.groupKey] {
+		return
+	}
+	if el, ok := c.items[k]; ok {
+		c.remove(el)
+	}
+	c.items[k] = c.ll.PushFront(&entry{key: k, value: v, expires: c.now().Add(c.ttl)})
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate сбрасывает группу. Записей немного, поэтому полный проход
+// дешевле отдельного индекса по группам.
+func (c *lru) invalidate(g groupKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gens[g]++
+	c.stats.Invalidations++
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*entry).key.groupKey == g {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+func (c *lru) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	c.ll.Init()
+	clear(c.items)
+}
+
+func (c *lru) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
+
+func (c *lru) snapshot() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Entries = c.ll.Len()
+	return s
+}
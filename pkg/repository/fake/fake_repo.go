@@ -1,12 +1,16 @@
-// Package fake содержит фейковую реализацию repository.DataBase для unit-тестов.
+// Package fake содержит реализацию repository.DataBase в памяти: для unit-тестов
+// и для запуска без базы (storage: memory).
 package fake
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 // FakeRepo реализует интерфейс repository.DataBase
@@ -14,10 +18,31 @@ type FakeRepo struct {
 	mu sync.RWMutex
 
 	// Хранилища данных (имитируют БД)
-	authors []models.Author
-	books   []models.Book
-	genres  []models.Genre
-	users   []models.User
+	authors      []models.Author
+	books        []models.Book
+	lastBookID   int // id не переиспользуются после удаления, как у SERIAL
+	genres       []models.Genre
+	users        []models.User
+	lastUserID   int
+	apiKeys      []models.APIKey
+	lastAPIKeyID int
+
+	identities map[[2]string]int // (issuer, subject) -> user id
+
+	oauthClients []models.OAuthClient
+	authCodes    map[string]models.AuthorizationCode
+
+	totp             map[int]models.TOTP
+	recoveryCodes    []models.RecoveryCode
+	lastRecoveryID   int
+	usedRecovery     map[int]bool
+	mfaRequiredRoles []string
+
+	resetTokens map[string]models.PasswordResetToken
+
+	tenants []models.Tenant // без арендатора по умолчанию, он есть всегда
+
+	catalogVersions map[catalogKey]models.CatalogVersion
 
 	// Флаги для эмуляции ошибок (опционально)
 	NewAuthorErr error
@@ -25,14 +50,48 @@ type FakeRepo struct {
 	NewGenreErr  error
 }
 
+type catalogKey struct {
+	tenantID   int
+	collection string
+}
+
+// --- CatalogDB ---
+// Версии ведутся вместо триггеров PGRepo: каждое изменение коллекции вызывает bumpCatalog.
+
+func (f *FakeRepo) CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.catalogVersions[catalogKey{tenant.FromContext(ctx), collection}], nil
+}
+
+// bumpCatalog вызывается под f.mu.
+func (f *FakeRepo) bumpCatalog(tenantID int, collection string, now time.Time) {
+	if f.catalogVersions == nil {
+		f.catalogVersions = make(map[catalogKey]models.CatalogVersion)
+	}
+	key := catalogKey{tenantID, collection}
+	v := f.catalogVersions[key]
+	v.Version++
+	if now.After(v.UpdatedAt) {
+		v.UpdatedAt = now
+	}
+	f.catalogVersions[key] = v
+}
+
 // --- AuthorDB ---
+// Данные каталога видны только арендатору из контекста, как в PGRepo с RLS.
 
 func (f *FakeRepo) GetAllAuthors(ctx context.Context) ([]models.Author, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 	// Копируем, чтобы избежать гонок при модификации
-	authors := make([]models.Author, len(f.authors))
-	copy(authors, f.authors)
+	tenantID := tenant.FromContext(ctx)
+	authors := []models.Author{}
+	for _, author := range f.authors {
+		if author.TenantID == tenantID {
+			authors = append(authors, author)
+		}
+	}
 	return authors, nil
 }
 
@@ -45,10 +104,12 @@ func (f *FakeRepo) NewAuthor(ctx context.Context, author models.Author) (int, er
 
 	id := len(f.authors) + 1
 	newAuthor := models.Author{
-		ID:     id,
-		Author: author.Author,
+		ID:       id,
+		Author:   author.Author,
+		TenantID: tenant.FromContext(ctx),
 	}
 	f.authors = append(f.authors, newAuthor)
+	f.bumpCatalog(newAuthor.TenantID, models.CollectionAuthors, time.Now())
 	return id, nil
 }
 
@@ -57,8 +118,13 @@ func (f *FakeRepo) NewAuthor(ctx context.Context, author models.Author) (int, er
 func (f *FakeRepo) GetAllGenres(ctx context.Context) ([]models.Genre, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	genres := make([]models.Genre, len(f.genres))
-	copy(genres, f.genres)
+	tenantID := tenant.FromContext(ctx)
+	genres := []models.Genre{}
+	for _, genre := range f.genres {
+		if genre.TenantID == tenantID {
+			genres = append(genres, genre)
+		}
+	}
 	return genres, nil
 }
 
@@ -69,12 +135,22 @@ func (f *FakeRepo) NewGenre(ctx context.Context, genre models.Genre) (int, error
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	// как UNIQUE (tenant_id, genre) в PGRepo
+	tenantID := tenant.FromContext(ctx)
+	for _, existing := range f.genres {
+		if existing.TenantID == tenantID && existing.Genre == genre.Genre {
+			return 0, apperr.Conflict("genre already exists")
+		}
+	}
+
 	id := len(f.genres) + 1
 	newGenre := models.Genre{
-		ID:    id,
-		Genre: genre.Genre,
+		ID:       id,
+		Genre:    genre.Genre,
+		TenantID: tenantID,
 	}
 	f.genres = append(f.genres, newGenre)
+	f.bumpCatalog(tenantID, models.CollectionGenres, time.Now())
 	return id, nil
 }
 
@@ -83,8 +159,13 @@ func (f *FakeRepo) NewGenre(ctx context.Context, genre models.Genre) (int, error
 func (f *FakeRepo) GetBooks(ctx context.Context) ([]models.Book, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	books := make([]models.Book, len(f.books))
-	copy(books, f.books)
+	tenantID := tenant.FromContext(ctx)
+	books := []models.Book{}
+	for _, book := range f.books {
+		if book.TenantID == tenantID {
+			books = append(books, book)
+		}
+	}
 	return books, nil
 }
 
@@ -95,53 +176,114 @@ func (f *FakeRepo) NewBook(ctx context.Context, book models.Book) (int, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	id := len(f.books) + 1
+	// как внешние ключи books -> authors/genres в PGRepo
+	tenantID := tenant.FromContext(ctx)
+	if !f.hasAuthor(tenantID, book.Author_id) {
+		return 0, apperr.Field("author_id", "author does not exist")
+	}
+	if !f.hasGenre(tenantID, book.Genre_id) {
+		return 0, apperr.Field("genre_id", "genre does not exist")
+	}
+	if book.Price < 0 {
+		return 0, apperr.Field("price", "must be non-negative")
+	}
+	if f.hasISBN(tenantID, book.ISBN, 0) {
+		return 0, apperr.Conflict("a book with this ISBN already exists")
+	}
+
+	now := time.Now()
+	f.lastBookID++
+	id := f.lastBookID
 	newBook := models.Book{
 		ID:        id,
 		Name:      book.Name,
 		Author_id: book.Author_id,
 		Genre_id:  book.Genre_id,
 		Price:     book.Price,
+		ISBN:      book.ISBN,
+		TenantID:  tenantID,
+		UpdatedAt: now,
 	}
 	f.books = append(f.books, newBook)
+	f.bumpCatalog(tenantID, models.CollectionBooks, now)
 	return id, nil
 }
 
+// hasISBN — частичный уникальный индекс books_tenant_isbn_key; книгу exceptID не учитываем.
+func (f *FakeRepo) hasISBN(tenantID int, isbn string, exceptID int) bool {
+	if isbn == "" {
+		return false
+	}
+	for _, book := range f.books {
+		if book.TenantID == tenantID && book.ISBN == isbn && book.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAuthor, hasGenre и hasISBN вызываются под f.mu.
+func (f *FakeRepo) hasAuthor(tenantID, id int) bool {
+	for _, author := range f.authors {
+		if author.ID == id && author.TenantID == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FakeRepo) hasGenre(tenantID, id int) bool {
+	for _, genre := range f.genres {
+		if genre.ID == id && genre.TenantID == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *FakeRepo) GetBookByID(ctx context.Context, id int) (models.Book, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	tenantID := tenant.FromContext(ctx)
 	for _, book := range f.books {
-		if int(book.ID) == id {
+		if int(book.ID) == id && book.TenantID == tenantID {
 			return book, nil
 		}
 	}
-	return models.Book{}, errors.New("book not found")
+	return models.Book{}, apperr.NotFound("book with id %d not found", id)
 }
 
 func (f *FakeRepo) DeleteBookById(ctx context.Context, id int) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	tenantID := tenant.FromContext(ctx)
 	for i, book := range f.books {
-		if int(book.ID) == id {
+		if int(book.ID) == id && book.TenantID == tenantID {
 			f.books = append(f.books[:i], f.books[i+1:]...)
+			f.bumpCatalog(tenantID, models.CollectionBooks, time.Now())
 			return nil
 		}
 	}
-	return errors.New("book not found")
+	// как DELETE в PGRepo: удалять нечего — не ошибка
+	return nil
 }
 
 func (f *FakeRepo) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuthor, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	var result []models.BookWithAuthor
+	tenantID := tenant.FromContext(ctx)
+	result := []models.BookWithAuthor{}
 	for _, book := range f.books {
+		if book.TenantID != tenantID {
+			continue
+		}
 		// Ищем автора по Author_id
 		var authorName string
 		for _, author := range f.authors {
-			if int(author.ID) == book.Author_id {
+			if int(author.ID) == book.Author_id && author.TenantID == tenantID {
 				authorName = author.Author
 				break
 			}
@@ -150,7 +292,7 @@ func (f *FakeRepo) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuth
 			ID:         book.ID,
 			Name:       book.Name,
 			Price:      book.Price,
-			GenreID:    -1,
+			GenreID:    book.Genre_id,
 			AuthorID:   book.Author_id,
 			AuthorName: authorName,
 		})
@@ -159,24 +301,40 @@ func (f *FakeRepo) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuth
 }
 
 func (f *FakeRepo) UpdateBook(ctx context.Context, id int, update models.BookUpdate) error {
+	// как в PGRepo: цена проверяется до поиска книги
+	if update.Price != nil && *update.Price < 0 {
+		return apperr.Field("price", "must be non-negative")
+	}
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	tenantID := tenant.FromContext(ctx)
 	for i, book := range f.books {
-		if book.ID == id {
-			if update.Name != nil {
-				f.books[i].Name = *update.Name
-			}
-			if update.Price != nil {
-				if *update.Price < 0 {
-					return errors.New("price must be non-negative")
-				}
-				f.books[i].Price = *update.Price
-			}
+		if book.ID != id || book.TenantID != tenantID {
+			continue
+		}
+		if update.Name == nil && update.Price == nil && update.ISBN == nil {
 			return nil
 		}
+		if update.ISBN != nil && f.hasISBN(tenantID, *update.ISBN, id) {
+			return apperr.Conflict("a book with this ISBN already exists")
+		}
+		if update.Name != nil {
+			f.books[i].Name = *update.Name
+		}
+		if update.Price != nil {
+			f.books[i].Price = *update.Price
+		}
+		if update.ISBN != nil {
+			f.books[i].ISBN = *update.ISBN
+		}
+		now := time.Now()
+		f.books[i].UpdatedAt = now
+		f.bumpCatalog(tenantID, models.CollectionBooks, now)
+		return nil
 	}
-	return fmt.Errorf("book with id %d not found", id)
+	return apperr.NotFound("book with id %d not found", id)
 }
 
 func (f *FakeRepo) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
@@ -188,5 +346,595 @@ func (f *FakeRepo) GetUserByUsername(ctx context.Context, username string) (*mod
 			return &user, nil
 		}
 	}
-	return nil, fmt.Errorf("the user was not found")
+	return nil, apperr.NotFound("the user was not found")
+}
+
+func (f *FakeRepo) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, user := range f.users {
+		if user.ID == id {
+			return &user, nil
+		}
+	}
+	return nil, apperr.NotFound("the user was not found")
+}
+
+// AddUser кладёт пользователя в хранилище (в PGRepo пользователи приходят из миграций).
+func (f *FakeRepo) AddUser(user models.User) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastUserID++
+	user.ID = f.lastUserID
+	if user.TenantID == 0 {
+		user.TenantID = tenant.DefaultID
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	f.users = append(f.users, user)
+	return user.ID
+}
+
+func (f *FakeRepo) UpdateUserRole(ctx context.Context, id int, role string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, user := range f.users {
+		if user.ID == id {
+			f.users[i].Role = role
+			return nil
+		}
+	}
+	return apperr.NotFound("user with id %d not found", id)
+}
+
+func (f *FakeRepo) UpdateUserPassword(ctx context.Context, id int, passwordHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, user := range f.users {
+		if user.ID == id {
+			f.users[i].Password = passwordHash
+			f.users[i].TokenVersion++
+			f.expireResetTokens(id)
+			return nil
+		}
+	}
+	return apperr.NotFound("user with id %d not found", id)
+}
+
+func (f *FakeRepo) UpgradePasswordHash(ctx context.Context, id int, oldHash, newHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, user := range f.users {
+		if user.ID == id && user.Password == oldHash {
+			f.users[i].Password = newHash
+		}
+	}
+	return nil
+}
+
+func (f *FakeRepo) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	matched := []models.User{}
+	for _, user := range f.users {
+		if filter.TenantID != 0 && user.TenantID != filter.TenantID {
+			continue
+		}
+		if strings.Contains(strings.ToLower(user.Username), search) ||
+			strings.Contains(strings.ToLower(user.Email), search) {
+			matched = append(matched, user)
+		}
+	}
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return []models.User{}, total, nil
+	}
+	end := min(filter.Offset+filter.Limit, total)
+	return matched[filter.Offset:end], total, nil
+}
+
+func (f *FakeRepo) SetUserDisabled(ctx context.Context, id int, disabledAt *time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, user := range f.users {
+		if user.ID == id {
+			f.users[i].DisabledAt = disabledAt
+			return nil
+		}
+	}
+	return apperr.NotFound("user with id %d not found", id)
+}
+
+func (f *FakeRepo) DeleteUser(ctx context.Context, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, user := range f.users {
+		if user.ID == id {
+			f.users = append(f.users[:i], f.users[i+1:]...)
+			f.deleteUserData(id)
+			return nil
+		}
+	}
+	return apperr.NotFound("user with id %d not found", id)
+}
+
+func (f *FakeRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	id, ok := f.identities[[2]string{issuer, subject}]
+	if !ok {
+		return nil, apperr.NotFound("the user was not found")
+	}
+	for _, user := range f.users {
+		if user.ID == id {
+			return &user, nil
+		}
+	}
+	return nil, apperr.NotFound("the user was not found")
+}
+
+func (f *FakeRepo) NewUserWithIdentity(ctx context.Context, user models.User, issuer, subject string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.userConflict(user); err != nil {
+		return 0, err
+	}
+	// как внешний ключ в PGRepo: нулевой арендатор тоже не существует
+	if !f.hasTenant(user.TenantID) {
+		return 0, apperr.Validation("tenant does not exist")
+	}
+	if f.identities == nil {
+		f.identities = make(map[[2]string]int)
+	}
+	if _, ok := f.identities[[2]string{issuer, subject}]; ok {
+		return 0, apperr.Conflict("identity already linked")
+	}
+	f.lastUserID++
+	user.ID = f.lastUserID
+	user.CreatedAt = time.Now()
+	f.users = append(f.users, user)
+	f.identities[[2]string{issuer, subject}] = user.ID
+	return user.ID, nil
+}
+
+// userConflict — уникальность users.username и users.email в PGRepo. Вызывается под f.mu.
+func (f *FakeRepo) userConflict(user models.User) error {
+	for _, u := range f.users {
+		if u.Username == user.Username {
+			return apperr.Conflict("username already taken")
+		}
+		if user.Email != "" && u.Email == user.Email {
+			return apperr.Conflict("email already taken")
+		}
+	}
+	return nil
+}
+
+// hasUser, hasTenant и hasOAuthClient — внешние ключи PGRepo. Вызываются под f.mu.
+func (f *FakeRepo) hasUser(id int) bool {
+	return slices.ContainsFunc(f.users, func(u models.User) bool { return u.ID == id })
+}
+
+func (f *FakeRepo) hasTenant(id int) bool {
+	return slices.ContainsFunc(f.allTenants(), func(t models.Tenant) bool { return t.ID == id })
+}
+
+func (f *FakeRepo) hasOAuthClient(clientID string) bool {
+	return slices.ContainsFunc(f.oauthClients, func(c models.OAuthClient) bool { return c.ClientID == clientID })
+}
+
+// deleteUserData удаляет всё, что ссылается на пользователя с ON DELETE CASCADE
+// в PGRepo. Вызывается под f.mu.
+func (f *FakeRepo) deleteUserData(id int) {
+	for key, userID := range f.identities {
+		if userID == id {
+			delete(f.identities, key)
+		}
+	}
+	f.apiKeys = slices.DeleteFunc(f.apiKeys, func(k models.APIKey) bool { return k.UserID == id })
+	for hash, code := range f.authCodes {
+		if code.UserID == id {
+			delete(f.authCodes, hash)
+		}
+	}
+	delete(f.totp, id)
+	f.recoveryCodes = slices.DeleteFunc(f.recoveryCodes, func(c models.RecoveryCode) bool { return c.UserID == id })
+	for hash, token := range f.resetTokens {
+		if token.UserID == id {
+			delete(f.resetTokens, hash)
+		}
+	}
+}
+
+// --- APIKeyDB ---
+
+func (f *FakeRepo) NewAPIKey(ctx context.Context, key models.APIKey) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.hasUser(key.UserID) {
+		return 0, apperr.Validation("user does not exist")
+	}
+	for _, k := range f.apiKeys {
+		if k.Prefix == key.Prefix {
+			return 0, apperr.Conflict("api key prefix already exists")
+		}
+	}
+	f.lastAPIKeyID++
+	key.ID = f.lastAPIKeyID
+	key.CreatedAt = time.Now()
+	f.apiKeys = append(f.apiKeys, key)
+	return key.ID, nil
+}
+
+func (f *FakeRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, key := range f.apiKeys {
+		if key.Prefix == prefix {
+			return &key, nil
+		}
+	}
+	return nil, apperr.NotFound("api key not found")
+}
+
+func (f *FakeRepo) ListAPIKeys(ctx context.Context, tenantID int) ([]models.APIKey, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	keys := []models.APIKey{}
+	for _, key := range f.apiKeys {
+		if !f.userInTenant(key.UserID, tenantID) {
+			continue
+		}
+		// как в PGRepo: список не читает хэш секрета
+		key.Hash = ""
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (f *FakeRepo) RevokeAPIKey(ctx context.Context, tenantID, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, key := range f.apiKeys {
+		if key.ID == id && key.RevokedAt == nil && f.userInTenant(key.UserID, tenantID) {
+			now := time.Now()
+			f.apiKeys[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return apperr.NotFound("api key with id %d not found", id)
+}
+
+// userInTenant вызывается под f.mu.
+func (f *FakeRepo) userInTenant(userID, tenantID int) bool {
+	for _, user := range f.users {
+		if user.ID == userID {
+			return user.TenantID == tenantID
+		}
+	}
+	return false
+}
+
+func (f *FakeRepo) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, key := range f.apiKeys {
+		if key.ID == id {
+			f.apiKeys[i].LastUsedAt = &usedAt
+			return nil
+		}
+	}
+	// отметка использования — не повод отказывать в запросе, как и в PGRepo
+	return nil
+}
+
+// --- OAuthDB ---
+
+func (f *FakeRepo) NewOAuthClient(ctx context.Context, client models.OAuthClient) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range f.oauthClients {
+		if c.ClientID == client.ClientID {
+			return 0, apperr.Conflict("oauth client already exists")
+		}
+	}
+	client.ID = len(f.oauthClients) + 1
+	client.CreatedAt = time.Now()
+	f.oauthClients = append(f.oauthClients, client)
+	return client.ID, nil
+}
+
+func (f *FakeRepo) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, c := range f.oauthClients {
+		if c.ClientID == clientID {
+			return &c, nil
+		}
+	}
+	return nil, apperr.NotFound("oauth client not found")
+}
+
+func (f *FakeRepo) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	clients := make([]models.OAuthClient, len(f.oauthClients))
+	copy(clients, f.oauthClients)
+	return clients, nil
+}
+
+func (f *FakeRepo) NewAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.hasUser(code.UserID) || !f.hasOAuthClient(code.ClientID) {
+		return apperr.Validation("user or oauth client does not exist")
+	}
+	if _, ok := f.authCodes[code.CodeHash]; ok {
+		return apperr.Conflict("authorization code already exists")
+	}
+	if f.authCodes == nil {
+		f.authCodes = make(map[string]models.AuthorizationCode)
+	}
+	f.authCodes[code.CodeHash] = code
+	return nil
+}
+
+func (f *FakeRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.AuthorizationCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code, ok := f.authCodes[codeHash]
+	if !ok || code.UsedAt != nil {
+		return nil, apperr.NotFound("authorization code not found")
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	f.authCodes[codeHash] = code
+	return &code, nil
+}
+
+// --- MFADB ---
+
+func (f *FakeRepo) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	totp, ok := f.totp[userID]
+	if !ok {
+		return nil, apperr.NotFound("totp not found")
+	}
+	return &totp, nil
+}
+
+func (f *FakeRepo) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.hasUser(userID) {
+		return apperr.Validation("user does not exist")
+	}
+	if f.totp == nil {
+		f.totp = make(map[int]models.TOTP)
+	}
+	f.totp[userID] = models.TOTP{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (f *FakeRepo) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	totp, ok := f.totp[userID]
+	if !ok {
+		return apperr.NotFound("totp for user %d not found", userID)
+	}
+	totp.Enabled = true
+	f.totp[userID] = totp
+
+	kept := f.recoveryCodes[:0]
+	for _, code := range f.recoveryCodes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	f.recoveryCodes = kept
+	for _, hash := range recoveryCodeHashes {
+		f.lastRecoveryID++
+		f.recoveryCodes = append(f.recoveryCodes, models.RecoveryCode{
+			ID:       f.lastRecoveryID,
+			UserID:   userID,
+			CodeHash: hash,
+		})
+	}
+	return nil
+}
+
+func (f *FakeRepo) DisableTOTP(ctx context.Context, userID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.totp, userID)
+	kept := f.recoveryCodes[:0]
+	for _, code := range f.recoveryCodes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	f.recoveryCodes = kept
+	return nil
+}
+
+func (f *FakeRepo) AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	totp, ok := f.totp[userID]
+	if !ok || totp.LastStep >= step {
+		return false, nil
+	}
+	totp.LastStep = step
+	f.totp[userID] = totp
+	return true, nil
+}
+
+func (f *FakeRepo) ListRecoveryCodes(ctx context.Context, userID int) ([]models.RecoveryCode, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	codes := []models.RecoveryCode{}
+	for _, code := range f.recoveryCodes {
+		if code.UserID == userID && !f.usedRecovery[code.ID] {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func (f *FakeRepo) UseRecoveryCode(ctx context.Context, id int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.usedRecovery == nil {
+		f.usedRecovery = make(map[int]bool)
+	}
+	for _, code := range f.recoveryCodes {
+		if code.ID == id && !f.usedRecovery[id] {
+			f.usedRecovery[id] = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *FakeRepo) GetMFARequiredRoles(ctx context.Context) ([]string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	roles := make([]string, len(f.mfaRequiredRoles))
+	copy(roles, f.mfaRequiredRoles)
+	slices.Sort(roles) // ORDER BY role в PGRepo
+	return roles, nil
+}
+
+func (f *FakeRepo) SetMFARequiredRoles(ctx context.Context, roles []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mfaRequiredRoles = append([]string(nil), roles...)
+	return nil
+}
+
+// --- PasswordResetDB ---
+
+func (f *FakeRepo) NewPasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.hasUser(token.UserID) {
+		return apperr.Validation("user does not exist")
+	}
+	if f.resetTokens == nil {
+		f.resetTokens = make(map[string]models.PasswordResetToken)
+	}
+	f.expireResetTokens(token.UserID)
+	token.CreatedAt = time.Now()
+	f.resetTokens[token.TokenHash] = token
+	return nil
+}
+
+func (f *FakeRepo) ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token, ok := f.resetTokens[tokenHash]
+	if !ok || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return nil, apperr.NotFound("password reset token not found")
+	}
+	token.UsedAt = &now
+	f.resetTokens[tokenHash] = token
+	return &token, nil
+}
+
+// expireResetTokens гасит неиспользованные токены пользователя. Вызывать под f.mu.
+func (f *FakeRepo) expireResetTokens(userID int) {
+	now := time.Now()
+	for hash, token := range f.resetTokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &now
+			f.resetTokens[hash] = token
+		}
+	}
+}
+
+// --- TenantDB ---
+
+func (f *FakeRepo) NewTenant(ctx context.Context, t models.Tenant, admin *models.User) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, existing := range f.allTenants() {
+		if existing.Slug == t.Slug {
+			return 0, apperr.Conflict("slug already taken")
+		}
+	}
+	if admin != nil {
+		if err := f.userConflict(*admin); err != nil {
+			return 0, err
+		}
+	}
+
+	t.ID = tenant.DefaultID + len(f.tenants) + 1
+	t.CreatedAt = time.Now()
+	f.tenants = append(f.tenants, t)
+
+	if admin != nil {
+		f.lastUserID++
+		user := *admin
+		user.ID = f.lastUserID
+		user.TenantID = t.ID
+		user.CreatedAt = time.Now()
+		f.users = append(f.users, user)
+	}
+	return t.ID, nil
+}
+
+func (f *FakeRepo) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.allTenants(), nil
+}
+
+func (f *FakeRepo) GetTenantBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, t := range f.allTenants() {
+		if t.Slug == slug {
+			return &t, nil
+		}
+	}
+	return nil, apperr.NotFound("tenant %q not found", slug)
+}
+
+// allTenants вызывается под f.mu.
+func (f *FakeRepo) allTenants() []models.Tenant {
+	return append([]models.Tenant{{ID: tenant.DefaultID, Slug: "default", Name: "Default library"}}, f.tenants...)
 }
@@ -0,0 +1,14 @@
+package fake
+
+import (
+	"testing"
+
+	"leti/pkg/repository"
+	"leti/pkg/repository/repositorytest"
+)
+
+func TestFakeRepo_Contract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.DataBase {
+		return &FakeRepo{}
+	})
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"time"
+)
+
+func (repo *PGRepo) NewAPIKey(ctx context.Context, key models.APIKey) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.pool.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id;
+	`,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		key.Scopes,
+		key.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
+
+func (repo *PGRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var key models.APIKey
+	err := repo.pool.QueryRow(ctx, `
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE prefix = $1;
+	`, prefix).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+func (repo *PGRepo) ListAPIKeys(ctx context.Context, tenantID int) ([]models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.pool.Query(ctx, `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.revoked_at, k.created_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE u.tenant_id = $1
+		ORDER BY k.id;
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			&key.Scopes,
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (repo *PGRepo) RevokeAPIKey(ctx context.Context, tenantID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.pool.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL
+			AND user_id IN (SELECT id FROM users WHERE tenant_id = $2);
+	`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound("api key with id %d not found", id)
+	}
+	return nil
+}
+
+func (repo *PGRepo) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `
+		UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1;
+	`, id, usedAt)
+	return err
+}
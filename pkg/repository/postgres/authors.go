@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"leti/pkg/models"
+
+	"github.com/jackc/pgx/v4"
 )
 
 func (repo *PGRepo) GetAllAuthors(ctx context.Context) ([]models.Author, error) {
@@ -10,26 +12,35 @@ func (repo *PGRepo) GetAllAuthors(ctx context.Context) ([]models.Author, error)
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel() // ВСЕГДА вызываем cancel в defer!
 
-	rows, err := repo.pool.Query(ctx, `
-        SELECT id, author
-        FROM authors
-    `)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	authors := []models.Author{}
-	for rows.Next() {
-		var author models.Author
-		err = rows.Scan(
-			&author.ID,
-			&author.Author,
-		)
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, author, tenant_id
+			FROM authors
+			WHERE tenant_id = $1
+			ORDER BY id
+		`, tenantID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		authors = append(authors, author)
+		defer rows.Close()
+
+		for rows.Next() {
+			var author models.Author
+			err = rows.Scan(
+				&author.ID,
+				&author.Author,
+				&author.TenantID,
+			)
+			if err != nil {
+				return err
+			}
+			authors = append(authors, author)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Проверяем контекст после цикла
@@ -44,11 +55,13 @@ func (repo *PGRepo) NewAuthor(ctx context.Context, author models.Author) (int, e
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
 	var id int
-	err := repo.pool.QueryRow(ctx, `
-		INSERT INTO authors (author)
-		VALUES ($1)
-		RETURNING id; 
-	`, author.Author).Scan(&id)
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		return tx.QueryRow(ctx, `
+			INSERT INTO authors (author, tenant_id)
+			VALUES ($1, $2)
+			RETURNING id;
+		`, author.Author, tenantID).Scan(&id)
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -2,9 +2,14 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
+	"leti/pkg/tenant"
 	"strings"
+
+	"github.com/jackc/pgx/v4"
 )
 
 // метод, который возвращает нам все книжки.
@@ -15,24 +20,31 @@ func (repo *PGRepo) GetBooks(ctx context.Context) ([]models.Book, error) {
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
 
-	rows, err := repo.pool.Query(ctx, `
-        SELECT id, name, author_id, genre_id, price
-        FROM books
-        WHERE author_id IS NOT NULL AND genre_id IS NOT NULL;
-    `)
+	data := []models.Book{}
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, name, author_id, genre_id, price, COALESCE(isbn, ''), tenant_id, updated_at
+			FROM books
+			WHERE author_id IS NOT NULL AND genre_id IS NOT NULL AND tenant_id = $1
+			ORDER BY id;
+		`, tenantID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var item models.Book
+			if err := rows.Scan(&item.ID, &item.Name, &item.Author_id, &item.Genre_id, &item.Price, &item.ISBN, &item.TenantID, &item.UpdatedAt); err != nil {
+				return err
+			}
+			data = append(data, item)
+		}
+		return rows.Err()
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var data []models.Book
-	for rows.Next() {
-		var item models.Book
-		if err := rows.Scan(&item.ID, &item.Name, &item.Author_id, &item.Genre_id, &item.Price); err != nil {
-			return nil, err
-		}
-		data = append(data, item)
-	}
 
 	return data, nil
 }
@@ -43,16 +55,20 @@ func (repo *PGRepo) NewBook(ctx context.Context, item models.Book) (int, error)
 
 	var id int
 	// возвращает id сразу
-	err := repo.pool.QueryRow(ctx, `
-		INSERT INTO books (name, author_id, genre_id, price)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id; 
-	`,
-		item.Name,
-		item.Author_id,
-		item.Genre_id,
-		item.Price,
-	).Scan(&id)
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		return tx.QueryRow(ctx, `
+			INSERT INTO books (name, author_id, genre_id, price, isbn, tenant_id)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+			RETURNING id;
+		`,
+			item.Name,
+			item.Author_id,
+			item.Genre_id,
+			item.Price,
+			item.ISBN,
+			tenantID,
+		).Scan(&id)
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -64,19 +80,26 @@ func (repo *PGRepo) GetBookByID(ctx context.Context, id int) (models.Book, error
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
 	var book models.Book
-	err := repo.pool.QueryRow(ctx, `
-		SELECT id, name, author_id, genre_id, price
-		FROM books
-		WHERE author_id IS NOT NULL AND genre_id IS NOT NULL AND id=$1;
-
-	`, id).Scan(
-		&book.ID,
-		&book.Name,
-		&book.Author_id,
-		&book.Genre_id,
-		&book.Price,
-	)
-
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		return tx.QueryRow(ctx, `
+			SELECT id, name, author_id, genre_id, price, COALESCE(isbn, ''), tenant_id, updated_at
+			FROM books
+			WHERE author_id IS NOT NULL AND genre_id IS NOT NULL AND id = $1 AND tenant_id = $2;
+		`, id, tenantID).Scan(
+			&book.ID,
+			&book.Name,
+			&book.Author_id,
+			&book.Genre_id,
+			&book.Price,
+			&book.ISBN,
+			&book.TenantID,
+			&book.UpdatedAt,
+		)
+	})
+
+	if errors.Is(err, apperr.ErrNotFound) {
+		return models.Book{}, apperr.NotFound("book with id %d not found", id)
+	}
 	if err != nil {
 		return models.Book{}, err
 	}
@@ -86,37 +109,44 @@ func (repo *PGRepo) GetBookByID(ctx context.Context, id int) (models.Book, error
 func (repo *PGRepo) DeleteBookById(ctx context.Context, id int) error {
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
-	_, err := repo.pool.Exec(ctx, `
-		DELETE FROM books
-		WHERE id=$1;
-	`, id)
-	if err != nil {
+	return repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM books
+			WHERE id = $1 AND tenant_id = $2;
+		`, id, tenantID)
 		return err
-	}
-	return nil
+	})
 }
 
 func (repo *PGRepo) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuthor, error) {
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
-	rows, err := repo.pool.Query(ctx, `
-        SELECT b.id, b.name, b.price, b.genre_id, b.author_id, a.author
-        FROM books b
-        JOIN authors a ON b.author_id = a.id
-    `)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var books []models.BookWithAuthor
-	for rows.Next() {
-		var b models.BookWithAuthor
-		err := rows.Scan(&b.ID, &b.Name, &b.Price, &b.GenreID, &b.AuthorID, &b.AuthorName)
+	books := []models.BookWithAuthor{}
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		rows, err := tx.Query(ctx, `
+			SELECT b.id, b.name, b.price, b.genre_id, b.author_id, a.author
+			FROM books b
+			JOIN authors a ON b.tenant_id = a.tenant_id AND b.author_id = a.id
+			WHERE b.tenant_id = $1
+			ORDER BY b.id
+		`, tenantID)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var b models.BookWithAuthor
+			err := rows.Scan(&b.ID, &b.Name, &b.Price, &b.GenreID, &b.AuthorID, &b.AuthorName)
+			if err != nil {
+				return err
+			}
+			books = append(books, b)
 		}
-		books = append(books, b)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 	return books, nil
 }
@@ -127,9 +157,9 @@ func (repo *PGRepo) UpdateBook(ctx context.Context, id int, update models.BookUp
 
 	var setParts []string
 	var args []interface{}
-	args = append(args, id) //$1 = book ID
+	args = append(args, id, tenant.FromContext(ctx)) //$1 = book ID, $2 = арендатор
 
-	argIndex := 2 //следующие параметры: $2, $3...
+	argIndex := 3 //следующие параметры: $3, $4...
 
 	if update.Name != nil {
 		setParts = append(setParts, fmt.Sprintf("name = $%d", argIndex))
@@ -139,29 +169,38 @@ func (repo *PGRepo) UpdateBook(ctx context.Context, id int, update models.BookUp
 
 	if update.Price != nil {
 		if *update.Price < 0 {
-			return fmt.Errorf("price must be non-negative")
+			return apperr.Field("price", "must be non-negative")
 		}
 		setParts = append(setParts, fmt.Sprintf("price = $%d", argIndex))
 		args = append(args, *update.Price)
+		argIndex++
+	}
+
+	if update.ISBN != nil {
+		setParts = append(setParts, fmt.Sprintf("isbn = NULLIF($%d, '')", argIndex))
+		args = append(args, *update.ISBN)
 	}
 
+	// менять нечего, но о несуществующей книге всё равно сообщаем
 	if len(setParts) == 0 {
-		return nil
+		_, err := repo.GetBookByID(ctx, id)
+		return err
 	}
 
 	query := fmt.Sprintf(
-		"UPDATE books SET %s WHERE id = $1",
+		"UPDATE books SET %s WHERE id = $1 AND tenant_id = $2",
 		strings.Join(setParts, ", "),
 	)
 
-	result, err := repo.pool.Exec(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("book with id %d not found", id)
-	}
+	return repo.inTenant(ctx, func(tx pgx.Tx, _ int) error {
+		result, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		if result.RowsAffected() == 0 {
+			return apperr.NotFound("book with id %d not found", id)
+		}
+		return nil
+	})
 }
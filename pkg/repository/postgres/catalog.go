@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// CatalogVersion читает счётчик, который ведут триггеры миграции 000017:
+// одна строка по первичному ключу вместо max(updated_at) по всей таблице.
+func (repo *PGRepo) CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var v models.CatalogVersion
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		return tx.QueryRow(ctx, `
+			SELECT version, updated_at
+			FROM catalog_versions
+			WHERE tenant_id = $1 AND collection = $2;
+		`, tenantID, collection).Scan(&v.Version, &v.UpdatedAt)
+	})
+	// коллекцию этой библиотеки ещё не меняли
+	if errors.Is(err, apperr.ErrNotFound) {
+		return models.CatalogVersion{}, nil
+	}
+	if err != nil {
+		return models.CatalogVersion{}, err
+	}
+	return v, nil
+}
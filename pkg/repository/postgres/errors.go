@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"errors"
+	"leti/pkg/apperr"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Коды SQLSTATE, которые означают ошибку в данных клиента, а не сбой БД.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// constraintFields связывает ограничения схемы с полями запроса, чтобы клиент
+// узнал, что именно не так, а не текст ошибки Postgres.
+var constraintFields = map[string]apperr.FieldError{
+	"books_author_fkey":       {Field: "author_id", Message: "author does not exist"},
+	"books_genre_fkey":        {Field: "genre_id", Message: "genre does not exist"},
+	"books_price_check":       {Field: "price", Message: "must be non-negative"},
+	"books_tenant_isbn_key":   {Field: "isbn", Message: "a book with this ISBN already exists"},
+	"genres_tenant_genre_key": {Field: "genre", Message: "genre already exists"},
+	"users_username_key":      {Field: "username", Message: "username already taken"},
+	"users_email_key":         {Field: "email", Message: "email already taken"},
+	"tenants_slug_key":        {Field: "slug", Message: "slug already taken"},
+}
+
+// translateError превращает ошибки pgx в ошибки apperr. Исходная ошибка
+// сохраняется в цепочке для логов.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperr.Wrap(apperr.ErrNotFound, err, "record not found")
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	field, known := constraintFields[pgErr.ConstraintName]
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		msg := "record already exists"
+		if known {
+			msg = field.Message
+		}
+		return &apperr.Error{Kind: apperr.ErrConflict, Msg: msg, Err: err}
+	case pgForeignKeyViolation, pgCheckViolation:
+		if !known {
+			return apperr.Wrap(apperr.ErrValidation, err, "invalid reference or value")
+		}
+		return &apperr.Error{
+			Kind:   apperr.ErrValidation,
+			Msg:    field.Field + ": " + field.Message,
+			Fields: []apperr.FieldError{field},
+			Err:    err,
+		}
+	}
+	return err
+}
@@ -3,30 +3,41 @@ package postgres
 import (
 	"context"
 	"leti/pkg/models"
+
+	"github.com/jackc/pgx/v4"
 )
 
 func (repo *PGRepo) GetAllGenres(ctx context.Context) ([]models.Genre, error) {
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
-	rows, err := repo.pool.Query(ctx, `
-		SELECT id, genre
-		FROM genres
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
 	genres := []models.Genre{}
-	for rows.Next() {
-		var genre models.Genre
-		err = rows.Scan(
-			&genre.ID,
-			&genre.Genre,
-		)
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, genre, tenant_id
+			FROM genres
+			WHERE tenant_id = $1
+			ORDER BY id
+		`, tenantID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		genres = append(genres, genre)
+		defer rows.Close()
+		for rows.Next() {
+			var genre models.Genre
+			err = rows.Scan(
+				&genre.ID,
+				&genre.Genre,
+				&genre.TenantID,
+			)
+			if err != nil {
+				return err
+			}
+			genres = append(genres, genre)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 	return genres, nil
 
@@ -36,11 +47,13 @@ func (repo *PGRepo) NewGenre(ctx context.Context, newGenre models.Genre) (int, e
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
 	var id int
-	err := repo.pool.QueryRow(ctx, `
-		INSERT INTO genres (genre)
-		VALUES ($1)
-		RETURNING id;
-	`, newGenre.Genre).Scan(&id)
+	err := repo.inTenant(ctx, func(tx pgx.Tx, tenantID int) error {
+		return tx.QueryRow(ctx, `
+			INSERT INTO genres (genre, tenant_id)
+			VALUES ($1, $2)
+			RETURNING id;
+		`, newGenre.Genre, tenantID).Scan(&id)
+	})
 	if err != nil {
 		return 0, err
 	}
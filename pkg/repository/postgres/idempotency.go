@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"leti/pkg/idempotency"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// PGRepo реализует idempotency.Store: повтор запроса найдёт ответ, на какую бы реплику ни попал.
+var _ idempotency.Store = (*PGRepo)(nil)
+
+// reserveAttempts — сколько раз ReserveIdempotencyKey повторяет пару
+// upsert/select, если чужая запись исчезла между ними.
+const reserveAttempts = 3
+
+// ReserveIdempotencyKey занимает ключ upsert'ом, который перезаписывает только
+// истёкшую запись. Если ключ занят, upsert ничего не меняет, и запись читается отдельно.
+func (repo *PGRepo) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, lockedUntil, now time.Time) (idempotency.Record, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	for range reserveAttempts {
+		tag, err := repo.pool.Exec(ctx, `
+			INSERT INTO idempotency_keys AS ik (key, fingerprint, expires_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key) DO UPDATE SET
+				fingerprint = EXCLUDED.fingerprint,
+				status = NULL,
+				content_type = NULL,
+				body = NULL,
+				expires_at = EXCLUDED.expires_at
+			WHERE ik.expires_at <= $4;
+		`, key, fingerprint, lockedUntil, now)
+		if err != nil {
+			return idempotency.Record{}, false, err
+		}
+		if tag.RowsAffected() == 1 {
+			return idempotency.Record{Fingerprint: fingerprint}, true, nil
+		}
+
+		var (
+			rec         idempotency.Record
+			status      *int
+			contentType *string
+			body        []byte
+		)
+		err = repo.pool.QueryRow(ctx, `
+			SELECT fingerprint, status, content_type, body
+			FROM idempotency_keys
+			WHERE key = $1 AND expires_at > $2;
+		`, key, now).Scan(&rec.Fingerprint, &status, &contentType, &body)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return idempotency.Record{}, false, err
+		}
+		if status != nil {
+			rec.Response = &idempotency.Response{Status: *status, Body: body}
+			if contentType != nil {
+				rec.Response.ContentType = *contentType
+			}
+		}
+		return rec, false, nil
+	}
+	return idempotency.Record{}, false, fmt.Errorf("idempotency key %q keeps changing", key)
+}
+
+func (repo *PGRepo) SaveIdempotentResponse(ctx context.Context, key string, resp idempotency.Response, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status = $2, content_type = $3, body = $4, expires_at = $5
+		WHERE key = $1;
+	`, key, resp.Status, resp.ContentType, resp.Body, expiresAt)
+	return err
+}
+
+func (repo *PGRepo) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status IS NULL;`, key)
+	return err
+}
+
+// PurgeIdempotencyKeys удаляет записи, истёкшие к before.
+func (repo *PGRepo) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tag, err := repo.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/lockout"
+	"leti/pkg/models"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// PGRepo реализует lockout.Store: счётчики общие для всех экземпляров сервиса.
+var _ lockout.Store = (*PGRepo)(nil)
+
+func (repo *PGRepo) GetLoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	state := models.LoginAttempts{Key: key}
+	var lockedUntil *time.Time
+	err := repo.pool.QueryRow(ctx, `
+		SELECT failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1;
+	`, key).Scan(&state.Failures, &state.LastFailureAt, &lockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if lockedUntil != nil {
+		state.LockedUntil = *lockedUntil
+	}
+	return state, nil
+}
+
+func (repo *PGRepo) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var failures int
+	err := repo.pool.QueryRow(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1
+			                ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures;
+	`, key, now, since).Scan(&failures)
+	return failures, err
+}
+
+func (repo *PGRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `
+		UPDATE login_attempts SET locked_until = $2
+		WHERE key = $1;
+	`, key, until)
+	return err
+}
+
+func (repo *PGRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `DELETE FROM login_attempts WHERE key = $1;`, key)
+	return err
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// pgxLogger пишет журнал pgx в slog. Контекст запроса передаётся дальше,
+// поэтому записи получают request_id и прочие атрибуты из logging.WithAttrs.
+type pgxLogger struct {
+	logger *slog.Logger
+}
+
+func (l pgxLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		// параметры запроса содержат хэши паролей и токенов — в лог их не пишем
+		if k == "args" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, data[k]))
+	}
+	l.logger.LogAttrs(ctx, slogLevel(level), "pgx: "+msg, attrs...)
+}
+
+// slogLevel опускает каждый выполненный запрос (pgx Info) до Debug, чтобы он
+// не засорял журнал в обычном режиме.
+func slogLevel(level pgx.LogLevel) slog.Level {
+	switch level {
+	case pgx.LogLevelError:
+		return slog.LevelError
+	case pgx.LogLevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelDebug
+	}
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"leti/pkg/logging"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgxLogger_CarriesRequestIDAndDropsArgs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(logging.NewContextHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	ctx := logging.WithAttrs(context.Background(), slog.String("request_id", "req-1"))
+
+	pgxLogger{logger: logger}.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql":  "SELECT password FROM users WHERE username = $1",
+		"args": []interface{}{"$argon2id$secret-hash"},
+	})
+	require.Contains(t, buf.String(), "level=DEBUG")
+	require.Contains(t, buf.String(), "request_id=req-1")
+	require.Contains(t, buf.String(), "SELECT password FROM users")
+	require.NotContains(t, buf.String(), "secret-hash")
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+)
+
+func (repo *PGRepo) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var totp models.TOTP
+	err := repo.pool.QueryRow(ctx, `
+		SELECT user_id, secret, enabled, last_step
+		FROM user_totp
+		WHERE user_id = $1;
+	`, userID).Scan(&totp.UserID, &totp.Secret, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &totp, nil
+}
+
+func (repo *PGRepo) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `
+		INSERT INTO user_totp (user_id, secret, enabled, last_step)
+		VALUES ($1, $2, false, 0)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled = false, last_step = 0;
+	`, userID, secret)
+	return translateError(err)
+}
+
+func (repo *PGRepo) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE user_totp SET enabled = true
+		WHERE user_id = $1;
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound("totp for user %d not found", userID)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2);
+		`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *PGRepo) DisableTOTP(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *PGRepo) AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.pool.Exec(ctx, `
+		UPDATE user_totp SET last_step = $2
+		WHERE user_id = $1 AND last_step < $2;
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (repo *PGRepo) ListRecoveryCodes(ctx context.Context, userID int) ([]models.RecoveryCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.pool.Query(ctx, `
+		SELECT id, user_id, code_hash
+		FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+		ORDER BY id;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []models.RecoveryCode{}
+	for rows.Next() {
+		var code models.RecoveryCode
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (repo *PGRepo) UseRecoveryCode(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.pool.Exec(ctx, `
+		UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND used_at IS NULL;
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (repo *PGRepo) GetMFARequiredRoles(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.pool.Query(ctx, `SELECT role FROM mfa_required_roles ORDER BY role;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (repo *PGRepo) SetMFARequiredRoles(ctx context.Context, roles []string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_required_roles;`); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if _, err := tx.Exec(ctx, `INSERT INTO mfa_required_roles (role) VALUES ($1);`, role); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"leti/pkg/repository/cache"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var _ cache.PubSub = (*PGRepo)(nil)
+
+// Notify отправляет payload всем, кто слушает channel (NOTIFY).
+func (repo *PGRepo) Notify(ctx context.Context, channel, payload string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `SELECT pg_notify($1, $2);`, channel, payload)
+	return translateError(err)
+}
+
+// Listen занимает соединение пула под LISTEN channel, пока не отменят ctx или
+// соединение не оборвётся.
+func (repo *PGRepo) Listen(ctx context.Context, channel string, subscribed func(), handle func(payload string)) error {
+	conn, err := repo.pool.Acquire(ctx)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		// соединение вернётся в пул, и подписка не должна остаться на нём
+		unlistenCtx, cancel := context.WithTimeout(context.Background(), repo.dbTimeout)
+		defer cancel()
+		if _, err := conn.Exec(unlistenCtx, `UNLISTEN *;`); err != nil {
+			conn.Conn().Close(unlistenCtx)
+		}
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, `LISTEN `+pgx.Identifier{channel}.Sanitize()+`;`); err != nil {
+		return translateError(err)
+	}
+	subscribed()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(n.Payload)
+	}
+}
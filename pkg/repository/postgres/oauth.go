@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (repo *PGRepo) NewOAuthClient(ctx context.Context, client models.OAuthClient) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.pool.QueryRow(ctx, `
+		INSERT INTO oauth_clients (client_id, secret_hash, name, redirect_uris, scopes, grant_types)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id;
+	`,
+		client.ClientID,
+		client.SecretHash,
+		client.Name,
+		client.RedirectURIs,
+		client.Scopes,
+		client.GrantTypes,
+	).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
+
+func (repo *PGRepo) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var client models.OAuthClient
+	err := repo.pool.QueryRow(ctx, `
+		SELECT id, client_id, secret_hash, name, redirect_uris, scopes, grant_types, created_at
+		FROM oauth_clients
+		WHERE client_id = $1;
+	`, clientID).Scan(
+		&client.ID,
+		&client.ClientID,
+		&client.SecretHash,
+		&client.Name,
+		&client.RedirectURIs,
+		&client.Scopes,
+		&client.GrantTypes,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &client, nil
+}
+
+func (repo *PGRepo) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.pool.Query(ctx, `
+		SELECT id, client_id, secret_hash, name, redirect_uris, scopes, grant_types, created_at
+		FROM oauth_clients
+		ORDER BY id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		var client models.OAuthClient
+		err := rows.Scan(
+			&client.ID,
+			&client.ClientID,
+			&client.SecretHash,
+			&client.Name,
+			&client.RedirectURIs,
+			&client.Scopes,
+			&client.GrantTypes,
+			&client.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+func (repo *PGRepo) NewAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `
+		INSERT INTO oauth_authorization_codes
+			(code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, code_challenge_method, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+	`,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.Scopes,
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		code.ExpiresAt,
+	)
+	return translateError(err)
+}
+
+func (repo *PGRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.AuthorizationCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	// UPDATE ... WHERE used_at IS NULL гарантирует одноразовость даже при гонке
+	var code models.AuthorizationCode
+	err := repo.pool.QueryRow(ctx, `
+		UPDATE oauth_authorization_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE code_hash = $1 AND used_at IS NULL
+		RETURNING code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, code_challenge_method, expires_at, used_at;
+	`, codeHash).Scan(
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scopes,
+		&code.CodeChallenge,
+		&code.CodeChallengeMethod,
+		&code.ExpiresAt,
+		&code.UsedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperr.NotFound("authorization code not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (repo *PGRepo) NewPasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Действует только последняя присланная ссылка
+	_, err = tx.Exec(ctx, `
+		UPDATE password_reset_tokens SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL;
+	`, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3);
+	`, token.TokenHash, token.UserID, token.ExpiresAt)
+	if err != nil {
+		return translateError(err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *PGRepo) ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var token models.PasswordResetToken
+	err := repo.pool.QueryRow(ctx, `
+		UPDATE password_reset_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING token_hash, user_id, expires_at, used_at, created_at;
+	`, tokenHash, now).Scan(&token.TokenHash, &token.UserID, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperr.NotFound("password reset token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -2,7 +2,7 @@ package postgres
 
 import (
 	"context"
-
+	"log/slog"
 	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -13,14 +13,76 @@ type PGRepo struct {
 	dbTimeout time.Duration
 }
 
-func New(connStr string) (*PGRepo, error) {
-	pool, err := pgxpool.Connect(context.Background(), connStr)
+// defaultQueryTimeout — предел одного запроса репозитория по умолчанию.
+const defaultQueryTimeout = 3 * time.Second
+
+type options struct {
+	pool         *pgxpool.Config
+	queryTimeout time.Duration
+}
+
+// Option настраивает пул соединений и репозиторий до подключения.
+type Option func(*options)
+
+// WithLogger направляет журнал pgx (запросы, ошибки соединений) в logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		addLogger(o.pool, pgxLogger{logger: logger})
+	}
+}
+
+// WithQueryTimeout ограничивает время каждого запроса репозитория.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(o *options) { o.queryTimeout = d }
+}
+
+// PoolConfig — размер пула и время жизни соединений; нулевые поля оставляют
+// значения pgx (или параметры pool_* из строки подключения).
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+// WithPool задаёт размер пула и время жизни соединений.
+func WithPool(p PoolConfig) Option {
+	return func(o *options) {
+		if p.MaxConns > 0 {
+			o.pool.MaxConns = p.MaxConns
+		}
+		if p.MinConns > 0 {
+			o.pool.MinConns = p.MinConns
+		}
+		if p.MaxConnLifetime > 0 {
+			o.pool.MaxConnLifetime = p.MaxConnLifetime
+		}
+		if p.MaxConnIdleTime > 0 {
+			o.pool.MaxConnIdleTime = p.MaxConnIdleTime
+		}
+		if p.HealthCheckPeriod > 0 {
+			o.pool.HealthCheckPeriod = p.HealthCheckPeriod
+		}
+	}
+}
+
+func New(connStr string, opts ...Option) (*PGRepo, error) {
+	cfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	o := &options{pool: cfg, queryTimeout: defaultQueryTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	pool, err := pgxpool.ConnectConfig(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
 	return &PGRepo{
 		pool:      pool,
-		dbTimeout: 3 * time.Second}, nil
+		dbTimeout: o.queryTimeout}, nil
 }
 
 func (r *PGRepo) TruncateAll(ctx context.Context) error {
@@ -30,6 +92,11 @@ func (r *PGRepo) TruncateAll(ctx context.Context) error {
 	return err
 }
 
+// Stat — статистика пула соединений (для метрик).
+func (r *PGRepo) Stat() *pgxpool.Stat {
+	return r.pool.Stat()
+}
+
 func (r *PGRepo) Close() {
 	r.pool.Close()
 }
@@ -4,15 +4,23 @@ package postgres
 
 import (
 	"context"
+	"leti/pkg/apperr"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"testing"
+	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
+	"leti/pkg/idempotency"
 	"leti/pkg/models"
+	"leti/pkg/ratelimit"
+	"leti/pkg/repository"
+	"leti/pkg/repository/repositorytest"
+	"leti/pkg/tenant"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/stretchr/testify/require"
@@ -92,6 +100,7 @@ func TestPGRepo_NewGenre_Duplicate(t *testing.T) {
 	_, err = repo.NewGenre(context.Background(), models.Genre{Genre: "Роман"})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "unique") // или "duplicate key"
+	require.ErrorIs(t, err, apperr.ErrConflict)
 }
 
 // могут быть авторы с одной фамилией!
@@ -175,4 +184,274 @@ func TestPGRepo_Book_NegativePrice(t *testing.T) {
 	})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "check constraint")
+	require.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+func TestPGRepo_Book_UnknownAuthor(t *testing.T) {
+	repo := setupTestDB(t)
+	genreID, err := repo.NewGenre(context.Background(), models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+
+	_, err = repo.NewBook(context.Background(), models.Book{
+		Name: "Book", Author_id: 42, Genre_id: genreID, Price: 100,
+	})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.Equal(t, []apperr.FieldError{{Field: "author_id", Message: "author does not exist"}}, apperr.Fields(err))
+}
+
+func TestPGRepo_TenantIsolation(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	lib2, err := repo.NewTenant(ctx, models.Tenant{Slug: "lib2", Name: "Вторая библиотека"}, nil)
+	require.NoError(t, err)
+	lib2Ctx := tenant.WithID(ctx, lib2)
+
+	authorID, err := repo.NewAuthor(lib2Ctx, models.Author{Author: "Булгаков"})
+	require.NoError(t, err)
+	genreID, err := repo.NewGenre(lib2Ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+	bookID, err := repo.NewBook(lib2Ctx, models.Book{Name: "Мастер и Маргарита", Author_id: authorID, Genre_id: genreID, Price: 700})
+	require.NoError(t, err)
+
+	// жанр уникален только в пределах библиотеки
+	_, err = repo.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+
+	books, err := repo.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Empty(t, books)
+	_, err = repo.GetBookByID(ctx, bookID)
+	require.Error(t, err)
+	price := 1
+	require.Error(t, repo.UpdateBook(ctx, bookID, models.BookUpdate{Price: &price}))
+	require.NoError(t, repo.DeleteBookById(ctx, bookID))
+
+	// книга библиотеки по умолчанию не может сослаться на автора из lib2
+	_, err = repo.NewBook(ctx, models.Book{Name: "Чужой автор", Author_id: authorID, Genre_id: genreID, Price: 1})
+	require.Error(t, err)
+
+	books, err = repo.GetBooks(lib2Ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, 700, books[0].Price)
+}
+
+// Сервис работает под library_app (миграция 000019): на неё RLS действует,
+// в отличие от суперпользователя, под которым подключаются тесты.
+func TestPGRepo_AppRoleRespectsRLS(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	lib2, err := repo.NewTenant(ctx, models.Tenant{Slug: "lib2", Name: "Вторая библиотека"}, nil)
+	require.NoError(t, err)
+	_, err = repo.NewAuthor(tenant.WithID(ctx, lib2), models.Author{Author: "Булгаков"})
+	require.NoError(t, err)
+	_, err = repo.NewAuthor(ctx, models.Author{Author: "Толстой"})
+	require.NoError(t, err)
+
+	tx, err := repo.pool.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `SET LOCAL ROLE library_app`)
+	require.NoError(t, err)
+
+	count := func() int {
+		var n int
+		require.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM authors`).Scan(&n))
+		return n
+	}
+	require.Zero(t, count(), "без app.tenant_id не видно ничего")
+
+	_, err = tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, strconv.Itoa(lib2))
+	require.NoError(t, err)
+	require.Equal(t, 1, count())
+
+	_, err = tx.Exec(ctx, `ALTER TABLE authors DISABLE ROW LEVEL SECURITY`)
+	require.Error(t, err, "роль сервиса не может выключить RLS")
+}
+
+func TestPGRepo_Book_ISBN(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	authorID, err := repo.NewAuthor(ctx, models.Author{Author: "Достоевский"})
+	require.NoError(t, err)
+	genreID, err := repo.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+
+	// книг без ISBN может быть сколько угодно
+	for i := 0; i < 2; i++ {
+		_, err = repo.NewBook(ctx, models.Book{Name: "Без ISBN", Author_id: authorID, Genre_id: genreID})
+		require.NoError(t, err)
+	}
+
+	id, err := repo.NewBook(ctx, models.Book{Name: "Идиот", Author_id: authorID, Genre_id: genreID, ISBN: "9785170906307"})
+	require.NoError(t, err)
+	book, err := repo.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "9785170906307", book.ISBN)
+
+	_, err = repo.NewBook(ctx, models.Book{Name: "Идиот", Author_id: authorID, Genre_id: genreID, ISBN: "9785170906307"})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+
+	empty := ""
+	require.NoError(t, repo.UpdateBook(ctx, id, models.BookUpdate{ISBN: &empty}))
+	book, err = repo.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Empty(t, book.ISBN)
+}
+
+func TestPGRepo_PingAndCheckSchema(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	require.NoError(t, repo.Ping(ctx))
+	require.NoError(t, repo.CheckSchema(ctx))
+
+	_, err := repo.pool.Exec(ctx, `UPDATE schema_migrations SET version = version - 1`)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = repo.pool.Exec(context.Background(), `UPDATE schema_migrations SET version = $1`, SchemaVersion)
+	})
+	require.ErrorContains(t, repo.CheckSchema(ctx), "schema version is")
+}
+
+func TestPGRepo_TakeToken(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	key := "ip:test-" + t.Name()
+	bucket := ratelimit.Bucket{Capacity: 2, RatePerSecond: 1}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	tokens, ok, err := repo.TakeToken(ctx, key, bucket, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.InDelta(t, 1, tokens, 1e-9)
+
+	_, ok, err = repo.TakeToken(ctx, key, bucket, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	tokens, ok, err = repo.TakeToken(ctx, key, bucket, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.InDelta(t, 0.5, tokens, 1e-6)
+
+	tokens, ok, err = repo.TakeToken(ctx, key, bucket, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.InDelta(t, 1, tokens, 1e-6)
+
+	purged, err := repo.PurgeRateLimits(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, int64(1))
+}
+
+func TestPGRepo_CatalogVersion(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	authorID, err := repo.NewAuthor(ctx, models.Author{Author: "Гоголь"})
+	require.NoError(t, err)
+	genreID, err := repo.NewGenre(ctx, models.Genre{Genre: "Поэма"})
+	require.NoError(t, err)
+
+	before, err := repo.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+
+	id, err := repo.NewBook(ctx, models.Book{Name: "Мёртвые души", Author_id: authorID, Genre_id: genreID, Price: 10})
+	require.NoError(t, err)
+	created, err := repo.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.False(t, created.UpdatedAt.IsZero())
+
+	afterInsert, err := repo.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Greater(t, afterInsert.Version, before.Version)
+	require.False(t, afterInsert.UpdatedAt.Before(before.UpdatedAt))
+
+	price := 20
+	require.NoError(t, repo.UpdateBook(ctx, id, models.BookUpdate{Price: &price}))
+	updated, err := repo.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.True(t, updated.UpdatedAt.After(created.UpdatedAt), "триггер обновляет updated_at")
+
+	afterUpdate, err := repo.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Greater(t, afterUpdate.Version, afterInsert.Version)
+
+	// удаление не оставляет строки с updated_at, но версию коллекции меняет
+	require.NoError(t, repo.DeleteBookById(ctx, id))
+	afterDelete, err := repo.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Greater(t, afterDelete.Version, afterUpdate.Version)
+
+	// у другой библиотеки своя версия
+	other, err := repo.CatalogVersion(tenant.WithID(ctx, 999), models.CollectionBooks)
+	require.NoError(t, err)
+	require.Zero(t, other.Version)
+}
+
+func TestPGRepo_NotifyListen(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscribed := make(chan struct{})
+	received := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.Listen(ctx, "test_channel", func() { close(subscribed) }, func(payload string) { received <- payload })
+	}()
+	<-subscribed
+
+	require.NoError(t, repo.Notify(context.Background(), "test_channel", "1:books"))
+	select {
+	case payload := <-received:
+		require.Equal(t, "1:books", payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not received")
+	}
+
+	cancel()
+	require.Error(t, <-done)
+}
+
+func TestPGRepo_IdempotencyKeys(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	key := "1|ip:test|POST /api/books|" + t.Name()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	_, ok, err := repo.ReserveIdempotencyKey(ctx, key, "fp", now.Add(time.Minute), now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	rec, ok, err := repo.ReserveIdempotencyKey(ctx, key, "fp", now.Add(time.Minute), now)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, "fp", rec.Fingerprint)
+	require.Nil(t, rec.Response)
+
+	resp := idempotency.Response{Status: 201, ContentType: "application/json", Body: []byte(`{"id":7}`)}
+	require.NoError(t, repo.SaveIdempotentResponse(ctx, key, resp, now.Add(time.Hour)))
+	// сохранённый ответ Release не трогает
+	require.NoError(t, repo.ReleaseIdempotencyKey(ctx, key))
+	rec, ok, err = repo.ReserveIdempotencyKey(ctx, key, "fp", now.Add(time.Minute), now.Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, &resp, rec.Response)
+
+	// по истечении окна ключ снова свободен
+	_, ok, err = repo.ReserveIdempotencyKey(ctx, key, "fp2", now.Add(2*time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	purged, err := repo.PurgeIdempotencyKeys(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, int64(1))
+}
+
+func TestPGRepo_Contract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.DataBase {
+		return setupTestDB(t)
+	})
 }
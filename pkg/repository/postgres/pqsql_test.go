@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPool_KeepsUnsetFields(t *testing.T) {
+	cfg, err := pgxpool.ParseConfig("postgres://localhost/test?pool_max_conn_lifetime=2h")
+	require.NoError(t, err)
+	defaultIdle := cfg.MaxConnIdleTime
+
+	WithPool(PoolConfig{MaxConns: 25, MinConns: 2, HealthCheckPeriod: 10 * time.Second})(&options{pool: cfg})
+	require.Equal(t, int32(25), cfg.MaxConns)
+	require.Equal(t, int32(2), cfg.MinConns)
+	require.Equal(t, 10*time.Second, cfg.HealthCheckPeriod)
+	// нулевые поля не затирают ни умолчания pgx, ни параметры строки подключения
+	require.Equal(t, 2*time.Hour, cfg.MaxConnLifetime)
+	require.Equal(t, defaultIdle, cfg.MaxConnIdleTime)
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"leti/pkg/ratelimit"
+	"time"
+)
+
+// PGRepo реализует ratelimit.Store: все экземпляры сервиса делят один бюджет.
+var _ ratelimit.Store = (*PGRepo)(nil)
+
+// TakeToken пополняет и списывает корзину одним upsert, поэтому параллельные
+// запросы с разных реплик не заберут один и тот же токен. В SET все выражения
+// видят строку до обновления.
+func (repo *PGRepo) TakeToken(ctx context.Context, key string, bucket ratelimit.Bucket, now time.Time) (float64, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+	err := repo.pool.QueryRow(ctx, `
+		INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+		VALUES ($1, $2 - 1, true, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = rate_limit_refill(rl.tokens, $4 - rl.updated_at, $2, $3)
+				- CASE WHEN rate_limit_refill(rl.tokens, $4 - rl.updated_at, $2, $3) >= 1 THEN 1 ELSE 0 END,
+			allowed = rate_limit_refill(rl.tokens, $4 - rl.updated_at, $2, $3) >= 1,
+			updated_at = GREATEST(rl.updated_at, $4)
+		RETURNING tokens, allowed;
+	`, key, float64(bucket.Capacity), bucket.RatePerSecond, now).Scan(&tokens, &allowed)
+	return tokens, allowed, err
+}
+
+// PurgeRateLimits удаляет корзины, к которым не обращались с before: к этому
+// времени они уже полны, и их отсутствие равнозначно полной корзине.
+func (repo *PGRepo) PurgeRateLimits(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tag, err := repo.pool.Exec(ctx, `DELETE FROM rate_limits WHERE updated_at < $1;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
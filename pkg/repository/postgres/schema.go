@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// SchemaVersion — номер последней миграции в migrations/, под которую написан
+// этот код. Добавили миграцию — увеличьте и его (за этим следит TestSchemaVersionMatchesMigrations).
+const SchemaVersion = 19
+
+// Ping проверяет, что пул может выдать живое соединение.
+func (r *PGRepo) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+// CheckSchema сверяет версию применённых миграций (таблица golang-migrate)
+// с SchemaVersion. Пока они не совпадают, экземпляр не должен принимать трафик.
+func (r *PGRepo) CheckSchema(ctx context.Context) error {
+	var (
+		version int
+		dirty   bool
+	)
+	err := r.pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("no migrations applied, expected version %d", SchemaVersion)
+	case err != nil:
+		return fmt.Errorf("read schema version: %w", err)
+	case dirty:
+		return fmt.Errorf("migration %d failed halfway (dirty), fix it and rerun migrate", version)
+	case version != SchemaVersion:
+		return fmt.Errorf("schema version is %d, expected %d", version, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "..", "migrations", "*.up.sql"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	latest := 0
+	for _, f := range files {
+		prefix, _, _ := strings.Cut(filepath.Base(f), "_")
+		n, err := strconv.Atoi(prefix)
+		require.NoError(t, err, f)
+		latest = max(latest, n)
+	}
+	require.Equal(t, latest, SchemaVersion, "update SchemaVersion after adding a migration")
+}
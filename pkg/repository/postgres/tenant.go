@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"strconv"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// inTenant выполняет fn в транзакции, где app.tenant_id указывает на арендатора из ctx.
+// Политики RLS (миграция 000014) пропускают только его строки, поэтому даже запрос,
+// в котором забыли фильтр по tenant_id, не увидит и не изменит чужие данные.
+func (repo *PGRepo) inTenant(ctx context.Context, fn func(tx pgx.Tx, tenantID int) error) error {
+	tenantID := tenant.FromContext(ctx)
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// true — настройка действует только до конца транзакции и не утечёт в пул
+	if _, err := tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true);`, strconv.Itoa(tenantID)); err != nil {
+		return err
+	}
+	if err := fn(tx, tenantID); err != nil {
+		return translateError(err)
+	}
+	return translateError(tx.Commit(ctx))
+}
+
+const tenantColumns = `id, slug, name, created_at`
+
+func scanTenant(row pgx.Row) (*models.Tenant, error) {
+	var t models.Tenant
+	if err := row.Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (repo *PGRepo) NewTenant(ctx context.Context, t models.Tenant, admin *models.User) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tenants (slug, name)
+		VALUES ($1, $2)
+		RETURNING id;
+	`, t.Slug, t.Name).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	if admin != nil {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO users (username, password, role, email, tenant_id)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5);
+		`, admin.Username, admin.Password, admin.Role, admin.Email, id)
+		if err != nil {
+			return 0, translateError(err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
+
+func (repo *PGRepo) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.pool.Query(ctx, `SELECT `+tenantColumns+` FROM tenants ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []models.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, *t)
+	}
+	return tenants, rows.Err()
+}
+
+func (repo *PGRepo) GetTenantBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	t, err := scanTenant(repo.pool.QueryRow(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE slug = $1;`, slug))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperr.NotFound("tenant %q not found", slug)
+	}
+	return t, err
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "leti/pkg/repository/postgres"
+
+// WithTracerProvider добавляет SQL-спаны к трассе запроса.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		addLogger(o.pool, pgxTracer{tracer: tp.Tracer(tracerName)})
+	}
+}
+
+// pgxTracer строит спаны из журнала pgx: отдельного хука трассировки в pgx v4
+// нет, но Logger вызывается после каждого запроса с его длительностью, так что
+// спан открывается задним числом. Запросы вне трассы (без родительского спана
+// в контексте) не записываются.
+type pgxTracer struct {
+	tracer trace.Tracer
+}
+
+func (t pgxTracer) Log(ctx context.Context, _ pgx.LogLevel, msg string, data map[string]interface{}) {
+	elapsed, ok := data["time"].(time.Duration)
+	// записи без длительности — подключение, закрытие соединения и т.п.
+	if !ok || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	end := time.Now()
+
+	name := msg
+	attrs := []attribute.KeyValue{semconv.DBSystemNamePostgreSQL}
+	if sql, ok := data["sql"].(string); ok {
+		sql = sanitizeSQL(sql)
+		if op, _, _ := strings.Cut(sql, " "); op != "" {
+			name = strings.ToUpper(op)
+			attrs = append(attrs, semconv.DBOperationName(name))
+		}
+		attrs = append(attrs, semconv.DBQueryText(sql))
+	}
+	if rows, ok := data["rowCount"].(int); ok {
+		attrs = append(attrs, semconv.DBResponseReturnedRows(rows))
+	}
+
+	_, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-elapsed)),
+		trace.WithAttributes(attrs...),
+	)
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberLiteral = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+	sqlSpaces        = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL убирает из текста запроса литералы: значения идут параметрами
+// ($1, $2 остаются), но на случай литерала в самом SQL его в трассу не отдаём.
+func sanitizeSQL(sql string) string {
+	sql = sqlStringLiteral.ReplaceAllString(sql, "?")
+	sql = sqlNumberLiteral.ReplaceAllStringFunc(sql, func(m string) string {
+		if strings.HasPrefix(m, "$") {
+			return m
+		}
+		return "?"
+	})
+	return strings.TrimSpace(sqlSpaces.ReplaceAllString(sql, " "))
+}
+
+// multiLogger раздаёт записи pgx нескольким получателям (журнал и трассировка).
+type multiLogger []pgx.Logger
+
+func (m multiLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	for _, l := range m {
+		l.Log(ctx, level, msg, data)
+	}
+}
+
+// addLogger подключает l, не заменяя уже подключённых получателей. Уровень
+// поднимается до Info: на нём pgx сообщает о каждом выполненном запросе.
+func addLogger(cfg *pgxpool.Config, l pgx.Logger) {
+	switch prev := cfg.ConnConfig.Logger.(type) {
+	case nil:
+		cfg.ConnConfig.Logger = l
+	case multiLogger:
+		cfg.ConnConfig.Logger = append(prev, l)
+	default:
+		cfg.ConnConfig.Logger = multiLogger{prev, l}
+	}
+	if cfg.ConnConfig.LogLevel < pgx.LogLevelInfo {
+		cfg.ConnConfig.LogLevel = pgx.LogLevelInfo
+	}
+}
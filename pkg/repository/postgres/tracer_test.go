@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	require.Equal(t,
+		"SELECT id FROM users WHERE username = $1 AND role = ? LIMIT ?",
+		sanitizeSQL("SELECT id\n\t\tFROM users\n\t\tWHERE username = $1 AND role = 'it''s admin' LIMIT 10\n"),
+	)
+	require.Equal(t, "UPDATE t2 SET price = ? WHERE id = $12", sanitizeSQL("UPDATE t2 SET price = 9.99 WHERE id = $12"))
+}
+
+func TestPgxTracer_RecordsSQLSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := pgxTracer{tracer: tp.Tracer("test")}
+
+	// вне трассы спаны не пишутся
+	tracer.Log(context.Background(), pgx.LogLevelInfo, "Exec", map[string]interface{}{"sql": "BEGIN", "time": time.Millisecond})
+	require.Empty(t, rec.Ended())
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "GET /api/books")
+	tracer.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql":      "SELECT name FROM books WHERE tenant_id = $1 AND name <> 'secret'",
+		"args":     []interface{}{1},
+		"time":     50 * time.Millisecond,
+		"rowCount": 3,
+	})
+	tracer.Log(ctx, pgx.LogLevelError, "Exec", map[string]interface{}{
+		"sql":  "INSERT INTO books (name) VALUES ($1)",
+		"err":  errors.New("duplicate key"),
+		"time": time.Millisecond,
+	})
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	query := spans[0]
+	require.Equal(t, "SELECT", query.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	require.InDelta(t, 50*time.Millisecond, query.EndTime().Sub(query.StartTime()), float64(time.Millisecond))
+	require.Contains(t, query.Attributes(), attribute.String("db.query.text", "SELECT name FROM books WHERE tenant_id = $1 AND name <> ?"))
+	require.Contains(t, query.Attributes(), attribute.Int("db.response.returned_rows", 3))
+
+	insert := spans[1]
+	require.Equal(t, "INSERT", insert.Name())
+	require.Equal(t, codes.Error, insert.Status().Code)
+}
+
+func TestAddLogger_KeepsExistingLoggers(t *testing.T) {
+	cfg, err := pgxpool.ParseConfig("postgres://localhost/test")
+	require.NoError(t, err)
+	tp := sdktrace.NewTracerProvider()
+	o := &options{pool: cfg}
+	WithLogger(nil)(o)
+	WithTracerProvider(tp)(o)
+	require.IsType(t, multiLogger{}, cfg.ConnConfig.Logger)
+	require.Len(t, cfg.ConnConfig.Logger.(multiLogger), 2)
+	require.Equal(t, pgx.LogLevel(pgx.LogLevelInfo), cfg.ConnConfig.LogLevel)
+}
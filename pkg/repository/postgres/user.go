@@ -2,24 +2,232 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
 )
 
+// userColumns и scanUser — общий набор полей пользователя для всех запросов.
+const userColumns = `u.id, u.username, u.password, u.role, COALESCE(u.email, ''), u.token_version, u.disabled_at, u.tenant_id, u.created_at`
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы поиск шёл по подстроке буквально.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.Role, &user.Email,
+		&user.TokenVersion, &user.DisabledAt, &user.TenantID, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (repo *PGRepo) GetUserByUsername(ctx context.Context, userName string) (*models.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
 	defer cancel()
 
-	var user models.User
-	err := repo.pool.QueryRow(ctx, `
-        SELECT id, username, password, role 
-        FROM users 
-        WHERE username = $1;
+	user, err := scanUser(repo.pool.QueryRow(ctx, `
+        SELECT `+userColumns+`
+        FROM users u
+        WHERE u.username = $1;
         `,
 		userName,
-	).Scan(&user.ID, &user.Username, &user.Password, &user.Role)
+	))
+	return user, translateError(err)
+}
 
+func (repo *PGRepo) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	user, err := scanUser(repo.pool.QueryRow(ctx, `
+        SELECT `+userColumns+`
+        FROM users u
+        WHERE u.id = $1;
+        `,
+		id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperr.NotFound("user with id %d not found", id)
+	}
+	return user, err
+}
+
+func (repo *PGRepo) UpdateUserRole(ctx context.Context, id int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.pool.Exec(ctx, `
+		UPDATE users SET role = $2
+		WHERE id = $1;
+	`, id, role)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &user, nil
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound("user with id %d not found", id)
+	}
+	return nil
+}
+
+func (repo *PGRepo) UpdateUserPassword(ctx context.Context, id int, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users SET password = $2, token_version = token_version + 1
+		WHERE id = $1;
+	`, id, passwordHash)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound("user with id %d not found", id)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE password_reset_tokens SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL;
+	`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *PGRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	user, err := scanUser(repo.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.issuer = $1 AND i.subject = $2;
+	`, issuer, subject))
+	return user, translateError(err)
+}
+
+func (repo *PGRepo) NewUserWithIdentity(ctx context.Context, user models.User, issuer, subject string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (username, password, role, email, tenant_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id;
+	`, user.Username, user.Password, user.Role, user.Email, user.TenantID).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_identities (user_id, issuer, subject)
+		VALUES ($1, $2, $3);
+	`, id, issuer, subject)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
+
+func (repo *PGRepo) UpgradePasswordHash(ctx context.Context, id int, oldHash, newHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `
+		UPDATE users SET password = $3
+		WHERE id = $1 AND password = $2;
+	`, id, oldHash, newHash)
+	return err
+}
+
+func (repo *PGRepo) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	// пустой поиск совпадает со всеми, нулевой арендатор — тоже
+	const where = `WHERE (u.username ILIKE '%' || $1 || '%' OR u.email ILIKE '%' || $1 || '%')
+		AND ($2 = 0 OR u.tenant_id = $2)`
+
+	var total int
+	search := likeEscaper.Replace(filter.Search)
+	if err := repo.pool.QueryRow(ctx, `SELECT count(*) FROM users u `+where, search, filter.TenantID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.pool.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		`+where+`
+		ORDER BY u.id
+		LIMIT $3 OFFSET $4;
+	`, search, filter.TenantID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+func (repo *PGRepo) SetUserDisabled(ctx context.Context, id int, disabledAt *time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.pool.Exec(ctx, `
+		UPDATE users SET disabled_at = $2
+		WHERE id = $1;
+	`, id, disabledAt)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound("user with id %d not found", id)
+	}
+	return nil
+}
+
+func (repo *PGRepo) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.pool.Exec(ctx, `DELETE FROM users WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound("user with id %d not found", id)
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"leti/pkg/models"
+	"time"
 )
 
 type AuthorDB interface {
@@ -10,15 +11,29 @@ type AuthorDB interface {
 	NewAuthor(context.Context, models.Author) (int, error)
 }
 
+// Общие правила для всех хранилищ (их проверяет пакет repositorytest):
+// списки упорядочены по id и пусты, а не nil; отсутствующая запись —
+// apperr.ErrNotFound, занятое уникальное значение — apperr.ErrConflict,
+// ссылка на несуществующую запись — apperr.ErrValidation.
+
 type BooksDB interface {
 	GetBooks(context.Context) ([]models.Book, error)
 	NewBook(context.Context, models.Book) (int, error)
 	GetBookByID(context.Context, int) (models.Book, error)
+	// DeleteBookById идемпотентен: удалять нечего — не ошибка.
 	DeleteBookById(context.Context, int) error
 	GetAllWithAuthors(context.Context) ([]models.BookWithAuthor, error)
+	// UpdateBook без изменяемых полей только проверяет, что книга существует.
 	UpdateBook(context.Context, int, models.BookUpdate) error
 }
 
+// CatalogDB отдаёт версии коллекций каталога, чтобы отвечать на условные
+// запросы, не читая саму коллекцию.
+type CatalogDB interface {
+	// CatalogVersion возвращает версию коллекции (models.Collection*) арендатора из контекста.
+	CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error)
+}
+
 type GenreDB interface {
 	GetAllGenres(context.Context) ([]models.Genre, error)
 	NewGenre(context.Context, models.Genre) (int, error)
@@ -26,11 +41,89 @@ type GenreDB interface {
 
 type UserDB interface {
 	GetUserByUsername(context.Context, string) (*models.User, error)
+	GetUserByID(context.Context, int) (*models.User, error)
+	UpdateUserRole(context.Context, int, string) error
+	// UpdateUserPassword меняет хэш пароля, увеличивает token_version
+	// и гасит все неиспользованные токены сброса пароля.
+	UpdateUserPassword(ctx context.Context, id int, passwordHash string) error
+	// UpgradePasswordHash заменяет хэш тем же паролем, посчитанным заново (сессии не трогает).
+	// Ничего не делает, если хэш уже сменился с oldHash.
+	UpgradePasswordHash(ctx context.Context, id int, oldHash, newHash string) error
+	// ListUsers возвращает страницу пользователей (по id) и общее число подходящих.
+	ListUsers(context.Context, models.UserFilter) ([]models.User, int, error)
+	// SetUserDisabled блокирует учётную запись (disabledAt != nil) или снимает блокировку.
+	SetUserDisabled(ctx context.Context, id int, disabledAt *time.Time) error
+	// DeleteUser удаляет и всё, что ссылается на пользователя: ключи, 2FA, токены.
+	DeleteUser(context.Context, int) error
+	// GetUserByIdentity ищет пользователя по (issuer, subject) внешнего провайдера.
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	// NewUserWithIdentity создаёт пользователя и привязку к внешнему провайдеру одной транзакцией.
+	NewUserWithIdentity(ctx context.Context, user models.User, issuer, subject string) (int, error)
+}
+
+type APIKeyDB interface {
+	NewAPIKey(context.Context, models.APIKey) (int, error)
+	GetAPIKeyByPrefix(context.Context, string) (*models.APIKey, error)
+	// ListAPIKeys и RevokeAPIKey видят только ключи пользователей библиотеки tenantID.
+	ListAPIKeys(ctx context.Context, tenantID int) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, tenantID, id int) error
+	// TouchAPIKey для неизвестного ключа ничего не делает и ошибки не возвращает.
+	TouchAPIKey(context.Context, int, time.Time) error
+}
+
+type OAuthDB interface {
+	NewOAuthClient(context.Context, models.OAuthClient) (int, error)
+	GetOAuthClient(context.Context, string) (*models.OAuthClient, error)
+	ListOAuthClients(context.Context) ([]models.OAuthClient, error)
+	NewAuthorizationCode(context.Context, models.AuthorizationCode) error
+	// ConsumeAuthorizationCode атомарно помечает код использованным и возвращает его.
+	// Повторный вызов для того же кода — apperr.ErrNotFound.
+	ConsumeAuthorizationCode(context.Context, string) (*models.AuthorizationCode, error)
+}
+
+type MFADB interface {
+	GetTOTP(context.Context, int) (*models.TOTP, error)
+	// SetTOTPSecret сохраняет новый неподтверждённый секрет (enabled = false).
+	SetTOTPSecret(ctx context.Context, userID int, secret string) error
+	// EnableTOTP включает 2FA и заменяет коды восстановления одной транзакцией.
+	EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error
+	DisableTOTP(context.Context, int) error
+	// AdvanceTOTPStep запоминает принятый шаг; false — шаг уже использован.
+	AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	ListRecoveryCodes(context.Context, int) ([]models.RecoveryCode, error)
+	// UseRecoveryCode гасит код; false — код уже использован.
+	UseRecoveryCode(context.Context, int) (bool, error)
+	GetMFARequiredRoles(context.Context) ([]string, error)
+	SetMFARequiredRoles(context.Context, []string) error
+}
+
+type PasswordResetDB interface {
+	// NewPasswordResetToken сохраняет токен; прежние неиспользованные токены
+	// пользователя перестают действовать.
+	NewPasswordResetToken(context.Context, models.PasswordResetToken) error
+	// ConsumePasswordResetToken атомарно гасит действующий токен и возвращает его;
+	// использованный, просроченный или неизвестный токен — apperr.ErrNotFound.
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+}
+
+// TenantDB — реестр библиотек. Сами данные каталога разделяются по арендатору
+// из контекста (см. пакет tenant), а не через этот интерфейс.
+type TenantDB interface {
+	// NewTenant создаёт библиотеку и, если admin != nil, её первого администратора одной транзакцией.
+	NewTenant(ctx context.Context, tenant models.Tenant, admin *models.User) (int, error)
+	ListTenants(context.Context) ([]models.Tenant, error)
+	GetTenantBySlug(context.Context, string) (*models.Tenant, error)
 }
 
 type DataBase interface {
 	BooksDB
+	CatalogDB
 	GenreDB
 	AuthorDB
 	UserDB
+	APIKeyDB
+	OAuthDB
+	MFADB
+	PasswordResetDB
+	TenantDB
 }
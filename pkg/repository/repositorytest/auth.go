@@ -0,0 +1,362 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/tenant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testAPIKeys(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	userID := newUser(t, db, "contract-keys")
+
+	keys, err := db.ListAPIKeys(ctx, tenant.DefaultID)
+	require.NoError(t, err)
+	require.NotNil(t, keys)
+	require.Empty(t, keys)
+
+	expires := time.Now().Add(time.Hour)
+	id, err := db.NewAPIKey(ctx, models.APIKey{
+		UserID:    userID,
+		Name:      "импорт",
+		Prefix:    "lk_contract",
+		Hash:      "key-hash",
+		Scopes:    []string{"books:read", "books:write"},
+		ExpiresAt: &expires,
+	})
+	require.NoError(t, err)
+	require.Positive(t, id)
+
+	_, err = db.NewAPIKey(ctx, models.APIKey{UserID: userID, Name: "дубль", Prefix: "lk_contract", Hash: "other", Scopes: []string{}})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+
+	key, err := db.GetAPIKeyByPrefix(ctx, "lk_contract")
+	require.NoError(t, err)
+	require.Equal(t, id, key.ID)
+	require.Equal(t, userID, key.UserID)
+	require.Equal(t, "импорт", key.Name)
+	require.Equal(t, "key-hash", key.Hash)
+	require.Equal(t, []string{"books:read", "books:write"}, key.Scopes)
+	require.NotNil(t, key.ExpiresAt)
+	require.WithinDuration(t, expires, *key.ExpiresAt, time.Second)
+	require.Nil(t, key.LastUsedAt)
+	require.Nil(t, key.RevokedAt)
+	require.WithinDuration(t, time.Now(), key.CreatedAt, time.Minute)
+
+	// ключ без срока действия
+	second, err := db.NewAPIKey(ctx, models.APIKey{UserID: userID, Name: "бессрочный", Prefix: "lk_forever", Hash: "forever", Scopes: []string{}})
+	require.NoError(t, err)
+	require.Greater(t, second, id)
+	key, err = db.GetAPIKeyByPrefix(ctx, "lk_forever")
+	require.NoError(t, err)
+	require.Nil(t, key.ExpiresAt)
+	require.Empty(t, key.Scopes)
+
+	usedAt := time.Now()
+	require.NoError(t, db.TouchAPIKey(ctx, id, usedAt))
+	// отметка использования не повод отказывать в запросе
+	require.NoError(t, db.TouchAPIKey(ctx, missingID, usedAt))
+
+	keys, err = db.ListAPIKeys(ctx, tenant.DefaultID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, id, keys[0].ID, "по возрастанию id")
+	require.Equal(t, second, keys[1].ID)
+	require.Empty(t, keys[0].Hash, "список не отдаёт хэш секрета")
+	require.NotNil(t, keys[0].LastUsedAt)
+	require.WithinDuration(t, usedAt, *keys[0].LastUsedAt, time.Second)
+	require.Nil(t, keys[1].LastUsedAt)
+
+	// ключи другой библиотеки не видны и не отзываются
+	otherID, err := db.NewTenant(ctx, models.Tenant{Slug: "contract-keys", Name: "Другая библиотека"}, nil)
+	require.NoError(t, err)
+	keys, err = db.ListAPIKeys(ctx, otherID)
+	require.NoError(t, err)
+	require.NotNil(t, keys)
+	require.Empty(t, keys)
+	require.ErrorIs(t, db.RevokeAPIKey(ctx, otherID, id), apperr.ErrNotFound)
+
+	require.NoError(t, db.RevokeAPIKey(ctx, tenant.DefaultID, id))
+	key, err = db.GetAPIKeyByPrefix(ctx, "lk_contract")
+	require.NoError(t, err, "отозванный ключ находится: отказ — забота сервиса")
+	require.NotNil(t, key.RevokedAt)
+	require.ErrorIs(t, db.RevokeAPIKey(ctx, tenant.DefaultID, id), apperr.ErrNotFound, "повторный отзыв")
+	require.ErrorIs(t, db.RevokeAPIKey(ctx, tenant.DefaultID, missingID), apperr.ErrNotFound)
+
+	_, err = db.GetAPIKeyByPrefix(ctx, "lk_missing")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func testOAuth(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	userID := newUser(t, db, "contract-oauth")
+
+	clients, err := db.ListOAuthClients(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, clients)
+	require.Empty(t, clients)
+
+	id, err := db.NewOAuthClient(ctx, models.OAuthClient{
+		ClientID:     "contract-app",
+		Name:         "Мобильное приложение",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"openid", "books:read"},
+		GrantTypes:   []string{"authorization_code", "refresh_token"},
+	})
+	require.NoError(t, err)
+	require.Positive(t, id)
+	_, err = db.NewOAuthClient(ctx, models.OAuthClient{
+		ClientID:     "contract-app",
+		Name:         "дубль",
+		RedirectURIs: []string{},
+		Scopes:       []string{},
+		GrantTypes:   []string{},
+	})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+
+	client, err := db.GetOAuthClient(ctx, "contract-app")
+	require.NoError(t, err)
+	require.Equal(t, id, client.ID)
+	require.Equal(t, "Мобильное приложение", client.Name)
+	require.False(t, client.IsConfidential())
+	require.Equal(t, []string{"https://app.example.com/callback"}, client.RedirectURIs)
+	require.Equal(t, []string{"openid", "books:read"}, client.Scopes)
+	require.Equal(t, []string{"authorization_code", "refresh_token"}, client.GrantTypes)
+
+	secretHash := "secret-hash"
+	service, err := db.NewOAuthClient(ctx, models.OAuthClient{
+		ClientID:     "contract-service",
+		SecretHash:   secretHash,
+		Name:         "Сервис",
+		RedirectURIs: []string{},
+		Scopes:       []string{"books:read"},
+		GrantTypes:   []string{"client_credentials"},
+	})
+	require.NoError(t, err)
+	client, err = db.GetOAuthClient(ctx, "contract-service")
+	require.NoError(t, err)
+	require.True(t, client.IsConfidential())
+	require.Equal(t, secretHash, client.SecretHash)
+
+	clients, err = db.ListOAuthClients(ctx)
+	require.NoError(t, err)
+	require.Len(t, clients, 2)
+	require.Equal(t, id, clients[0].ID, "по возрастанию id")
+	require.Equal(t, service, clients[1].ID)
+
+	_, err = db.GetOAuthClient(ctx, "missing-app")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	expires := time.Now().Add(time.Minute)
+	code := models.AuthorizationCode{
+		CodeHash:            "code-hash",
+		ClientID:            "contract-app",
+		UserID:              userID,
+		RedirectURI:         "https://app.example.com/callback",
+		Scopes:              []string{"openid"},
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: "S256",
+		ExpiresAt:           expires,
+	}
+	require.NoError(t, db.NewAuthorizationCode(ctx, code))
+	require.ErrorIs(t, db.NewAuthorizationCode(ctx, code), apperr.ErrConflict)
+
+	consumed, err := db.ConsumeAuthorizationCode(ctx, "code-hash")
+	require.NoError(t, err)
+	require.Equal(t, "code-hash", consumed.CodeHash)
+	require.Equal(t, "contract-app", consumed.ClientID)
+	require.Equal(t, userID, consumed.UserID)
+	require.Equal(t, "https://app.example.com/callback", consumed.RedirectURI)
+	require.Equal(t, []string{"openid"}, consumed.Scopes)
+	require.Equal(t, "challenge", consumed.CodeChallenge)
+	require.Equal(t, "S256", consumed.CodeChallengeMethod)
+	require.WithinDuration(t, expires, consumed.ExpiresAt, time.Second)
+	require.NotNil(t, consumed.UsedAt)
+
+	// срок действия проверяет сервис; хранилище отвечает только за одноразовость
+	_, err = db.ConsumeAuthorizationCode(ctx, "code-hash")
+	require.ErrorIs(t, err, apperr.ErrNotFound, "код одноразовый")
+	_, err = db.ConsumeAuthorizationCode(ctx, "missing-code")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func testMFA(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	userID := newUser(t, db, "contract-mfa")
+
+	_, err := db.GetTOTP(ctx, userID)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	require.ErrorIs(t, db.EnableTOTP(ctx, userID, nil), apperr.ErrNotFound)
+	ok, err := db.AdvanceTOTPStep(ctx, userID, 1)
+	require.NoError(t, err)
+	require.False(t, ok, "без секрета шаг не принимается")
+	require.NoError(t, db.DisableTOTP(ctx, userID), "выключать нечего — не ошибка")
+
+	require.NoError(t, db.SetTOTPSecret(ctx, userID, "secret"))
+	totp, err := db.GetTOTP(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, userID, totp.UserID)
+	require.Equal(t, "secret", totp.Secret)
+	require.False(t, totp.Enabled)
+	require.Zero(t, totp.LastStep)
+
+	require.NoError(t, db.EnableTOTP(ctx, userID, []string{"code-1", "code-2"}))
+	totp, err = db.GetTOTP(ctx, userID)
+	require.NoError(t, err)
+	require.True(t, totp.Enabled)
+
+	ok, err = db.AdvanceTOTPStep(ctx, userID, 10)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = db.AdvanceTOTPStep(ctx, userID, 10)
+	require.NoError(t, err)
+	require.False(t, ok, "шаг нельзя использовать повторно")
+	ok, err = db.AdvanceTOTPStep(ctx, userID, 9)
+	require.NoError(t, err)
+	require.False(t, ok, "и более ранний тоже")
+	totp, err = db.GetTOTP(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), totp.LastStep)
+
+	codes, err := db.ListRecoveryCodes(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, codes, 2)
+	require.Equal(t, "code-1", codes[0].CodeHash, "по возрастанию id")
+	require.Equal(t, userID, codes[0].UserID)
+	ok, err = db.UseRecoveryCode(ctx, codes[0].ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = db.UseRecoveryCode(ctx, codes[0].ID)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = db.UseRecoveryCode(ctx, missingID)
+	require.NoError(t, err)
+	require.False(t, ok)
+	codes, err = db.ListRecoveryCodes(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, codes, 1)
+	require.Equal(t, "code-2", codes[0].CodeHash)
+
+	// повторное включение заменяет коды восстановления
+	require.NoError(t, db.EnableTOTP(ctx, userID, []string{"code-3"}))
+	codes, err = db.ListRecoveryCodes(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, codes, 1)
+	require.Equal(t, "code-3", codes[0].CodeHash)
+
+	// новый секрет снова требует подтверждения
+	require.NoError(t, db.SetTOTPSecret(ctx, userID, "rotated"))
+	totp, err = db.GetTOTP(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, "rotated", totp.Secret)
+	require.False(t, totp.Enabled)
+	require.Zero(t, totp.LastStep)
+
+	require.NoError(t, db.DisableTOTP(ctx, userID))
+	_, err = db.GetTOTP(ctx, userID)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	codes, err = db.ListRecoveryCodes(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, codes)
+	require.Empty(t, codes)
+
+	roles, err := db.GetMFARequiredRoles(ctx)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+	require.NoError(t, db.SetMFARequiredRoles(ctx, []string{"user", "admin"}))
+	roles, err = db.GetMFARequiredRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin", "user"}, roles, "роли по алфавиту")
+	require.NoError(t, db.SetMFARequiredRoles(ctx, []string{"admin"}))
+	roles, err = db.GetMFARequiredRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin"}, roles, "список заменяется целиком")
+	require.NoError(t, db.SetMFARequiredRoles(ctx, nil))
+	roles, err = db.GetMFARequiredRoles(ctx)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+}
+
+func testPasswordReset(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	userID := newUser(t, db, "contract-reset")
+	now := time.Now()
+
+	require.NoError(t, db.NewPasswordResetToken(ctx, models.PasswordResetToken{TokenHash: "old", UserID: userID, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, db.NewPasswordResetToken(ctx, models.PasswordResetToken{TokenHash: "new", UserID: userID, ExpiresAt: now.Add(time.Hour)}))
+
+	_, err := db.ConsumePasswordResetToken(ctx, "old", now)
+	require.ErrorIs(t, err, apperr.ErrNotFound, "действует только последний токен")
+
+	_, err = db.ConsumePasswordResetToken(ctx, "new", now.Add(2*time.Hour))
+	require.ErrorIs(t, err, apperr.ErrNotFound, "просроченный токен")
+
+	token, err := db.ConsumePasswordResetToken(ctx, "new", now)
+	require.NoError(t, err)
+	require.Equal(t, "new", token.TokenHash)
+	require.Equal(t, userID, token.UserID)
+	require.NotNil(t, token.UsedAt)
+	require.WithinDuration(t, now.Add(time.Hour), token.ExpiresAt, time.Second)
+
+	_, err = db.ConsumePasswordResetToken(ctx, "new", now)
+	require.ErrorIs(t, err, apperr.ErrNotFound, "токен одноразовый")
+	_, err = db.ConsumePasswordResetToken(ctx, "missing", now)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	// смена пароля гасит выданные токены
+	require.NoError(t, db.NewPasswordResetToken(ctx, models.PasswordResetToken{TokenHash: "pending", UserID: userID, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, db.UpdateUserPassword(ctx, userID, "changed"))
+	_, err = db.ConsumePasswordResetToken(ctx, "pending", now)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+// testReferences фиксирует, что ссылки на несуществующих пользователя и
+// клиента отклоняются как ошибка валидации — так же, как внешние ключи в PGRepo.
+func testReferences(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	userID := newUser(t, db, "contract-refs")
+	_, err := db.NewOAuthClient(ctx, models.OAuthClient{
+		ClientID:     "contract-refs-app",
+		Name:         "Приложение",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"openid"},
+		GrantTypes:   []string{"authorization_code"},
+	})
+	require.NoError(t, err)
+
+	_, err = db.NewAPIKey(ctx, models.APIKey{UserID: missingID, Name: "ничей", Prefix: "lk_orphan", Hash: "hash", Scopes: []string{}})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = db.GetAPIKeyByPrefix(ctx, "lk_orphan")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	require.ErrorIs(t, db.SetTOTPSecret(ctx, missingID, "secret"), apperr.ErrValidation)
+	_, err = db.GetTOTP(ctx, missingID)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	require.ErrorIs(t, db.NewPasswordResetToken(ctx, models.PasswordResetToken{
+		TokenHash: "orphan", UserID: missingID, ExpiresAt: time.Now().Add(time.Hour),
+	}), apperr.ErrValidation)
+
+	code := models.AuthorizationCode{
+		CodeHash:            "orphan-code",
+		ClientID:            "contract-refs-app",
+		UserID:              missingID,
+		RedirectURI:         "https://app.example.com/callback",
+		Scopes:              []string{"openid"},
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: "S256",
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+	require.ErrorIs(t, db.NewAuthorizationCode(ctx, code), apperr.ErrValidation)
+	code.UserID, code.ClientID = userID, "missing-app"
+	require.ErrorIs(t, db.NewAuthorizationCode(ctx, code), apperr.ErrValidation)
+	_, err = db.ConsumeAuthorizationCode(ctx, "orphan-code")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+}
@@ -0,0 +1,369 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/tenant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testAuthors(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+
+	authors, err := db.GetAllAuthors(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, authors, "пустой список, а не nil: в JSON это [], а не null")
+	require.Empty(t, authors)
+
+	id, err := db.NewAuthor(ctx, models.Author{Author: "Лев Толстой"})
+	require.NoError(t, err)
+	require.Positive(t, id)
+
+	// имя автора не уникально: тёзки бывают
+	namesake, err := db.NewAuthor(ctx, models.Author{Author: "Лев Толстой"})
+	require.NoError(t, err)
+	require.Greater(t, namesake, id)
+
+	authors, err = db.GetAllAuthors(ctx)
+	require.NoError(t, err)
+	require.Len(t, authors, 2)
+	require.Equal(t, id, authors[0].ID, "по возрастанию id")
+	require.Equal(t, "Лев Толстой", authors[0].Author)
+	require.Equal(t, namesake, authors[1].ID)
+	require.Equal(t, "Лев Толстой", authors[1].Author)
+}
+
+func testGenres(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+
+	genres, err := db.GetAllGenres(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, genres)
+	require.Empty(t, genres)
+
+	id, err := db.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+	require.Positive(t, id)
+
+	_, err = db.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+
+	drama, err := db.NewGenre(ctx, models.Genre{Genre: "Драма"})
+	require.NoError(t, err)
+	require.Greater(t, drama, id)
+
+	genres, err = db.GetAllGenres(ctx)
+	require.NoError(t, err)
+	require.Len(t, genres, 2)
+	require.Equal(t, id, genres[0].ID, "по возрастанию id, а не по имени")
+	require.Equal(t, "Роман", genres[0].Genre)
+	require.Equal(t, drama, genres[1].ID)
+	require.Equal(t, "Драма", genres[1].Genre)
+}
+
+func testBooks(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+
+	books, err := db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, books)
+	require.Empty(t, books)
+	withAuthors, err := db.GetAllWithAuthors(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, withAuthors)
+	require.Empty(t, withAuthors)
+
+	authorID, genreID := catalog(t, ctx, db)
+
+	id, err := db.NewBook(ctx, models.Book{
+		Name:      "Война и мир",
+		Price:     500,
+		Author_id: authorID,
+		Genre_id:  genreID,
+		ISBN:      "978-5-17-090335-2",
+	})
+	require.NoError(t, err)
+	require.Positive(t, id)
+
+	book, err := db.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, id, book.ID)
+	require.Equal(t, "Война и мир", book.Name)
+	require.Equal(t, 500, book.Price)
+	require.Equal(t, authorID, book.Author_id)
+	require.Equal(t, genreID, book.Genre_id)
+	require.Equal(t, "978-5-17-090335-2", book.ISBN)
+	require.WithinDuration(t, time.Now(), book.UpdatedAt, time.Minute)
+
+	second, err := db.NewBook(ctx, models.Book{Name: "Анна Каренина", Price: 450, Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	require.Greater(t, second, id)
+
+	books, err = db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+	require.Equal(t, id, books[0].ID, "по возрастанию id")
+	require.Equal(t, second, books[1].ID)
+	require.Equal(t, "", books[1].ISBN, "книга без ISBN")
+
+	withAuthors, err = db.GetAllWithAuthors(ctx)
+	require.NoError(t, err)
+	require.Len(t, withAuthors, 2)
+	require.Equal(t, models.BookWithAuthor{
+		ID:         id,
+		Name:       "Война и мир",
+		Price:      500,
+		AuthorID:   authorID,
+		AuthorName: "Лев Толстой",
+		GenreID:    genreID,
+	}, withAuthors[0])
+	require.Equal(t, second, withAuthors[1].ID)
+
+	name, price := "Воскресение", 700
+	before := book.UpdatedAt
+	require.NoError(t, db.UpdateBook(ctx, id, models.BookUpdate{Name: &name, Price: &price}))
+	book, err = db.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, name, book.Name)
+	require.Equal(t, price, book.Price)
+	require.Equal(t, "978-5-17-090335-2", book.ISBN, "поля без значения в BookUpdate не меняются")
+	require.False(t, book.UpdatedAt.Before(before))
+
+	// пустой ISBN снимает его с книги
+	empty := ""
+	require.NoError(t, db.UpdateBook(ctx, id, models.BookUpdate{ISBN: &empty}))
+	book, err = db.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "", book.ISBN)
+
+	// менять нечего — не ошибка, книга остаётся прежней
+	require.NoError(t, db.UpdateBook(ctx, id, models.BookUpdate{}))
+	unchanged, err := db.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, book.Name, unchanged.Name)
+	require.Equal(t, book.Price, unchanged.Price)
+
+	require.NoError(t, db.DeleteBookById(ctx, second))
+	_, err = db.GetBookByID(ctx, second)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	books, err = db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+
+	// id удалённой книги не достаётся новой
+	third, err := db.NewBook(ctx, models.Book{Name: "Хаджи-Мурат", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	require.Greater(t, third, second)
+}
+
+// testBookMissing фиксирует ответы для несуществующей книги.
+func testBookMissing(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	authorID, genreID := catalog(t, ctx, db)
+	id, err := db.NewBook(ctx, models.Book{Name: "Война и мир", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	before, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+
+	_, err = db.GetBookByID(ctx, missingID)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	name := "Никакая"
+	require.ErrorIs(t, db.UpdateBook(ctx, missingID, models.BookUpdate{Name: &name}), apperr.ErrNotFound)
+	require.ErrorIs(t, db.UpdateBook(ctx, missingID, models.BookUpdate{}), apperr.ErrNotFound,
+		"пустое изменение несуществующей книги — тоже 404")
+
+	// удаление идемпотентно: удалять нечего — не ошибка
+	require.NoError(t, db.DeleteBookById(ctx, missingID))
+	require.NoError(t, db.DeleteBookById(ctx, id))
+	require.NoError(t, db.DeleteBookById(ctx, id))
+
+	after, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Equal(t, before.Version+1, after.Version, "версию сдвинуло только настоящее удаление")
+}
+
+func testBookValidation(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	authorID, genreID := catalog(t, ctx, db)
+
+	_, err := db.NewBook(ctx, models.Book{Name: "Без автора", Author_id: authorID + 100, Genre_id: genreID})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.Equal(t, "author_id", fieldOf(err))
+
+	_, err = db.NewBook(ctx, models.Book{Name: "Без жанра", Author_id: authorID, Genre_id: genreID + 100})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.Equal(t, "genre_id", fieldOf(err))
+
+	_, err = db.NewBook(ctx, models.Book{Name: "Дешёвая", Price: -1, Author_id: authorID, Genre_id: genreID})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.Equal(t, "price", fieldOf(err))
+
+	books, err := db.GetBooks(ctx)
+	require.NoError(t, err)
+	require.Empty(t, books, "отклонённые книги не сохраняются")
+
+	isbn := "978-5-389-06256-6"
+	first, err := db.NewBook(ctx, models.Book{Name: "Первая", Author_id: authorID, Genre_id: genreID, ISBN: isbn})
+	require.NoError(t, err)
+	_, err = db.NewBook(ctx, models.Book{Name: "Вторая", Author_id: authorID, Genre_id: genreID, ISBN: isbn})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+
+	// пустой ISBN уникальным не считается
+	_, err = db.NewBook(ctx, models.Book{Name: "Без ISBN 1", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	second, err := db.NewBook(ctx, models.Book{Name: "Без ISBN 2", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+
+	// неудачное изменение не меняет книгу даже частично
+	name := "Переименованная"
+	err = db.UpdateBook(ctx, second, models.BookUpdate{Name: &name, ISBN: &isbn})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	book, err := db.GetBookByID(ctx, second)
+	require.NoError(t, err)
+	require.Equal(t, "Без ISBN 2", book.Name)
+	require.Equal(t, "", book.ISBN)
+
+	err = db.UpdateBook(ctx, first, models.BookUpdate{ISBN: &isbn})
+	require.NoError(t, err, "книга не конфликтует сама с собой")
+
+	negative := -5
+	err = db.UpdateBook(ctx, first, models.BookUpdate{Name: &name, Price: &negative})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.Equal(t, "price", fieldOf(err))
+	book, err = db.GetBookByID(ctx, first)
+	require.NoError(t, err)
+	require.Equal(t, "Первая", book.Name)
+
+	// цена проверяется раньше, чем ищется книга
+	err = db.UpdateBook(ctx, missingID, models.BookUpdate{Price: &negative})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+func testTenantIsolation(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	otherID, err := db.NewTenant(ctx, models.Tenant{Slug: "isolation", Name: "Другая библиотека"}, nil)
+	require.NoError(t, err)
+	other := tenant.WithID(ctx, otherID)
+
+	authorID, genreID := catalog(t, ctx, db)
+	bookID, err := db.NewBook(ctx, models.Book{Name: "Война и мир", Author_id: authorID, Genre_id: genreID, ISBN: "978-5-17-090335-2"})
+	require.NoError(t, err)
+
+	authors, err := db.GetAllAuthors(other)
+	require.NoError(t, err)
+	require.Empty(t, authors)
+	genres, err := db.GetAllGenres(other)
+	require.NoError(t, err)
+	require.Empty(t, genres)
+	books, err := db.GetBooks(other)
+	require.NoError(t, err)
+	require.Empty(t, books)
+	withAuthors, err := db.GetAllWithAuthors(other)
+	require.NoError(t, err)
+	require.Empty(t, withAuthors)
+
+	// чужая книга для библиотеки не существует
+	_, err = db.GetBookByID(other, bookID)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	name := "Чужая"
+	require.ErrorIs(t, db.UpdateBook(other, bookID, models.BookUpdate{Name: &name}), apperr.ErrNotFound)
+	require.ErrorIs(t, db.UpdateBook(other, bookID, models.BookUpdate{}), apperr.ErrNotFound)
+	require.NoError(t, db.DeleteBookById(other, bookID))
+
+	// ссылки на чужих автора и жанр не проходят
+	_, err = db.NewBook(other, models.Book{Name: "Чужой автор", Author_id: authorID, Genre_id: genreID})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+
+	// жанр и ISBN уникальны только внутри библиотеки
+	otherAuthor, otherGenre := catalog(t, other, db)
+	_, err = db.NewBook(other, models.Book{Name: "Война и мир", Author_id: otherAuthor, Genre_id: otherGenre, ISBN: "978-5-17-090335-2"})
+	require.NoError(t, err)
+
+	book, err := db.GetBookByID(ctx, bookID)
+	require.NoError(t, err, "чужое удаление не трогает книгу")
+	require.Equal(t, "Война и мир", book.Name)
+}
+
+func testCatalogVersion(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+
+	// версия новой библиотеки — нулевая, пока каталог не менялся
+	otherID, err := db.NewTenant(ctx, models.Tenant{Slug: "versions", Name: "Другая библиотека"}, nil)
+	require.NoError(t, err)
+	other := tenant.WithID(ctx, otherID)
+	for _, collection := range []string{models.CollectionBooks, models.CollectionAuthors, models.CollectionGenres} {
+		v, err := db.CatalogVersion(other, collection)
+		require.NoError(t, err)
+		require.Zero(t, v.Version, collection)
+	}
+
+	books, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	genresBefore, err := db.CatalogVersion(ctx, models.CollectionGenres)
+	require.NoError(t, err)
+
+	authorID, err := db.NewAuthor(ctx, models.Author{Author: "Лев Толстой"})
+	require.NoError(t, err)
+	authors, err := db.CatalogVersion(ctx, models.CollectionAuthors)
+	require.NoError(t, err)
+	require.Positive(t, authors.Version)
+	require.WithinDuration(t, time.Now(), authors.UpdatedAt, time.Minute)
+
+	// коллекции версионируются независимо
+	genres, err := db.CatalogVersion(ctx, models.CollectionGenres)
+	require.NoError(t, err)
+	require.Equal(t, genresBefore.Version, genres.Version)
+	unchanged, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Equal(t, books.Version, unchanged.Version)
+
+	genreID, err := db.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+	genres, err = db.CatalogVersion(ctx, models.CollectionGenres)
+	require.NoError(t, err)
+	require.Greater(t, genres.Version, genresBefore.Version)
+
+	// неудачная запись версию не сдвигает
+	_, err = db.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	afterConflict, err := db.CatalogVersion(ctx, models.CollectionGenres)
+	require.NoError(t, err)
+	require.Equal(t, genres.Version, afterConflict.Version)
+
+	id, err := db.NewBook(ctx, models.Book{Name: "Война и мир", Author_id: authorID, Genre_id: genreID})
+	require.NoError(t, err)
+	created, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Greater(t, created.Version, books.Version)
+
+	_, err = db.NewBook(ctx, models.Book{Name: "Без автора", Author_id: missingID, Genre_id: genreID})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.NoError(t, db.UpdateBook(ctx, id, models.BookUpdate{}))
+	unchanged, err = db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Equal(t, created.Version, unchanged.Version, "ни отказ, ни пустое изменение не сдвигают версию")
+
+	price := 100
+	require.NoError(t, db.UpdateBook(ctx, id, models.BookUpdate{Price: &price}))
+	updated, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Greater(t, updated.Version, created.Version)
+
+	require.NoError(t, db.DeleteBookById(ctx, id))
+	deleted, err := db.CatalogVersion(ctx, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Greater(t, deleted.Version, updated.Version)
+	require.False(t, deleted.UpdatedAt.Before(updated.UpdatedAt))
+
+	// версия у каждой библиотеки своя
+	v, err := db.CatalogVersion(other, models.CollectionBooks)
+	require.NoError(t, err)
+	require.Zero(t, v.Version)
+}
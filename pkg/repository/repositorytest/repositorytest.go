@@ -0,0 +1,81 @@
+// Package repositorytest — общий набор контрактных тестов repository.DataBase.
+// Его прогоняют против каждого хранилища (fake, Postgres, SQLite), чтобы API
+// вело себя одинаково, какое бы хранилище ни стояло за ним.
+//
+// Эталон — PGRepo в продакшене: если хранилища расходятся, правят остальные.
+// Тесты фиксируют не только данные, но и вид ошибки (apperr.ErrNotFound,
+// ErrConflict, ErrValidation): по нему API выбирает код ответа.
+package repositorytest
+
+import (
+	"context"
+	"testing"
+
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/tenant"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Factory возвращает пустой каталог: без авторов, жанров и книг. Реестр
+// библиотек и пользователей может быть непустым (например, после миграций),
+// поэтому тесты создают записи с уникальными именами и не считают чужие.
+type Factory func(t *testing.T) repository.DataBase
+
+// Run прогоняет контрактные тесты; каждый подтест получает свежее хранилище.
+func Run(t *testing.T, newDB Factory) {
+	t.Run("Authors", func(t *testing.T) { testAuthors(t, newDB(t)) })
+	t.Run("Genres", func(t *testing.T) { testGenres(t, newDB(t)) })
+	t.Run("Books", func(t *testing.T) { testBooks(t, newDB(t)) })
+	t.Run("BookMissing", func(t *testing.T) { testBookMissing(t, newDB(t)) })
+	t.Run("BookValidation", func(t *testing.T) { testBookValidation(t, newDB(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newDB(t)) })
+	t.Run("CatalogVersion", func(t *testing.T) { testCatalogVersion(t, newDB(t)) })
+	t.Run("Tenants", func(t *testing.T) { testTenants(t, newDB(t)) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, newDB(t)) })
+	t.Run("ListUsers", func(t *testing.T) { testListUsers(t, newDB(t)) })
+	t.Run("DeleteUser", func(t *testing.T) { testDeleteUser(t, newDB(t)) })
+	t.Run("APIKeys", func(t *testing.T) { testAPIKeys(t, newDB(t)) })
+	t.Run("OAuth", func(t *testing.T) { testOAuth(t, newDB(t)) })
+	t.Run("MFA", func(t *testing.T) { testMFA(t, newDB(t)) })
+	t.Run("PasswordReset", func(t *testing.T) { testPasswordReset(t, newDB(t)) })
+	t.Run("References", func(t *testing.T) { testReferences(t, newDB(t)) })
+}
+
+// catalog создаёт автора и жанр в арендаторе из ctx.
+func catalog(t *testing.T, ctx context.Context, db repository.DataBase) (authorID, genreID int) {
+	t.Helper()
+	authorID, err := db.NewAuthor(ctx, models.Author{Author: "Лев Толстой"})
+	require.NoError(t, err)
+	genreID, err = db.NewGenre(ctx, models.Genre{Genre: "Роман"})
+	require.NoError(t, err)
+	return authorID, genreID
+}
+
+// newUser создаёт пользователя в библиотеке по умолчанию.
+func newUser(t *testing.T, db repository.DataBase, username string) int {
+	t.Helper()
+	id, err := db.NewUserWithIdentity(context.Background(), models.User{
+		Username: username,
+		Password: "hash-" + username,
+		Role:     "user",
+		Email:    username + "@example.com",
+		TenantID: tenant.DefaultID,
+	}, "https://idp.example.com", "sub-"+username)
+	require.NoError(t, err)
+	return id
+}
+
+// missingID — id, которого заведомо нет ни в одной таблице.
+const missingID = 1_000_000
+
+// fieldOf возвращает поле первой ошибки валидации или "".
+func fieldOf(err error) string {
+	fields := apperr.Fields(err)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0].Field
+}
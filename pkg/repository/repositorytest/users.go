@@ -0,0 +1,276 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/tenant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testTenants(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+
+	def, err := db.GetTenantBySlug(ctx, "default")
+	require.NoError(t, err)
+	require.Equal(t, tenant.DefaultID, def.ID)
+
+	admin := &models.User{Username: "branch-admin", Password: "hash", Role: "admin", Email: "branch-admin@example.com"}
+	id, err := db.NewTenant(ctx, models.Tenant{Slug: "branch", Name: "Филиал"}, admin)
+	require.NoError(t, err)
+	require.Greater(t, id, tenant.DefaultID)
+
+	got, err := db.GetTenantBySlug(ctx, "branch")
+	require.NoError(t, err)
+	require.Equal(t, id, got.ID)
+	require.Equal(t, "branch", got.Slug)
+	require.Equal(t, "Филиал", got.Name)
+	require.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+
+	user, err := db.GetUserByUsername(ctx, "branch-admin")
+	require.NoError(t, err)
+	require.Equal(t, id, user.TenantID, "администратор попадает в новую библиотеку, а не в библиотеку из ctx")
+	require.Equal(t, "admin", user.Role)
+	require.Equal(t, "branch-admin@example.com", user.Email)
+
+	// без администратора библиотека создаётся пустой
+	bare, err := db.NewTenant(ctx, models.Tenant{Slug: "bare", Name: "Без администратора"}, nil)
+	require.NoError(t, err)
+	users, total, err := db.ListUsers(ctx, models.UserFilter{TenantID: bare, Limit: 10})
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, users)
+
+	tenants, err := db.ListTenants(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(tenants), 3)
+	require.Equal(t, "default", tenants[0].Slug, "по возрастанию id")
+	for i := 1; i < len(tenants); i++ {
+		require.Greater(t, tenants[i].ID, tenants[i-1].ID)
+	}
+	require.Equal(t, "branch", tenants[len(tenants)-2].Slug)
+	require.Equal(t, "bare", tenants[len(tenants)-1].Slug)
+
+	_, err = db.NewTenant(ctx, models.Tenant{Slug: "branch", Name: "Ещё раз"}, nil)
+	require.ErrorIs(t, err, apperr.ErrConflict)
+
+	// администратор с занятым именем или почтой откатывает создание библиотеки
+	_, err = db.NewTenant(ctx, models.Tenant{Slug: "branch-2", Name: "Второй филиал"}, admin)
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = db.GetTenantBySlug(ctx, "branch-2")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	sameEmail := &models.User{Username: "branch-admin-2", Password: "hash", Role: "admin", Email: admin.Email}
+	_, err = db.NewTenant(ctx, models.Tenant{Slug: "branch-3", Name: "Третий филиал"}, sameEmail)
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = db.GetTenantBySlug(ctx, "branch-3")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = db.GetUserByUsername(ctx, "branch-admin-2")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	_, err = db.GetTenantBySlug(ctx, "missing")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func testUsers(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	id := newUser(t, db, "contract-alice")
+
+	byIdentity, err := db.GetUserByIdentity(ctx, "https://idp.example.com", "sub-contract-alice")
+	require.NoError(t, err)
+	require.Equal(t, id, byIdentity.ID)
+	_, err = db.GetUserByIdentity(ctx, "https://other-idp.example.com", "sub-contract-alice")
+	require.ErrorIs(t, err, apperr.ErrNotFound, "subject ищется только у своего провайдера")
+
+	user, err := db.GetUserByUsername(ctx, "contract-alice")
+	require.NoError(t, err)
+	require.Equal(t, id, user.ID)
+	require.Equal(t, "contract-alice", user.Username)
+	require.Equal(t, "hash-contract-alice", user.Password)
+	require.Equal(t, "user", user.Role)
+	require.Equal(t, "contract-alice@example.com", user.Email)
+	require.Equal(t, tenant.DefaultID, user.TenantID)
+	require.Nil(t, user.DisabledAt)
+	require.WithinDuration(t, time.Now(), user.CreatedAt, time.Minute)
+
+	_, err = db.GetUserByUsername(ctx, "CONTRACT-ALICE")
+	require.ErrorIs(t, err, apperr.ErrNotFound, "имя пользователя сравнивается с учётом регистра")
+
+	// занятые имя, почта и привязка к провайдеру
+	_, err = db.NewUserWithIdentity(ctx, models.User{Username: "contract-alice", Password: "x", Role: "user", TenantID: tenant.DefaultID},
+		"https://idp.example.com", "sub-other")
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = db.NewUserWithIdentity(ctx, models.User{Username: "contract-carol", Password: "x", Role: "user", Email: "contract-alice@example.com", TenantID: tenant.DefaultID},
+		"https://idp.example.com", "sub-contract-carol")
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = db.NewUserWithIdentity(ctx, models.User{Username: "contract-bob", Password: "x", Role: "user", TenantID: tenant.DefaultID},
+		"https://idp.example.com", "sub-contract-alice")
+	require.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = db.GetUserByUsername(ctx, "contract-bob")
+	require.ErrorIs(t, err, apperr.ErrNotFound, "пользователь и привязка создаются одной транзакцией")
+
+	// пустая почта не уникальна
+	noEmail1, err := db.NewUserWithIdentity(ctx, models.User{Username: "contract-no-email-1", Role: "user", TenantID: tenant.DefaultID},
+		"https://idp.example.com", "sub-no-email-1")
+	require.NoError(t, err)
+	_, err = db.NewUserWithIdentity(ctx, models.User{Username: "contract-no-email-2", Role: "user", TenantID: tenant.DefaultID},
+		"https://idp.example.com", "sub-no-email-2")
+	require.NoError(t, err)
+	user, err = db.GetUserByID(ctx, noEmail1)
+	require.NoError(t, err)
+	require.Equal(t, "", user.Email)
+
+	// арендатор должен существовать, нулевой — тоже нет
+	for _, tenantID := range []int{0, missingID} {
+		_, err = db.NewUserWithIdentity(ctx, models.User{Username: "contract-orphan", Role: "user", TenantID: tenantID},
+			"https://idp.example.com", "sub-contract-orphan")
+		require.ErrorIs(t, err, apperr.ErrValidation, "tenant %d", tenantID)
+	}
+
+	require.NoError(t, db.UpdateUserRole(ctx, id, "admin"))
+	user, err = db.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "admin", user.Role)
+
+	version := user.TokenVersion
+	require.NoError(t, db.UpdateUserPassword(ctx, id, "new-hash"))
+	user, err = db.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", user.Password)
+	require.Equal(t, version+1, user.TokenVersion)
+
+	require.NoError(t, db.UpgradePasswordHash(ctx, id, "stale-hash", "ignored"))
+	require.NoError(t, db.UpgradePasswordHash(ctx, id, "new-hash", "rehashed"))
+	user, err = db.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "rehashed", user.Password)
+	require.Equal(t, version+1, user.TokenVersion, "перехэширование не сбрасывает сессии")
+	require.NoError(t, db.UpgradePasswordHash(ctx, missingID, "rehashed", "x"), "пароль мог смениться или пользователь — удалиться")
+
+	disabledAt := time.Now()
+	require.NoError(t, db.SetUserDisabled(ctx, id, &disabledAt))
+	user, err = db.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, user.DisabledAt)
+	require.WithinDuration(t, disabledAt, *user.DisabledAt, time.Second)
+	require.NoError(t, db.SetUserDisabled(ctx, id, nil))
+	user, err = db.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.Nil(t, user.DisabledAt)
+
+	_, err = db.GetUserByID(ctx, missingID)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	require.ErrorIs(t, db.UpdateUserRole(ctx, missingID, "user"), apperr.ErrNotFound)
+	require.ErrorIs(t, db.UpdateUserPassword(ctx, missingID, "x"), apperr.ErrNotFound)
+	require.ErrorIs(t, db.SetUserDisabled(ctx, missingID, nil), apperr.ErrNotFound)
+	require.ErrorIs(t, db.DeleteUser(ctx, missingID), apperr.ErrNotFound)
+}
+
+func testListUsers(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	alice := newUser(t, db, "list-alice")
+	bob := newUser(t, db, "list-bob")
+	carol := newUser(t, db, "list_carol")
+	branchID, err := db.NewTenant(ctx, models.Tenant{Slug: "list-branch", Name: "Филиал"},
+		&models.User{Username: "list-admin", Password: "hash", Role: "admin", Email: "admin@list.example.com"})
+	require.NoError(t, err)
+
+	users, total, err := db.ListUsers(ctx, models.UserFilter{Search: "LIST", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 4, total, "поиск без учёта регистра, по всем библиотекам")
+	require.Len(t, users, 4)
+	require.Equal(t, alice, users[0].ID, "по возрастанию id")
+	require.Equal(t, bob, users[1].ID)
+	require.Equal(t, carol, users[2].ID)
+	require.Equal(t, "list-admin", users[3].Username)
+
+	// страница не меняет общее число
+	users, total, err = db.ListUsers(ctx, models.UserFilter{Search: "list", Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Equal(t, 4, total)
+	require.Len(t, users, 2)
+	require.Equal(t, bob, users[0].ID)
+	require.Equal(t, carol, users[1].ID)
+
+	users, total, err = db.ListUsers(ctx, models.UserFilter{Search: "list", Limit: 10, Offset: 10})
+	require.NoError(t, err)
+	require.Equal(t, 4, total)
+	require.NotNil(t, users)
+	require.Empty(t, users)
+
+	users, total, err = db.ListUsers(ctx, models.UserFilter{Search: "list", Limit: 0})
+	require.NoError(t, err)
+	require.Equal(t, 4, total)
+	require.Empty(t, users, "нулевой лимит — пустая страница")
+
+	// ищется и по почте
+	users, total, err = db.ListUsers(ctx, models.UserFilter{Search: "@list.example", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "list-admin", users[0].Username)
+
+	// % и _ ищутся буквально, а не как шаблоны LIKE
+	users, total, err = db.ListUsers(ctx, models.UserFilter{Search: "list_", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, carol, users[0].ID)
+	_, total, err = db.ListUsers(ctx, models.UserFilter{Search: "%", Limit: 10})
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	users, total, err = db.ListUsers(ctx, models.UserFilter{Search: "list", TenantID: branchID, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "list-admin", users[0].Username)
+	require.Equal(t, branchID, users[0].TenantID)
+
+	// пустой поиск совпадает со всеми
+	_, all, err := db.ListUsers(ctx, models.UserFilter{Limit: 10})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, all, 4)
+}
+
+// testDeleteUser фиксирует, что вместе с пользователем удаляется всё, что на него ссылается.
+func testDeleteUser(t *testing.T, db repository.DataBase) {
+	ctx := context.Background()
+	id := newUser(t, db, "contract-deleted")
+	keep := newUser(t, db, "contract-kept")
+
+	_, err := db.NewAPIKey(ctx, models.APIKey{UserID: id, Name: "импорт", Prefix: "lk_deleted", Hash: "hash", Scopes: []string{}})
+	require.NoError(t, err)
+	_, err = db.NewAPIKey(ctx, models.APIKey{UserID: keep, Name: "импорт", Prefix: "lk_kept", Hash: "hash", Scopes: []string{}})
+	require.NoError(t, err)
+	require.NoError(t, db.SetTOTPSecret(ctx, id, "secret"))
+	require.NoError(t, db.EnableTOTP(ctx, id, []string{"code"}))
+	require.NoError(t, db.NewPasswordResetToken(ctx, models.PasswordResetToken{TokenHash: "deleted-reset", UserID: id, ExpiresAt: time.Now().Add(time.Hour)}))
+
+	require.NoError(t, db.DeleteUser(ctx, id))
+
+	_, err = db.GetUserByID(ctx, id)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = db.GetUserByUsername(ctx, "contract-deleted")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = db.GetUserByIdentity(ctx, "https://idp.example.com", "sub-contract-deleted")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = db.GetAPIKeyByPrefix(ctx, "lk_deleted")
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = db.GetTOTP(ctx, id)
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+	codes, err := db.ListRecoveryCodes(ctx, id)
+	require.NoError(t, err)
+	require.Empty(t, codes)
+	_, err = db.ConsumePasswordResetToken(ctx, "deleted-reset", time.Now())
+	require.ErrorIs(t, err, apperr.ErrNotFound)
+
+	_, err = db.GetAPIKeyByPrefix(ctx, "lk_kept")
+	require.NoError(t, err, "чужие данные не трогаются")
+
+	require.ErrorIs(t, db.DeleteUser(ctx, id), apperr.ErrNotFound, "повторное удаление")
+
+	// освободившиеся имя и привязка снова доступны
+	newUser(t, db, "contract-deleted")
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"time"
+)
+
+func (repo *SQLiteRepo) NewAPIKey(ctx context.Context, key models.APIKey) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id;
+	`,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		stringList(key.Scopes),
+		utcPtr(key.ExpiresAt),
+	).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
+
+func (repo *SQLiteRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var key models.APIKey
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE prefix = $1;
+	`, prefix).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		listOf(&key.Scopes),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+func (repo *SQLiteRepo) ListAPIKeys(ctx context.Context, tenantID int) ([]models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.revoked_at, k.created_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE u.tenant_id = $1
+		ORDER BY k.id;
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			listOf(&key.Scopes),
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (repo *SQLiteRepo) RevokeAPIKey(ctx context.Context, tenantID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return execAffected(ctx, repo.db, apperr.NotFound("api key with id %d not found", id), `
+		UPDATE api_keys SET revoked_at = $2
+		WHERE id = $1 AND revoked_at IS NULL
+			AND user_id IN (SELECT id FROM users WHERE tenant_id = $3);
+	`, id, utc(time.Now()), tenantID)
+}
+
+func (repo *SQLiteRepo) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1;
+	`, id, utc(usedAt))
+	return err
+}
@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+)
+
+func (repo *SQLiteRepo) GetAllAuthors(ctx context.Context) ([]models.Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, author, tenant_id
+		FROM authors
+		WHERE tenant_id = $1
+		ORDER BY id
+	`, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := []models.Author{}
+	for rows.Next() {
+		var author models.Author
+		if err := rows.Scan(&author.ID, &author.Author, &author.TenantID); err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, rows.Err()
+}
+
+func (repo *SQLiteRepo) NewAuthor(ctx context.Context, author models.Author) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.db.QueryRowContext(ctx, `
+		INSERT INTO authors (author, tenant_id)
+		VALUES ($1, $2)
+		RETURNING id;
+	`, author.Author, tenant.FromContext(ctx)).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"strings"
+)
+
+func (repo *SQLiteRepo) GetBooks(ctx context.Context) ([]models.Book, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, name, author_id, genre_id, price, COALESCE(isbn, ''), tenant_id, updated_at
+		FROM books
+		WHERE author_id IS NOT NULL AND genre_id IS NOT NULL AND tenant_id = $1
+		ORDER BY id;
+	`, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []models.Book{}
+	for rows.Next() {
+		var item models.Book
+		if err := rows.Scan(&item.ID, &item.Name, &item.Author_id, &item.Genre_id, &item.Price, &item.ISBN, &item.TenantID, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		data = append(data, item)
+	}
+	return data, rows.Err()
+}
+
+func (repo *SQLiteRepo) NewBook(ctx context.Context, item models.Book) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	tenantID := tenant.FromContext(ctx)
+	var id int
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		// SQLite не сообщает, какой внешний ключ нарушен, поэтому ссылки
+		// проверяются заранее — с теми же ошибками, что у PGRepo
+		if !exists(ctx, tx, `SELECT 1 FROM authors WHERE tenant_id = $1 AND id = $2`, tenantID, item.Author_id) {
+			return apperr.Field("author_id", "author does not exist")
+		}
+		if !exists(ctx, tx, `SELECT 1 FROM genres WHERE tenant_id = $1 AND id = $2`, tenantID, item.Genre_id) {
+			return apperr.Field("genre_id", "genre does not exist")
+		}
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO books (name, author_id, genre_id, price, isbn, tenant_id)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+			RETURNING id;
+		`,
+			item.Name,
+			item.Author_id,
+			item.Genre_id,
+			item.Price,
+			item.ISBN,
+			tenantID,
+		).Scan(&id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// exists сообщает, вернул ли запрос хотя бы одну строку.
+func exists(ctx context.Context, tx *sql.Tx, query string, args ...any) bool {
+	var one int
+	return tx.QueryRowContext(ctx, query, args...).Scan(&one) == nil
+}
+
+func (repo *SQLiteRepo) GetBookByID(ctx context.Context, id int) (models.Book, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var book models.Book
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT id, name, author_id, genre_id, price, COALESCE(isbn, ''), tenant_id, updated_at
+		FROM books
+		WHERE author_id IS NOT NULL AND genre_id IS NOT NULL AND id = $1 AND tenant_id = $2;
+	`, id, tenant.FromContext(ctx)).Scan(
+		&book.ID,
+		&book.Name,
+		&book.Author_id,
+		&book.Genre_id,
+		&book.Price,
+		&book.ISBN,
+		&book.TenantID,
+		&book.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Book{}, apperr.NotFound("book with id %d not found", id)
+	}
+	if err != nil {
+		return models.Book{}, err
+	}
+	return book, nil
+}
+
+func (repo *SQLiteRepo) DeleteBookById(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `
+		DELETE FROM books
+		WHERE id = $1 AND tenant_id = $2;
+	`, id, tenant.FromContext(ctx))
+	return err
+}
+
+func (repo *SQLiteRepo) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuthor, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT b.id, b.name, b.price, b.genre_id, b.author_id, a.author
+		FROM books b
+		JOIN authors a ON b.tenant_id = a.tenant_id AND b.author_id = a.id
+		WHERE b.tenant_id = $1
+		ORDER BY b.id
+	`, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []models.BookWithAuthor{}
+	for rows.Next() {
+		var b models.BookWithAuthor
+		if err := rows.Scan(&b.ID, &b.Name, &b.Price, &b.GenreID, &b.AuthorID, &b.AuthorName); err != nil {
+			return nil, err
+		}
+		books = append(books, b)
+	}
+	return books, rows.Err()
+}
+
+func (repo *SQLiteRepo) UpdateBook(ctx context.Context, id int, update models.BookUpdate) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var setParts []string
+	var args []interface{}
+	args = append(args, id, tenant.FromContext(ctx)) //$1 = book ID, $2 = арендатор
+
+	argIndex := 3 //следующие параметры: $3, $4...
+
+	if update.Name != nil {
+		setParts = append(setParts, fmt.Sprintf("name = $%d", argIndex))
+		args = append(args, *update.Name)
+		argIndex++
+	}
+
+	if update.Price != nil {
+		if *update.Price < 0 {
+			return apperr.Field("price", "must be non-negative")
+		}
+		setParts = append(setParts, fmt.Sprintf("price = $%d", argIndex))
+		args = append(args, *update.Price)
+		argIndex++
+	}
+
+	if update.ISBN != nil {
+		setParts = append(setParts, fmt.Sprintf("isbn = NULLIF($%d, '')", argIndex))
+		args = append(args, *update.ISBN)
+	}
+
+	// менять нечего, но о несуществующей книге всё равно сообщаем
+	if len(setParts) == 0 {
+		_, err := repo.GetBookByID(ctx, id)
+		return err
+	}
+
+	query := fmt.Sprintf(
+		"UPDATE books SET %s WHERE id = $1 AND tenant_id = $2",
+		strings.Join(setParts, ", "),
+	)
+
+	result, err := repo.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return apperr.NotFound("book with id %d not found", id)
+	}
+	return nil
+}
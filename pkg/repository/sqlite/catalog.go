@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+)
+
+// CatalogVersion читает счётчик, который ведут триггеры миграции 000017.
+func (repo *SQLiteRepo) CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var v models.CatalogVersion
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT version, updated_at
+		FROM catalog_versions
+		WHERE tenant_id = $1 AND collection = $2;
+	`, tenant.FromContext(ctx), collection).Scan(&v.Version, &v.UpdatedAt)
+	// коллекцию этой библиотеки ещё не меняли
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CatalogVersion{}, nil
+	}
+	if err != nil {
+		return models.CatalogVersion{}, err
+	}
+	return v, nil
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"leti/pkg/apperr"
+	"strings"
+
+	sqlitedrv "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// constraintFields связывает нарушенные ограничения с полями запроса, как в
+// postgres.translateError. SQLite называет в ошибке не имя ограничения, а
+// столбцы уникального ключа ("genres.tenant_id, genres.genre") или имя CHECK.
+// Какой внешний ключ нарушен, SQLite не сообщает: ссылки книги на автора и
+// жанр NewBook проверяет сам.
+var constraintFields = map[string]apperr.FieldError{
+	"books_price_check":              {Field: "price", Message: "must be non-negative"},
+	"books.tenant_id, books.isbn":    {Field: "isbn", Message: "a book with this ISBN already exists"},
+	"genres.tenant_id, genres.genre": {Field: "genre", Message: "genre already exists"},
+	"users.username":                 {Field: "username", Message: "username already taken"},
+	"users.email":                    {Field: "email", Message: "email already taken"},
+	"tenants.slug":                   {Field: "slug", Message: "slug already taken"},
+}
+
+// translateError превращает ошибки SQLite в ошибки apperr. Исходная ошибка
+// сохраняется в цепочке для логов.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.Wrap(apperr.ErrNotFound, err, "record not found")
+	}
+
+	var sqliteErr *sqlitedrv.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	field, known := constraintFields[constraintDetail(sqliteErr.Error())]
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		msg := "record already exists"
+		if known {
+			msg = field.Message
+		}
+		return &apperr.Error{Kind: apperr.ErrConflict, Msg: msg, Err: err}
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY, sqlite3.SQLITE_CONSTRAINT_CHECK:
+		if !known {
+			return apperr.Wrap(apperr.ErrValidation, err, "invalid reference or value")
+		}
+		return &apperr.Error{
+			Kind:   apperr.ErrValidation,
+			Msg:    field.Field + ": " + field.Message,
+			Fields: []apperr.FieldError{field},
+			Err:    err,
+		}
+	}
+	return err
+}
+
+// constraintDetail достаёт из текста ошибки то, что SQLite пишет после
+// "constraint failed: ": "constraint failed: UNIQUE constraint failed: users.username (2067)"
+// -> "users.username".
+func constraintDetail(msg string) string {
+	const marker = "constraint failed: "
+	i := strings.LastIndex(msg, marker)
+	if i < 0 {
+		return ""
+	}
+	detail := msg[i+len(marker):]
+	if j := strings.LastIndex(detail, " ("); j >= 0 {
+		detail = detail[:j]
+	}
+	return detail
+}
@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+)
+
+func (repo *SQLiteRepo) GetAllGenres(ctx context.Context) ([]models.Genre, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, genre, tenant_id
+		FROM genres
+		WHERE tenant_id = $1
+		ORDER BY id
+	`, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(&genre.ID, &genre.Genre, &genre.TenantID); err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+	return genres, rows.Err()
+}
+
+func (repo *SQLiteRepo) NewGenre(ctx context.Context, newGenre models.Genre) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.db.QueryRowContext(ctx, `
+		INSERT INTO genres (genre, tenant_id)
+		VALUES ($1, $2)
+		RETURNING id;
+	`, newGenre.Genre, tenant.FromContext(ctx)).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
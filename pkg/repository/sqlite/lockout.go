@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"leti/pkg/lockout"
+	"leti/pkg/models"
+	"time"
+)
+
+// SQLiteRepo реализует lockout.Store: счётчики переживают перезапуск сервиса.
+var _ lockout.Store = (*SQLiteRepo)(nil)
+
+func (repo *SQLiteRepo) GetLoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	state := models.LoginAttempts{Key: key}
+	var lockedUntil *time.Time
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1;
+	`, key).Scan(&state.Failures, &state.LastFailureAt, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if lockedUntil != nil {
+		state.LockedUntil = *lockedUntil
+	}
+	return state, nil
+}
+
+func (repo *SQLiteRepo) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var failures int
+	err := repo.db.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1
+			                ELSE login_attempts.failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures;
+	`, key, utc(now), utc(since)).Scan(&failures)
+	return failures, err
+}
+
+func (repo *SQLiteRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `
+		UPDATE login_attempts SET locked_until = $2
+		WHERE key = $1;
+	`, key, utc(until))
+	return err
+}
+
+func (repo *SQLiteRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1;`, key)
+	return err
+}
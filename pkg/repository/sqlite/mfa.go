@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"time"
+)
+
+func (repo *SQLiteRepo) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var totp models.TOTP
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT user_id, secret, enabled, last_step
+		FROM user_totp
+		WHERE user_id = $1;
+	`, userID).Scan(&totp.UserID, &totp.Secret, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &totp, nil
+}
+
+func (repo *SQLiteRepo) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret, enabled, last_step)
+		VALUES ($1, $2, false, 0)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled = false, last_step = 0;
+	`, userID, secret)
+	return translateError(err)
+}
+
+func (repo *SQLiteRepo) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		err := execAffected(ctx, tx, apperr.NotFound("totp for user %d not found", userID), `
+			UPDATE user_totp SET enabled = true
+			WHERE user_id = $1;
+		`, userID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO user_recovery_codes (user_id, code_hash)
+				VALUES ($1, $2);
+			`, userID, hash)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (repo *SQLiteRepo) DisableTOTP(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1;`, userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1;`, userID)
+		return err
+	})
+}
+
+func (repo *SQLiteRepo) AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.db.ExecContext(ctx, `
+		UPDATE user_totp SET last_step = $2
+		WHERE user_id = $1 AND last_step < $2;
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (repo *SQLiteRepo) ListRecoveryCodes(ctx context.Context, userID int) ([]models.RecoveryCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, user_id, code_hash
+		FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+		ORDER BY id;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []models.RecoveryCode{}
+	for rows.Next() {
+		var code models.RecoveryCode
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (repo *SQLiteRepo) UseRecoveryCode(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	result, err := repo.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL;
+	`, id, utc(time.Now()))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (repo *SQLiteRepo) GetMFARequiredRoles(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `SELECT role FROM mfa_required_roles ORDER BY role;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (repo *SQLiteRepo) SetMFARequiredRoles(ctx context.Context, roles []string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_required_roles;`); err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO mfa_required_roles (role) VALUES ($1);`, role); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
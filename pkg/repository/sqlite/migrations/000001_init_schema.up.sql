@@ -0,0 +1,19 @@
+-- Создаем таблицы. SERIAL в SQLite — INTEGER PRIMARY KEY AUTOINCREMENT (id не
+-- переиспользуются); ограничения названы как в Postgres, по имени translateError находит поле.
+CREATE TABLE IF NOT EXISTS authors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    author VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS genres (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    genre VARCHAR(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS books (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(200) NOT NULL,
+    author_id INTEGER REFERENCES authors(id),
+    genre_id INTEGER REFERENCES genres(id),
+    price INTEGER NOT NULL CONSTRAINT books_price_check CHECK (price >= 0)
+);
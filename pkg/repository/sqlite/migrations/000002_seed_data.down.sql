@@ -0,0 +1,4 @@
+-- Очищаем тестовые данные
+DELETE FROM books;
+DELETE FROM authors;
+DELETE FROM genres;
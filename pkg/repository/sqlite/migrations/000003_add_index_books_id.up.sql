@@ -0,0 +1,2 @@
+-- CONCURRENTLY в SQLite нет: база одна на процесс, строить индекс не мешая некому
+CREATE INDEX idx_id ON books (id);
@@ -0,0 +1,2 @@
+CREATE INDEX authors_id ON authors (id);
+CREATE INDEX genres_id ON genres (id);
@@ -0,0 +1,2 @@
+INSERT INTO users (username, password, role) VALUES
+('Den', '$2a$10$PwpqIpesHQr7S66hrZGkzumVjFuwZwU2l9Eq084Ctk0ZNZeHla3ni', 'admin');
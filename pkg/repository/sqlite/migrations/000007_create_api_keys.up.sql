@@ -0,0 +1,15 @@
+-- Массивов в SQLite нет: scopes хранится JSON-массивом строк (см. stringList)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '[]',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX user_recovery_codes_user_id ON user_recovery_codes (user_id);
+
+-- Роли, для которых вход без второго фактора запрещён
+CREATE TABLE IF NOT EXISTS mfa_required_roles (
+    role VARCHAR(20) PRIMARY KEY
+);
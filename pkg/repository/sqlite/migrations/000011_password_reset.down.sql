@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS password_reset_tokens;
+ALTER TABLE users DROP COLUMN token_version;
+DROP INDEX IF EXISTS users_email_key;
+ALTER TABLE users DROP COLUMN email;
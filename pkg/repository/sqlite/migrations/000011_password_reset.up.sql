@@ -0,0 +1,16 @@
+-- email нужен для доставки ссылки сброса пароля;
+-- token_version увеличивается при смене пароля и отзывает все выданные токены.
+-- ADD COLUMN в SQLite не принимает UNIQUE, поэтому уникальность — отдельным индексом.
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+CREATE UNIQUE INDEX users_email_key ON users (email);
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX password_reset_tokens_user_id ON password_reset_tokens (user_id);
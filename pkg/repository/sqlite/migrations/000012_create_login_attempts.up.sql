@@ -0,0 +1,7 @@
+-- Счётчики неудачных входов; key — 'user:<имя>' или 'ip:<адрес>'
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(300) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
@@ -0,0 +1,36 @@
+DROP INDEX IF EXISTS users_tenant_id;
+
+-- Обратное пересоздание таблиц, как в 000014_multi_tenant.up.sql
+CREATE TABLE books_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(200) NOT NULL,
+    author_id INTEGER REFERENCES authors(id),
+    genre_id INTEGER REFERENCES genres(id),
+    price INTEGER NOT NULL CONSTRAINT books_price_check CHECK (price >= 0)
+);
+INSERT INTO books_old (id, name, author_id, genre_id, price) SELECT id, name, author_id, genre_id, price FROM books;
+DROP TABLE books;
+ALTER TABLE books_old RENAME TO books;
+CREATE INDEX idx_id ON books (id);
+
+CREATE TABLE genres_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    genre VARCHAR(50) NOT NULL UNIQUE
+);
+INSERT INTO genres_old (id, genre) SELECT id, genre FROM genres;
+DROP TABLE genres;
+ALTER TABLE genres_old RENAME TO genres;
+CREATE INDEX genres_id ON genres (id);
+
+CREATE TABLE authors_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    author VARCHAR(100) NOT NULL
+);
+INSERT INTO authors_old (id, author) SELECT id, author FROM authors;
+DROP TABLE authors;
+ALTER TABLE authors_old RENAME TO authors;
+CREATE INDEX authors_id ON authors (id);
+
+ALTER TABLE users DROP COLUMN tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(200) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Всё, что уже есть в базе, принадлежит арендатору по умолчанию
+INSERT OR IGNORE INTO tenants (id, slug, name) VALUES (1, 'default', 'Default library');
+
+ALTER TABLE users ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+
+-- ALTER TABLE в SQLite не добавляет ограничений к существующим столбцам,
+-- поэтому таблицы каталога пересоздаются. Внешние ключи на время миграций
+-- выключены (см. migrateUp), иначе DROP TABLE проверял бы ссылки на удаляемую таблицу.
+-- Значения tenant_id по умолчанию нет: арендатора из контекста передаёт SQLiteRepo.
+CREATE TABLE authors_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    author VARCHAR(100) NOT NULL,
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id),
+    CONSTRAINT authors_tenant_id_id_key UNIQUE (tenant_id, id)
+);
+INSERT INTO authors_new (id, author, tenant_id) SELECT id, author, 1 FROM authors;
+DROP TABLE authors;
+ALTER TABLE authors_new RENAME TO authors;
+CREATE INDEX authors_id ON authors (id);
+
+CREATE TABLE genres_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    genre VARCHAR(50) NOT NULL,
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id),
+    -- Жанр уникален в пределах библиотеки
+    CONSTRAINT genres_tenant_genre_key UNIQUE (tenant_id, genre),
+    CONSTRAINT genres_tenant_id_id_key UNIQUE (tenant_id, id)
+);
+INSERT INTO genres_new (id, genre, tenant_id) SELECT id, genre, 1 FROM genres;
+DROP TABLE genres;
+ALTER TABLE genres_new RENAME TO genres;
+CREATE INDEX genres_id ON genres (id);
+
+-- Книга может ссылаться только на автора и жанр своей библиотеки
+CREATE TABLE books_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(200) NOT NULL,
+    author_id INTEGER,
+    genre_id INTEGER,
+    price INTEGER NOT NULL CONSTRAINT books_price_check CHECK (price >= 0),
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id),
+    CONSTRAINT books_author_fkey FOREIGN KEY (tenant_id, author_id) REFERENCES authors (tenant_id, id),
+    CONSTRAINT books_genre_fkey FOREIGN KEY (tenant_id, genre_id) REFERENCES genres (tenant_id, id)
+);
+INSERT INTO books_new (id, name, author_id, genre_id, price, tenant_id)
+SELECT id, name, author_id, genre_id, price, 1 FROM books;
+DROP TABLE books;
+ALTER TABLE books_new RENAME TO books;
+CREATE INDEX idx_id ON books (id);
+
+CREATE INDEX books_tenant_id ON books (tenant_id);
+CREATE INDEX users_tenant_id ON users (tenant_id);
+
+-- Row-level security в SQLite нет: строки арендатора отбирает каждый запрос SQLiteRepo.
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS books_tenant_isbn_key;
+ALTER TABLE books DROP COLUMN isbn;
@@ -0,0 +1,11 @@
+-- Корзины ограничителя частоты запросов, как в Postgres. Сервис с storage: sqlite
+-- работает одним процессом и держит корзины в памяти; таблица нужна, чтобы схемы
+-- не расходились. Функции rate_limit_refill нет: в SQLite нет SQL-функций.
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(300) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_idx ON rate_limits (updated_at);
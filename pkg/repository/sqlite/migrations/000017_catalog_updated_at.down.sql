@@ -0,0 +1,21 @@
+DROP TRIGGER IF EXISTS books_bump_catalog_version_delete;
+DROP TRIGGER IF EXISTS books_bump_catalog_version_update;
+DROP TRIGGER IF EXISTS books_bump_catalog_version_insert;
+DROP TRIGGER IF EXISTS genres_bump_catalog_version_delete;
+DROP TRIGGER IF EXISTS genres_bump_catalog_version_update;
+DROP TRIGGER IF EXISTS genres_bump_catalog_version_insert;
+DROP TRIGGER IF EXISTS authors_bump_catalog_version_delete;
+DROP TRIGGER IF EXISTS authors_bump_catalog_version_update;
+DROP TRIGGER IF EXISTS authors_bump_catalog_version_insert;
+DROP TABLE IF EXISTS catalog_versions;
+
+DROP TRIGGER IF EXISTS books_set_updated_at;
+DROP TRIGGER IF EXISTS genres_set_updated_at;
+DROP TRIGGER IF EXISTS authors_set_updated_at;
+DROP TRIGGER IF EXISTS books_insert_updated_at;
+DROP TRIGGER IF EXISTS genres_insert_updated_at;
+DROP TRIGGER IF EXISTS authors_insert_updated_at;
+
+ALTER TABLE books DROP COLUMN updated_at;
+ALTER TABLE genres DROP COLUMN updated_at;
+ALTER TABLE authors DROP COLUMN updated_at;
@@ -0,0 +1,139 @@
+-- Время последнего изменения строк каталога. ADD COLUMN в SQLite принимает только
+-- постоянное значение по умолчанию: существующие строки получают время UPDATE ниже,
+-- новые — триггер *_insert_updated_at. Время пишется в UTC с миллисекундами
+-- ("YYYY-MM-DD HH:MM:SS.SSS"), так что строки сравниваются в хронологическом порядке.
+ALTER TABLE authors ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE genres ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE books ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE authors SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+UPDATE genres SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+UPDATE books SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+CREATE TRIGGER authors_insert_updated_at AFTER INSERT ON authors FOR EACH ROW
+BEGIN
+    UPDATE authors SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER genres_insert_updated_at AFTER INSERT ON genres FOR EACH ROW
+BEGIN
+    UPDATE genres SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER books_insert_updated_at AFTER INSERT ON books FOR EACH ROW
+BEGIN
+    UPDATE books SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+
+-- Как set_updated_at в Postgres: время не идёт назад. Триггер срабатывает только
+-- на столбцы данных, поэтому его собственный UPDATE не вызывает его снова.
+CREATE TRIGGER authors_set_updated_at AFTER UPDATE OF author ON authors FOR EACH ROW
+BEGIN
+    UPDATE authors SET updated_at = max(OLD.updated_at, strftime('%Y-%m-%d %H:%M:%f', 'now')) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER genres_set_updated_at AFTER UPDATE OF genre ON genres FOR EACH ROW
+BEGIN
+    UPDATE genres SET updated_at = max(OLD.updated_at, strftime('%Y-%m-%d %H:%M:%f', 'now')) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER books_set_updated_at AFTER UPDATE OF name, author_id, genre_id, price, isbn ON books FOR EACH ROW
+BEGIN
+    UPDATE books SET updated_at = max(OLD.updated_at, strftime('%Y-%m-%d %H:%M:%f', 'now')) WHERE id = NEW.id;
+END;
+
+-- Версия коллекции целиком: max(updated_at) по строкам не замечает удалений,
+-- поэтому каждое изменение строки увеличивает счётчик коллекции. Запись в SQLite
+-- одна на всю базу, так что версии упорядочены так же, как коммиты.
+CREATE TABLE IF NOT EXISTS catalog_versions (
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id),
+    collection VARCHAR(20) NOT NULL,
+    version BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tenant_id, collection)
+);
+
+INSERT OR IGNORE INTO catalog_versions (tenant_id, collection, version, updated_at)
+SELECT t.id, c.collection, 1, strftime('%Y-%m-%d %H:%M:%f', 'now')
+FROM tenants t CROSS JOIN (SELECT 'authors' AS collection UNION ALL SELECT 'genres' UNION ALL SELECT 'books') c;
+
+-- bump_catalog_version из Postgres: у триггера SQLite одно событие, поэтому их по три на таблицу
+CREATE TRIGGER authors_bump_catalog_version_insert AFTER INSERT ON authors FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (NEW.tenant_id, 'authors', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER authors_bump_catalog_version_update AFTER UPDATE OF author ON authors FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (NEW.tenant_id, 'authors', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER authors_bump_catalog_version_delete AFTER DELETE ON authors FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (OLD.tenant_id, 'authors', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER genres_bump_catalog_version_insert AFTER INSERT ON genres FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (NEW.tenant_id, 'genres', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER genres_bump_catalog_version_update AFTER UPDATE OF genre ON genres FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (NEW.tenant_id, 'genres', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER genres_bump_catalog_version_delete AFTER DELETE ON genres FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (OLD.tenant_id, 'genres', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER books_bump_catalog_version_insert AFTER INSERT ON books FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (NEW.tenant_id, 'books', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER books_bump_catalog_version_update AFTER UPDATE OF name, author_id, genre_id, price, isbn ON books FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (NEW.tenant_id, 'books', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
+
+CREATE TRIGGER books_bump_catalog_version_delete AFTER DELETE ON books FOR EACH ROW
+BEGIN
+    INSERT INTO catalog_versions (tenant_id, collection, version, updated_at)
+    VALUES (OLD.tenant_id, 'books', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ON CONFLICT (tenant_id, collection) DO UPDATE
+    SET version = version + 1,
+        updated_at = max(updated_at, excluded.updated_at);
+END;
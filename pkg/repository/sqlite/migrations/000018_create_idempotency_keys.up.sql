@@ -0,0 +1,12 @@
+-- Ответы на запросы с Idempotency-Key, как в Postgres; сервис с storage: sqlite
+-- хранит их в памяти, таблица держит схемы в одном виде.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(600) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    status INTEGER,
+    content_type VARCHAR(100),
+    body BLOB,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+-- Ролей в SQLite нет: база — файл, доступ к нему ограничивают права ОС
+SELECT 1;
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"time"
+)
+
+func (repo *SQLiteRepo) NewOAuthClient(ctx context.Context, client models.OAuthClient) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.db.QueryRowContext(ctx, `
+		INSERT INTO oauth_clients (client_id, secret_hash, name, redirect_uris, scopes, grant_types)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id;
+	`,
+		client.ClientID,
+		client.SecretHash,
+		client.Name,
+		stringList(client.RedirectURIs),
+		stringList(client.Scopes),
+		stringList(client.GrantTypes),
+	).Scan(&id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return id, nil
+}
+
+const oauthClientColumns = `id, client_id, secret_hash, name, redirect_uris, scopes, grant_types, created_at`
+
+func scanOAuthClient(row interface{ Scan(...any) error }) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := row.Scan(
+		&client.ID,
+		&client.ClientID,
+		&client.SecretHash,
+		&client.Name,
+		listOf(&client.RedirectURIs),
+		listOf(&client.Scopes),
+		listOf(&client.GrantTypes),
+		&client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (repo *SQLiteRepo) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	client, err := scanOAuthClient(repo.db.QueryRowContext(ctx, `
+		SELECT `+oauthClientColumns+`
+		FROM oauth_clients
+		WHERE client_id = $1;
+	`, clientID))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return client, nil
+}
+
+func (repo *SQLiteRepo) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT `+oauthClientColumns+`
+		FROM oauth_clients
+		ORDER BY id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, *client)
+	}
+	return clients, rows.Err()
+}
+
+func (repo *SQLiteRepo) NewAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `
+		INSERT INTO oauth_authorization_codes
+			(code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, code_challenge_method, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+	`,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		stringList(code.Scopes),
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		utc(code.ExpiresAt),
+	)
+	return translateError(err)
+}
+
+func (repo *SQLiteRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.AuthorizationCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	// UPDATE ... WHERE used_at IS NULL гарантирует одноразовость даже при гонке
+	var code models.AuthorizationCode
+	err := repo.db.QueryRowContext(ctx, `
+		UPDATE oauth_authorization_codes SET used_at = $2
+		WHERE code_hash = $1 AND used_at IS NULL
+		RETURNING code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, code_challenge_method, expires_at, used_at;
+	`, codeHash, utc(time.Now())).Scan(
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		listOf(&code.Scopes),
+		&code.CodeChallenge,
+		&code.CodeChallengeMethod,
+		&code.ExpiresAt,
+		&code.UsedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("authorization code not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
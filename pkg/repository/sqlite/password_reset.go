@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"time"
+)
+
+func (repo *SQLiteRepo) NewPasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		// Действует только последняя присланная ссылка
+		_, err := tx.ExecContext(ctx, `
+			UPDATE password_reset_tokens SET used_at = $2
+			WHERE user_id = $1 AND used_at IS NULL;
+		`, token.UserID, utc(time.Now()))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+			VALUES ($1, $2, $3);
+		`, token.TokenHash, token.UserID, utc(token.ExpiresAt))
+		return err
+	})
+}
+
+func (repo *SQLiteRepo) ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var token models.PasswordResetToken
+	err := repo.db.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING token_hash, user_id, expires_at, used_at, created_at;
+	`, tokenHash, utc(now)).Scan(&token.TokenHash, &token.UserID, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("password reset token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// SchemaVersion — номер последней миграции в migrations/. Совпадает с
+// postgres.SchemaVersion: миграция добавляется в оба набора сразу (за этим
+// следит TestMigrationsMatchPostgres).
+const SchemaVersion = 19
+
+// Migrations — миграции схемы SQLite; New применяет их сам, утилита migrate —
+// по команде.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// migrateUp применяет недостающие миграции. Внешние ключи на это время
+// выключаются: миграции пересоздают таблицы, а SQLite проверял бы ссылки на
+// удаляемую таблицу. Соединение у db одно, так что PRAGMA действует на миграции.
+func migrateUp(db *sql.DB) error {
+	if _, err := db.Exec(`PRAGMA foreign_keys = OFF;`); err != nil {
+		return err
+	}
+
+	src, err := iofs.New(Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return err
+	}
+	// m.Close не вызываем: он закрыл бы и db
+	m, err := migrate.NewWithInstance("iofs", src, "sqlite", driver)
+	if err != nil {
+		return err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	if _, err := db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
+		return err
+	}
+	// пересозданные таблицы не должны были потерять ни одной ссылки
+	rows, err := db.Query(`PRAGMA foreign_key_check;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table string
+		var rowid sql.NullInt64
+		var parent string
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key check failed: %s row %d references missing %s", table, rowid.Int64, parent)
+	}
+	return rows.Err()
+}
+
+// Ping проверяет, что файл базы доступен.
+func (r *SQLiteRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// CheckSchema сверяет версию применённых миграций с SchemaVersion, как
+// PGRepo.CheckSchema. New применяет миграции сам, так что расхождение означает,
+// что файл базы новее кода (его открыла более новая версия сервиса).
+func (r *SQLiteRepo) CheckSchema(ctx context.Context) error {
+	var (
+		version int
+		dirty   bool
+	)
+	err := r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("no migrations applied, expected version %d", SchemaVersion)
+	case err != nil:
+		return fmt.Errorf("read schema version: %w", err)
+	case dirty:
+		return fmt.Errorf("migration %d failed halfway (dirty), fix it and rerun migrate", version)
+	case version != SchemaVersion:
+		return fmt.Errorf("schema version is %d, expected %d", version, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package sqlite — хранилище repository.DataBase в одном файле SQLite: для
+// небольших филиалов и для разработки без Docker. Запросы повторяют
+// postgres.PGRepo, схема — собственный набор миграций (каталог migrations),
+// который идёт в ногу с migrations/ в корне репозитория.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // драйвер "sqlite" на чистом Go: сборка без cgo
+)
+
+type SQLiteRepo struct {
+	db        *sql.DB
+	dbTimeout time.Duration
+}
+
+// defaultQueryTimeout — предел одного запроса репозитория по умолчанию.
+const defaultQueryTimeout = 3 * time.Second
+
+type options struct {
+	queryTimeout time.Duration
+}
+
+// Option настраивает репозиторий до открытия базы.
+type Option func(*options)
+
+// WithQueryTimeout ограничивает время каждого запроса репозитория.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(o *options) { o.queryTimeout = d }
+}
+
+// New открывает (или создаёт) файл базы path и применяет к нему миграции.
+// ":memory:" — база в памяти, живёт до Close.
+//
+// Соединение одно: SQLite всё равно пишет по одному, а с одним соединением
+// транзакции не упираются в SQLITE_BUSY и база в памяти не теряется.
+func New(path string, opts ...Option) (*SQLiteRepo, error) {
+	o := &options{queryTimeout: defaultQueryTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	if err := migrateUp(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate %s: %w", path, err)
+	}
+	return &SQLiteRepo{
+		db:        db,
+		dbTimeout: o.queryTimeout,
+	}, nil
+}
+
+// dsn включает внешние ключи (в SQLite они выключены по умолчанию), ожидание
+// блокировки файла другим процессом и WAL, а время пишет в формате, который
+// драйвер разбирает обратно в time.Time.
+func dsn(path string) string {
+	return "file:" + path +
+		"?_pragma=foreign_keys(1)" +
+		"&_pragma=busy_timeout(5000)" +
+		"&_pragma=journal_mode(WAL)" +
+		"&_time_format=sqlite"
+}
+
+// TruncateAll очищает каталог и сбрасывает счётчики id, как TRUNCATE ... RESTART IDENTITY в PGRepo.
+func (r *SQLiteRepo) TruncateAll(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM books;
+		DELETE FROM genres;
+		DELETE FROM authors;
+		DELETE FROM sqlite_sequence WHERE name IN ('authors', 'genres', 'books');
+	`)
+	return err
+}
+
+func (r *SQLiteRepo) Close() {
+	r.db.Close()
+}
+
+// utc приводит время к UTC перед записью: SQLite хранит время строкой и
+// сравнивает строки, поэтому все значения должны быть в одном поясе.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"leti/pkg/models"
+	"leti/pkg/repository"
+	"leti/pkg/repository/postgres"
+	"leti/pkg/repository/repositorytest"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRepo открывает базу во временном файле с уже применёнными миграциями.
+func newTestRepo(t *testing.T) (*SQLiteRepo, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "library.db")
+	repo, err := New(path)
+	require.NoError(t, err)
+	t.Cleanup(repo.Close)
+	return repo, path
+}
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.DataBase {
+		repo, _ := newTestRepo(t)
+		require.NoError(t, repo.TruncateAll(context.Background()))
+		return repo
+	})
+}
+
+func TestMigrationsMatchPostgres(t *testing.T) {
+	pg, err := filepath.Glob(filepath.Join("..", "..", "..", "migrations", "*.sql"))
+	require.NoError(t, err)
+	require.NotEmpty(t, pg)
+	own, err := fs.Glob(Migrations, "migrations/*.sql")
+	require.NoError(t, err)
+
+	names := func(files []string) []string {
+		out := make([]string, 0, len(files))
+		for _, f := range files {
+			out = append(out, filepath.Base(f))
+		}
+		return out
+	}
+	require.ElementsMatch(t, names(pg), names(own), "add the SQLite counterpart of every migration")
+
+	latest := 0
+	for _, f := range own {
+		prefix, _, _ := strings.Cut(filepath.Base(f), "_")
+		n, err := strconv.Atoi(prefix)
+		require.NoError(t, err, f)
+		latest = max(latest, n)
+	}
+	require.Equal(t, latest, SchemaVersion, "update SchemaVersion after adding a migration")
+	require.Equal(t, postgres.SchemaVersion, SchemaVersion)
+}
+
+func TestSQLiteRepo_SeedAndSchema(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Ping(ctx))
+	require.NoError(t, repo.CheckSchema(ctx))
+
+	// те же начальные данные, что и в Postgres
+	books, err := repo.GetAllWithAuthors(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, books)
+	admin, err := repo.GetUserByUsername(ctx, "Den")
+	require.NoError(t, err)
+	require.Equal(t, "admin", admin.Role)
+}
+
+func TestSQLiteRepo_Reopen(t *testing.T) {
+	repo, path := newTestRepo(t)
+	ctx := context.Background()
+
+	id, err := repo.NewAuthor(ctx, models.Author{Author: "Антон Чехов"})
+	require.NoError(t, err)
+	repo.Close()
+
+	reopened, err := New(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.NoError(t, reopened.CheckSchema(ctx))
+
+	authors, err := reopened.GetAllAuthors(ctx)
+	require.NoError(t, err)
+	require.Contains(t, authors, models.Author{ID: id, Author: "Антон Чехов", TenantID: 1})
+}
+
+func TestMigrationsDownUp(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	src, err := iofs.New(Migrations, "migrations")
+	require.NoError(t, err)
+	driver, err := migratesqlite.WithInstance(repo.db, &migratesqlite.Config{})
+	require.NoError(t, err)
+	m, err := migrate.NewWithInstance("iofs", src, "sqlite", driver)
+	require.NoError(t, err)
+
+	_, err = repo.db.Exec(`PRAGMA foreign_keys = OFF;`)
+	require.NoError(t, err)
+	require.NoError(t, m.Down())
+	require.NoError(t, migrateUp(repo.db))
+	require.NoError(t, repo.CheckSchema(context.Background()))
+}
@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// stringList хранит []string (TEXT[] в Postgres) JSON-массивом: массивов в SQLite нет.
+type stringList []string
+
+func (l stringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+// listOf сканирует столбец-массив в *dst; пустой массив — пустой срез, не nil, как у pgx.
+func listOf(dst *[]string) *stringListScanner {
+	return &stringListScanner{dst: dst}
+}
+
+type stringListScanner struct {
+	dst *[]string
+}
+
+func (s *stringListScanner) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("scan string list: unsupported type %T", src)
+	}
+	list := []string{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("scan string list: %w", err)
+	}
+	*s.dst = list
+	return nil
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+)
+
+// inTx выполняет fn в транзакции. Политик RLS, как в Postgres, здесь нет:
+// запросы каталога сами отбирают строки арендатора из контекста.
+// Внутри fn нельзя обращаться к repo.db: единственное соединение занято транзакцией.
+func (repo *SQLiteRepo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return translateError(err)
+	}
+	return translateError(tx.Commit())
+}
+
+const tenantColumns = `id, slug, name, created_at`
+
+func scanTenant(row interface{ Scan(...any) error }) (*models.Tenant, error) {
+	var t models.Tenant
+	if err := row.Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (repo *SQLiteRepo) NewTenant(ctx context.Context, t models.Tenant, admin *models.User) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO tenants (slug, name)
+			VALUES ($1, $2)
+			RETURNING id;
+		`, t.Slug, t.Name).Scan(&id)
+		if err != nil || admin == nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO users (username, password, role, email, tenant_id)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5);
+		`, admin.Username, admin.Password, admin.Role, admin.Email, id)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (repo *SQLiteRepo) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	rows, err := repo.db.QueryContext(ctx, `SELECT `+tenantColumns+` FROM tenants ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []models.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, *t)
+	}
+	return tenants, rows.Err()
+}
+
+func (repo *SQLiteRepo) GetTenantBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	t, err := scanTenant(repo.db.QueryRowContext(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE slug = $1;`, slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("tenant %q not found", slug)
+	}
+	return t, err
+}
@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/models"
+	"strings"
+	"time"
+)
+
+// userColumns и scanUser — общий набор полей пользователя для всех запросов.
+const userColumns = `u.id, u.username, u.password, u.role, COALESCE(u.email, ''), u.token_version, u.disabled_at, u.tenant_id, u.created_at`
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы поиск шёл по подстроке буквально.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func scanUser(row interface{ Scan(...any) error }) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.Role, &user.Email,
+		&user.TokenVersion, &user.DisabledAt, &user.TenantID, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (repo *SQLiteRepo) GetUserByUsername(ctx context.Context, userName string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	user, err := scanUser(repo.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE u.username = $1;
+	`, userName))
+	return user, translateError(err)
+}
+
+func (repo *SQLiteRepo) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	user, err := scanUser(repo.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE u.id = $1;
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("user with id %d not found", id)
+	}
+	return user, err
+}
+
+// execAffected выполняет запрос и возвращает NotFound, если он не затронул ни одной строки.
+func execAffected(ctx context.Context, exec interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, notFound error, query string, args ...any) error {
+	result, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func (repo *SQLiteRepo) UpdateUserRole(ctx context.Context, id int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return execAffected(ctx, repo.db, apperr.NotFound("user with id %d not found", id), `
+		UPDATE users SET role = $2
+		WHERE id = $1;
+	`, id, role)
+}
+
+func (repo *SQLiteRepo) UpdateUserPassword(ctx context.Context, id int, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		err := execAffected(ctx, tx, apperr.NotFound("user with id %d not found", id), `
+			UPDATE users SET password = $2, token_version = token_version + 1
+			WHERE id = $1;
+		`, id, passwordHash)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE password_reset_tokens SET used_at = $2
+			WHERE user_id = $1 AND used_at IS NULL;
+		`, id, utc(time.Now()))
+		return err
+	})
+}
+
+func (repo *SQLiteRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	user, err := scanUser(repo.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.issuer = $1 AND i.subject = $2;
+	`, issuer, subject))
+	return user, translateError(err)
+}
+
+func (repo *SQLiteRepo) NewUserWithIdentity(ctx context.Context, user models.User, issuer, subject string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	var id int
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (username, password, role, email, tenant_id)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+			RETURNING id;
+		`, user.Username, user.Password, user.Role, user.Email, user.TenantID).Scan(&id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO user_identities (user_id, issuer, subject)
+			VALUES ($1, $2, $3);
+		`, id, issuer, subject)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (repo *SQLiteRepo) UpgradePasswordHash(ctx context.Context, id int, oldHash, newHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	_, err := repo.db.ExecContext(ctx, `
+		UPDATE users SET password = $3
+		WHERE id = $1 AND password = $2;
+	`, id, oldHash, newHash)
+	return err
+}
+
+func (repo *SQLiteRepo) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	// пустой поиск совпадает со всеми, нулевой арендатор — тоже.
+	// LIKE в SQLite не различает регистр, как ILIKE, но только для латиницы.
+	const where = `WHERE (u.username LIKE '%' || $1 || '%' ESCAPE '\' OR u.email LIKE '%' || $1 || '%' ESCAPE '\')
+		AND ($2 = 0 OR u.tenant_id = $2)`
+
+	var total int
+	search := likeEscaper.Replace(filter.Search)
+	if err := repo.db.QueryRowContext(ctx, `SELECT count(*) FROM users u `+where, search, filter.TenantID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		`+where+`
+		ORDER BY u.id
+		LIMIT $3 OFFSET $4;
+	`, search, filter.TenantID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+func (repo *SQLiteRepo) SetUserDisabled(ctx context.Context, id int, disabledAt *time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return execAffected(ctx, repo.db, apperr.NotFound("user with id %d not found", id), `
+		UPDATE users SET disabled_at = $2
+		WHERE id = $1;
+	`, id, utcPtr(disabledAt))
+}
+
+func (repo *SQLiteRepo) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.dbTimeout)
+	defer cancel()
+
+	return execAffected(ctx, repo.db, apperr.NotFound("user with id %d not found", id),
+		`DELETE FROM users WHERE id = $1;`, id)
+}
@@ -1,11 +1,77 @@
 package service
 
-import "leti/pkg/repository"
+import (
+	"context"
+	"leti/pkg/lockout"
+	"leti/pkg/metrics"
+	"leti/pkg/notify"
+	"leti/pkg/repository"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "leti/pkg/service"
 
 type Service struct {
-	db repository.DataBase
+	db       repository.DataBase
+	notifier notify.Notifier
+	// resetURL — адрес страницы сброса пароля; токен добавляется параметром ?token=
+	resetURL string
+	lockout  *lockout.Guard
+	metrics  *metrics.Metrics
+	tracer   trace.Tracer
+}
+
+// Option настраивает необязательные зависимости сервиса.
+type Option func(*Service)
+
+// WithNotifier задаёт способ доставки писем пользователям.
+func WithNotifier(n notify.Notifier) Option {
+	return func(s *Service) { s.notifier = n }
+}
+
+// WithPasswordResetURL задаёт страницу, ссылка на которую уходит в письме сброса пароля.
+func WithPasswordResetURL(url string) Option {
+	return func(s *Service) { s.resetURL = url }
+}
+
+// WithLockout задаёт защиту входа от перебора (например, с общим хранилищем в Postgres).
+func WithLockout(g *lockout.Guard) Option {
+	return func(s *Service) { s.lockout = g }
+}
+
+// WithMetrics включает счётчики доменных событий (входы, новые книги).
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Service) { s.metrics = m }
+}
+
+// WithTracerProvider задаёт, куда пишутся спаны вызовов сервиса (по умолчанию — глобальный провайдер otel).
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Service) { s.tracer = tp.Tracer(tracerName) }
+}
+
+func NewService(db repository.DataBase, opts ...Option) *Service {
+	s := &Service{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// по умолчанию — как в dev: письма пишутся в лог
+	if s.notifier == nil {
+		s.notifier = notify.NewLogNotifier(slog.Default())
+	}
+	if s.tracer == nil {
+		s.tracer = otel.Tracer(tracerName)
+	}
+	if s.lockout == nil {
+		s.lockout = lockout.NewGuard(lockout.NewMemoryStore(), lockout.DefaultUserPolicy, lockout.DefaultIPPolicy)
+	}
+	return s
 }
 
-func NewService(db repository.DataBase) *Service {
-	return &Service{db: db}
+// startSpan открывает дочерний спан на вызов сервиса, чтобы в трассе было
+// видно, сколько времени ушло на логику, а сколько — на запросы к БД.
+func (s *Service) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "Service."+name)
 }
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// apiKeyTouchInterval — как часто обновляется last_used_at. Отметка нужна лишь
+// для списка ключей, а запись в базу на каждый запрос с ключом ей не нужна.
+const apiKeyTouchInterval = time.Minute
+
+// CreateAPIKey выпускает ключ для пользователя ownerID. Открытый ключ возвращается
+// только здесь — в хранилище остаётся лишь хэш.
+func (s *Service) CreateAPIKey(ctx context.Context, ownerID int, name string, scopes []string, expiresAt *time.Time) (models.APIKey, string, error) {
+	ctx, span := s.startSpan(ctx, "CreateAPIKey")
+	defer span.End()
+
+	if strings.TrimSpace(name) == "" {
+		return models.APIKey{}, "", apperr.Field("name", "cannot be empty")
+	}
+	if len(scopes) == 0 {
+		return models.APIKey{}, "", apperr.Field("scopes", "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !auth.IsKnownScope(scope) {
+			return models.APIKey{}, "", apperr.Field("scopes", "unknown scope %q", scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return models.APIKey{}, "", apperr.Field("expires_at", "must be in the future")
+	}
+
+	plain, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	key := models.APIKey{
+		UserID:    ownerID,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      auth.HashAPIKey(plain),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	id, err := s.db.NewAPIKey(ctx, key)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	key.ID = id
+	return key, plain, nil
+}
+
+// ListAPIKeys возвращает ключи пользователей библиотеки текущего запроса.
+func (s *Service) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, span := s.startSpan(ctx, "ListAPIKeys")
+	defer span.End()
+
+	return s.db.ListAPIKeys(ctx, tenant.FromContext(ctx))
+}
+
+// RevokeAPIKey отзывает ключ; ключ другой библиотеки считается несуществующим.
+func (s *Service) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, span := s.startSpan(ctx, "RevokeAPIKey")
+	defer span.End()
+
+	return s.db.RevokeAPIKey(ctx, tenant.FromContext(ctx), id)
+}
+
+// AuthenticateAPIKey проверяет ключ из заголовка и строит Principal.
+// Области ключа урезаются до тех, что положены роли владельца.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, plain string) (*auth.Principal, error) {
+	ctx, span := s.startSpan(ctx, "AuthenticateAPIKey")
+	defer span.End()
+
+	prefix, err := auth.ParseAPIKeyPrefix(plain)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.db.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if !auth.CheckAPIKey(key.Hash, plain) {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	owner, err := s.db.GetUserByID(ctx, key.UserID)
+	if err != nil || owner.DisabledAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.db.TouchAPIKey(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth.Principal{
+		UserID:   owner.ID,
+		Role:     owner.Role,
+		Scopes:   intersect(key.Scopes, auth.ScopesForRole(owner.Role)),
+		AuthType: auth.AuthTypeAPIKey,
+		APIKeyID: key.ID,
+		TenantID: owner.TenantID,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_APIKeyLifecycle(t *testing.T) {
+	fakeDB := &fake.FakeRepo{}
+	ownerID := fakeDB.AddUser(models.User{Username: "importer", Role: auth.RoleUser})
+	svc := NewService(fakeDB)
+	ctx := context.Background()
+
+	key, plain, err := svc.CreateAPIKey(ctx, ownerID, "nightly import", []string{auth.ScopeBooksWrite, auth.ScopeAdmin}, nil)
+	require.NoError(t, err)
+	require.Contains(t, plain, key.Prefix)
+	require.NotContains(t, key.Hash, plain)
+
+	principal, err := svc.AuthenticateAPIKey(ctx, plain)
+	require.NoError(t, err)
+	require.Equal(t, auth.AuthTypeAPIKey, principal.AuthType)
+	require.Equal(t, ownerID, principal.UserID)
+	require.True(t, principal.HasScope(auth.ScopeBooksWrite))
+	// роль user не даёт admin, даже если он указан в ключе
+	require.False(t, principal.HasScope(auth.ScopeAdmin))
+
+	keys, err := svc.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	_, err = svc.AuthenticateAPIKey(ctx, plain+"x")
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	require.NoError(t, svc.RevokeAPIKey(ctx, key.ID))
+	_, err = svc.AuthenticateAPIKey(ctx, plain)
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestService_APIKeyTouchThrottled(t *testing.T) {
+	fakeDB := &fake.FakeRepo{}
+	ownerID := fakeDB.AddUser(models.User{Username: "importer", Role: auth.RoleUser})
+	svc := NewService(fakeDB)
+	ctx := context.Background()
+
+	_, plain, err := svc.CreateAPIKey(ctx, ownerID, "nightly import", []string{auth.ScopeProfileRead}, nil)
+	require.NoError(t, err)
+	lastUsed := func() time.Time {
+		keys, err := svc.ListAPIKeys(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, keys[0].LastUsedAt)
+		return *keys[0].LastUsedAt
+	}
+
+	_, err = svc.AuthenticateAPIKey(ctx, plain)
+	require.NoError(t, err)
+	first := lastUsed()
+
+	// в пределах apiKeyTouchInterval отметка не переписывается
+	_, err = svc.AuthenticateAPIKey(ctx, plain)
+	require.NoError(t, err)
+	require.Equal(t, first, lastUsed())
+
+	keys, err := svc.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.NoError(t, fakeDB.TouchAPIKey(ctx, keys[0].ID, first.Add(-apiKeyTouchInterval)))
+	_, err = svc.AuthenticateAPIKey(ctx, plain)
+	require.NoError(t, err)
+	require.True(t, lastUsed().After(first))
+}
+
+func TestService_APIKeyExpired(t *testing.T) {
+	fakeDB := &fake.FakeRepo{}
+	ownerID := fakeDB.AddUser(models.User{Username: "partner", Role: auth.RoleAdmin})
+	svc := NewService(fakeDB)
+
+	plain, prefix, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Minute)
+	_, err = fakeDB.NewAPIKey(context.Background(), models.APIKey{
+		UserID:    ownerID,
+		Name:      "old",
+		Prefix:    prefix,
+		Hash:      auth.HashAPIKey(plain),
+		Scopes:    []string{auth.ScopeBooksWrite},
+		ExpiresAt: &expired,
+	})
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateAPIKey(context.Background(), plain)
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestService_CreateAPIKey_UnknownScope(t *testing.T) {
+	svc := NewService(&fake.FakeRepo{})
+	_, _, err := svc.CreateAPIKey(context.Background(), 1, "bad", []string{"everything"}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown scope")
+}
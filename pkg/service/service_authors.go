@@ -2,18 +2,24 @@ package service
 
 import (
 	"context"
-	"errors"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
 	"strings"
 )
 
 func (s *Service) GetAllAuthors(ctx context.Context) ([]models.Author, error) {
+	ctx, span := s.startSpan(ctx, "GetAllAuthors")
+	defer span.End()
+
 	return s.db.GetAllAuthors(ctx)
 }
 
 func (s *Service) NewAuthor(ctx context.Context, author models.Author) (int, error) {
+	ctx, span := s.startSpan(ctx, "NewAuthor")
+	defer span.End()
+
 	if strings.TrimSpace(author.Author) == "" {
-		return 0, errors.New("author name cannot be empty")
+		return 0, apperr.Field("name", "cannot be empty")
 	}
 	return s.db.NewAuthor(ctx, author)
 }
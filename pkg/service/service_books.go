@@ -4,33 +4,69 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
-	"strings"
+	"leti/pkg/validation"
 )
 
 func (s *Service) CreateBook(ctx context.Context, book models.Book) (int, error) {
-	return s.db.NewBook(ctx, book)
+	ctx, span := s.startSpan(ctx, "CreateBook")
+	defer span.End()
+
+	if book.ISBN != "" {
+		isbn, err := normalizeISBN(book.ISBN)
+		if err != nil {
+			return 0, err
+		}
+		book.ISBN = isbn
+	}
+	id, err := s.db.NewBook(ctx, book)
+	if err != nil {
+		return 0, err
+	}
+	s.metrics.BookCreated()
+	return id, nil
 }
 
 func (s *Service) GetBookByID(ctx context.Context, id int) (models.Book, error) {
+	ctx, span := s.startSpan(ctx, "GetBookByID")
+	defer span.End()
+
 	return s.db.GetBookByID(ctx, id)
 }
 
 func (s *Service) GetAllBooks(ctx context.Context) ([]models.Book, error) {
+	ctx, span := s.startSpan(ctx, "GetAllBooks")
+	defer span.End()
+
 	return s.db.GetBooks(ctx) // Передаем контекст дальше
 }
 
 func (s *Service) RemoveBook(ctx context.Context, id int) error {
+	ctx, span := s.startSpan(ctx, "RemoveBook")
+	defer span.End()
+
 	return s.db.DeleteBookById(ctx, id)
 }
 
 func (s *Service) UpdateBook(ctx context.Context, id int, update models.BookUpdate) error {
+	ctx, span := s.startSpan(ctx, "UpdateBook")
+	defer span.End()
+
 	if update.Price != nil && *update.Price < 0 {
-		return errors.New("price must be non-negative")
+		return apperr.Validation("price must be non-negative", apperr.FieldError{Field: "price", Message: "must be non-negative"})
+	}
+	// Пустая строка стирает ISBN
+	if update.ISBN != nil && *update.ISBN != "" {
+		isbn, err := normalizeISBN(*update.ISBN)
+		if err != nil {
+			return err
+		}
+		update.ISBN = &isbn
 	}
 	err := s.db.UpdateBook(ctx, id, update)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, apperr.ErrNotFound) {
 			return fmt.Errorf("book not found: %w", err)
 		}
 		return err
@@ -38,6 +74,17 @@ func (s *Service) UpdateBook(ctx context.Context, id int, update models.BookUpda
 	return nil
 }
 
+// normalizeISBN хранит ISBN без дефисов, чтобы уникальность не зависела от записи.
+func normalizeISBN(isbn string) (string, error) {
+	if !validation.ValidISBN(isbn) {
+		return "", apperr.Field("isbn", "must be a valid ISBN-10 or ISBN-13")
+	}
+	return validation.NormalizeISBN(isbn), nil
+}
+
 func (s *Service) GetAllWithAuthors(ctx context.Context) ([]models.BookWithAuthor, error) {
+	ctx, span := s.startSpan(ctx, "GetAllWithAuthors")
+	defer span.End()
+
 	return s.db.GetAllWithAuthors(ctx)
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
 	"leti/pkg/repository/fake"
 	"testing"
@@ -53,6 +54,9 @@ func TestUpdateBook(t *testing.T) {
 		t.Run(tc.TestName, func(t *testing.T) {
 			var fakeDB = &fake.FakeRepo{}
 			svc := NewService(fakeDB)
+			// книга ссылается на существующих автора и жанр
+			_, _ = fakeDB.NewAuthor(context.Background(), models.Author{Author: "Пушкин"})
+			_, _ = fakeDB.NewGenre(context.Background(), models.Genre{Genre: "Роман"})
 			id, _ := svc.CreateBook(context.Background(), models.Book{
 				Name:      "Онегин",
 				Author_id: 1,
@@ -83,3 +87,20 @@ func TestUpdateBook(t *testing.T) {
 	}
 
 }
+
+func TestCreateBook_ISBN(t *testing.T) {
+	fakeDB := &fake.FakeRepo{}
+	svc := NewService(fakeDB)
+	ctx := context.Background()
+	_, _ = fakeDB.NewAuthor(ctx, models.Author{Author: "Пушкин"})
+	_, _ = fakeDB.NewGenre(ctx, models.Genre{Genre: "Роман"})
+
+	_, err := svc.CreateBook(ctx, models.Book{Name: "Онегин", Author_id: 1, Genre_id: 1, ISBN: "978-5-17-090630-8"})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+
+	id, err := svc.CreateBook(ctx, models.Book{Name: "Онегин", Author_id: 1, Genre_id: 1, ISBN: "978-5-17-090630-7"})
+	require.NoError(t, err)
+	book, err := svc.GetBookByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "9785170906307", book.ISBN)
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+	"leti/pkg/models"
+)
+
+// CatalogVersion — версия коллекции каталога для ETag и Last-Modified.
+func (s *Service) CatalogVersion(ctx context.Context, collection string) (models.CatalogVersion, error) {
+	ctx, span := s.startSpan(ctx, "CatalogVersion")
+	defer span.End()
+
+	return s.db.CatalogVersion(ctx, collection)
+}
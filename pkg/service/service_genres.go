@@ -2,18 +2,24 @@ package service
 
 import (
 	"context"
-	"errors"
+	"leti/pkg/apperr"
 	"leti/pkg/models"
 	"strings"
 )
 
 func (s *Service) GetAllGenres(ctx context.Context) ([]models.Genre, error) {
+	ctx, span := s.startSpan(ctx, "GetAllGenres")
+	defer span.End()
+
 	return s.db.GetAllGenres(ctx)
 }
 
 func (s *Service) NewGenre(ctx context.Context, genre models.Genre) (int, error) {
+	ctx, span := s.startSpan(ctx, "NewGenre")
+	defer span.End()
+
 	if strings.TrimSpace(genre.Genre) == "" {
-		return 0, errors.New("genre name cannot be empty")
+		return 0, apperr.Field("genre", "cannot be empty")
 	}
 	return s.db.NewGenre(ctx, genre)
 }
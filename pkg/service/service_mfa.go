@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"strings"
+	"time"
+)
+
+const totpIssuer = "Library API"
+
+var (
+	ErrMFAAlreadyEnabled = apperr.New(apperr.ErrConflict, "2fa is already enabled")
+	ErrMFANotEnrolled    = errors.New("2fa enrollment not started")
+	ErrInvalidMFACode    = errors.New("invalid 2fa code")
+)
+
+// LoginStep — что нужно сделать после проверки пароля.
+type LoginStep int
+
+const (
+	LoginComplete           LoginStep = iota // можно выдавать access-токен
+	LoginNeedsSecondFactor                   // 2FA включена: нужен код
+	LoginNeedsMFAEnrollment                  // роль требует 2FA, а она не настроена
+)
+
+// TOTPEnrollment — данные для подключения приложения-аутентификатора.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte // PNG
+}
+
+// NextLoginStep решает, хватает ли пароля для входа этого пользователя.
+func (s *Service) NextLoginStep(ctx context.Context, user *models.User) (LoginStep, error) {
+	ctx, span := s.startSpan(ctx, "NextLoginStep")
+	defer span.End()
+
+	enabled, err := s.totpEnabled(ctx, user.ID)
+	if err != nil {
+		return 0, err
+	}
+	if enabled {
+		return LoginNeedsSecondFactor, nil
+	}
+	roles, err := s.db.GetMFARequiredRoles(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if contains(roles, user.Role) {
+		return LoginNeedsMFAEnrollment, nil
+	}
+	return LoginComplete, nil
+}
+
+// BeginTOTPEnrollment создаёт новый секрет. 2FA включится только после
+// подтверждения кодом в ConfirmTOTPEnrollment.
+func (s *Service) BeginTOTPEnrollment(ctx context.Context, userID int) (*TOTPEnrollment, error) {
+	ctx, span := s.startSpan(ctx, "BeginTOTPEnrollment")
+	defer span.End()
+
+	enabled, err := s.totpEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	uri := auth.TOTPURI(totpIssuer, user.Username, secret)
+	png, err := auth.TOTPQRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, URI: uri, QRCode: png}, nil
+}
+
+// ConfirmTOTPEnrollment включает 2FA и возвращает коды восстановления —
+// в открытом виде они показываются только здесь.
+func (s *Service) ConfirmTOTPEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := s.startSpan(ctx, "ConfirmTOTPEnrollment")
+	defer span.End()
+
+	totp, err := s.db.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if totp.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err := s.checkTOTP(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = auth.HashSecret(c)
+	}
+	if err := s.db.EnableTOTP(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP отключает 2FA; нужен действующий код или код восстановления.
+func (s *Service) DisableTOTP(ctx context.Context, userID int, code string) error {
+	ctx, span := s.startSpan(ctx, "DisableTOTP")
+	defer span.End()
+
+	if err := s.VerifySecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.db.DisableTOTP(ctx, userID)
+}
+
+// VerifyLoginSecondFactor — второй шаг входа по challenge из первого. Неверные коды
+// считаются по пользователю и по IP, как пароли в Authenticate: после серии ошибок
+// возвращается *lockout.LockedError, а challenge гасится (lockout.ErrChallengeSpent).
+func (s *Service) VerifyLoginSecondFactor(ctx context.Context, challenge *auth.MFAChallenge, code, ip string) error {
+	ctx, span := s.startSpan(ctx, "VerifyLoginSecondFactor")
+	defer span.End()
+
+	if err := s.lockout.CheckSecondFactor(ctx, challenge.UserID, challenge.ID, ip); err != nil {
+		return err
+	}
+	err := s.VerifySecondFactor(ctx, challenge.UserID, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		var expiresAt time.Time
+		if challenge.ExpiresAt != nil {
+			expiresAt = challenge.ExpiresAt.Time
+		}
+		if lockErr := s.lockout.FailSecondFactor(ctx, challenge.UserID, challenge.ID, ip, expiresAt); lockErr != nil {
+			return lockErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return s.lockout.SucceedSecondFactor(ctx, challenge.UserID)
+}
+
+// VerifySecondFactor принимает TOTP-код или один из кодов восстановления.
+func (s *Service) VerifySecondFactor(ctx context.Context, userID int, code string) error {
+	ctx, span := s.startSpan(ctx, "VerifySecondFactor")
+	defer span.End()
+
+	totp, err := s.db.GetTOTP(ctx, userID)
+	if err != nil || !totp.Enabled {
+		return ErrMFANotEnrolled
+	}
+	// коды восстановления ищем, только если ввод на них похож, а не после каждого неверного TOTP-кода
+	if !auth.IsRecoveryCode(code) {
+		return s.checkTOTP(ctx, totp, code)
+	}
+	return s.useRecoveryCode(ctx, userID, strings.ToLower(code))
+}
+
+// useRecoveryCode гасит код восстановления, найденный по хэшу.
+func (s *Service) useRecoveryCode(ctx context.Context, userID int, code string) error {
+	recovery, err := s.db.ListRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	hash := []byte(auth.HashSecret(code))
+	for _, rc := range recovery {
+		if subtle.ConstantTimeCompare([]byte(rc.CodeHash), hash) != 1 {
+			continue
+		}
+		used, err := s.db.UseRecoveryCode(ctx, rc.ID)
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+	}
+	return ErrInvalidMFACode
+}
+
+func (s *Service) GetMFARequiredRoles(ctx context.Context) ([]string, error) {
+	ctx, span := s.startSpan(ctx, "GetMFARequiredRoles")
+	defer span.End()
+
+	return s.db.GetMFARequiredRoles(ctx)
+}
+
+func (s *Service) SetMFARequiredRoles(ctx context.Context, roles []string) error {
+	ctx, span := s.startSpan(ctx, "SetMFARequiredRoles")
+	defer span.End()
+
+	for _, role := range roles {
+		if role != auth.RoleAdmin && role != auth.RoleUser {
+			return apperr.Field("roles", "unknown role %q", role)
+		}
+	}
+	return s.db.SetMFARequiredRoles(ctx, roles)
+}
+
+// totpEnabled сообщает, включена ли 2FA. Только ErrNotFound значит «не настроена»:
+// при сбое базы второй фактор нельзя молча пропустить.
+func (s *Service) totpEnabled(ctx context.Context, userID int) (bool, error) {
+	totp, err := s.db.GetTOTP(ctx, userID)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.Enabled, nil
+}
+
+// checkTOTP сверяет код и запоминает шаг, чтобы перехваченный код нельзя было повторить.
+func (s *Service) checkTOTP(ctx context.Context, totp *models.TOTP, code string) error {
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	fresh, err := s.db.AdvanceTOTPStep(ctx, totp.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/repository/fake"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySecondFactor_RecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	fakeDB := &fake.FakeRepo{}
+	userID := fakeDB.AddUser(models.User{Username: "Den", Role: auth.RoleUser})
+	srv := NewService(fakeDB)
+
+	require.NoError(t, fakeDB.SetTOTPSecret(ctx, userID, "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, fakeDB.EnableTOTP(ctx, userID, []string{auth.HashSecret("abcde-23456")}))
+
+	// код восстановления хранится sha256 и принимается в любом регистре
+	require.NoError(t, srv.VerifySecondFactor(ctx, userID, strings.ToUpper("abcde-23456")))
+	require.ErrorIs(t, srv.VerifySecondFactor(ctx, userID, "abcde-23456"), ErrInvalidMFACode, "код одноразовый")
+
+	// ввод не вида xxxxx-xxxxx проверяется только как TOTP-код
+	require.ErrorIs(t, srv.VerifySecondFactor(ctx, userID, "000000"), ErrInvalidMFACode)
+	require.ErrorIs(t, srv.VerifySecondFactor(ctx, userID, "abcde23456"), ErrInvalidMFACode)
+}
+
+// brokenTOTPRepo — база, которая не может прочитать настройки 2FA.
+type brokenTOTPRepo struct {
+	*fake.FakeRepo
+}
+
+func (r brokenTOTPRepo) GetTOTP(context.Context, int) (*models.TOTP, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestNextLoginStep(t *testing.T) {
+	ctx := context.Background()
+	fakeDB := &fake.FakeRepo{}
+	userID := fakeDB.AddUser(models.User{Username: "Den", Role: auth.RoleUser})
+	user, err := fakeDB.GetUserByID(ctx, userID)
+	require.NoError(t, err)
+	srv := NewService(fakeDB)
+
+	step, err := srv.NextLoginStep(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, LoginComplete, step, "2FA не настроена")
+
+	require.NoError(t, fakeDB.SetTOTPSecret(ctx, userID, "JBSWY3DPEHPK3PXP"))
+	step, err = srv.NextLoginStep(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, LoginComplete, step, "секрет не подтверждён")
+
+	require.NoError(t, fakeDB.EnableTOTP(ctx, userID, nil))
+	step, err = srv.NextLoginStep(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, LoginNeedsSecondFactor, step)
+
+	// сбой базы — ошибка, а не вход без второго фактора
+	broken := NewService(brokenTOTPRepo{fakeDB})
+	_, err = broken.NextLoginStep(ctx, user)
+	require.Error(t, err)
+	_, err = broken.BeginTOTPEnrollment(ctx, userID)
+	require.Error(t, err)
+	totp, err := fakeDB.GetTOTP(ctx, userID)
+	require.NoError(t, err)
+	require.True(t, totp.Enabled, "включённая 2FA не сброшена новым секретом")
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const authorizationCodeTTL = 5 * time.Minute
+
+// RegisterOAuthClient регистрирует стороннее приложение. Для конфиденциальных
+// клиентов возвращается секрет — показывается один раз, хранится только хэш.
+func (s *Service) RegisterOAuthClient(ctx context.Context, name string, redirectURIs, scopes, grantTypes []string, confidential bool) (models.OAuthClient, string, error) {
+	ctx, span := s.startSpan(ctx, "RegisterOAuthClient")
+	defer span.End()
+
+	if strings.TrimSpace(name) == "" {
+		return models.OAuthClient{}, "", apperr.Field("name", "cannot be empty")
+	}
+	if len(grantTypes) == 0 {
+		return models.OAuthClient{}, "", apperr.Field("grant_types", "at least one grant type is required")
+	}
+	for _, gt := range grantTypes {
+		switch gt {
+		case auth.GrantTypeAuthorizationCode:
+			if len(redirectURIs) == 0 {
+				return models.OAuthClient{}, "", apperr.Field("redirect_uris", "authorization_code grant requires redirect_uris")
+			}
+		case auth.GrantTypeClientCredentials:
+			if !confidential {
+				return models.OAuthClient{}, "", apperr.Field("grant_types", "client_credentials grant requires a confidential client")
+			}
+		default:
+			return models.OAuthClient{}, "", apperr.Field("grant_types", "unsupported grant type %q", gt)
+		}
+	}
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			return models.OAuthClient{}, "", apperr.Field("redirect_uris", "invalid redirect uri %q", uri)
+		}
+	}
+	if len(scopes) == 0 {
+		return models.OAuthClient{}, "", apperr.Field("scopes", "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		// admin никогда не делегируется сторонним приложениям
+		if !auth.IsKnownScope(scope) || scope == auth.ScopeAdmin {
+			return models.OAuthClient{}, "", apperr.Field("scopes", "unknown scope %q", scope)
+		}
+	}
+
+	clientID, secret, err := auth.GenerateClientCredentials()
+	if err != nil {
+		return models.OAuthClient{}, "", err
+	}
+	client := models.OAuthClient{
+		ClientID:     clientID,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		GrantTypes:   grantTypes,
+	}
+	if confidential {
+		client.SecretHash = auth.HashSecret(secret)
+	} else {
+		secret = ""
+	}
+
+	id, err := s.db.NewOAuthClient(ctx, client)
+	if err != nil {
+		return models.OAuthClient{}, "", err
+	}
+	client.ID = id
+	return client, secret, nil
+}
+
+func (s *Service) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	ctx, span := s.startSpan(ctx, "ListOAuthClients")
+	defer span.End()
+
+	return s.db.ListOAuthClients(ctx)
+}
+
+// AuthenticateOAuthClient проверяет клиента на token/introspection endpoint.
+// Публичный клиент идентифицируется только client_id и не должен слать секрет.
+func (s *Service) AuthenticateOAuthClient(ctx context.Context, clientID, secret string) (*models.OAuthClient, error) {
+	ctx, span := s.startSpan(ctx, "AuthenticateOAuthClient")
+	defer span.End()
+
+	client, err := s.db.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		return nil, auth.NewOAuthError(auth.OAuthErrInvalidClient, "unknown client")
+	}
+	if client.IsConfidential() {
+		if !auth.CheckSecret(client.SecretHash, secret) {
+			return nil, auth.NewOAuthError(auth.OAuthErrInvalidClient, "client authentication failed")
+		}
+	} else if secret != "" {
+		return nil, auth.NewOAuthError(auth.OAuthErrInvalidClient, "public client must not send a secret")
+	}
+	return client, nil
+}
+
+// ValidateAuthorizeRequest проверяет запрос к authorization endpoint и дополняет
+// его значениями по умолчанию. Если клиент или redirect_uri не прошли проверку,
+// возвращаемый клиент равен nil — перенаправлять пользователя по такому адресу нельзя.
+func (s *Service) ValidateAuthorizeRequest(ctx context.Context, req *auth.AuthorizeRequest) (*models.OAuthClient, error) {
+	ctx, span := s.startSpan(ctx, "ValidateAuthorizeRequest")
+	defer span.End()
+
+	client, err := s.db.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, auth.NewOAuthError(auth.OAuthErrInvalidClient, "unknown client")
+	}
+	if req.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		req.RedirectURI = client.RedirectURIs[0]
+	}
+	if !contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, auth.NewOAuthError(auth.OAuthErrInvalidRequest, "redirect_uri is not registered")
+	}
+
+	if req.ResponseType != auth.ResponseTypeCode {
+		return client, auth.NewOAuthError(auth.OAuthErrUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if !contains(client.GrantTypes, auth.GrantTypeAuthorizationCode) {
+		return client, auth.NewOAuthError(auth.OAuthErrUnauthorizedClient, "client may not use authorization_code")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != auth.PKCEMethodS256 {
+		return client, auth.NewOAuthError(auth.OAuthErrInvalidRequest, "PKCE with code_challenge_method=S256 is required")
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = client.Scopes
+	}
+	for _, scope := range req.Scopes {
+		if !contains(client.Scopes, scope) {
+			return client, auth.NewOAuthError(auth.OAuthErrInvalidScope, fmt.Sprintf("scope %q is not allowed for this client", scope))
+		}
+	}
+	return client, nil
+}
+
+// IssueAuthorizationCode выдаёт код после согласия пользователя. Итоговые области —
+// пересечение запрошенных и тех, что есть у роли пользователя.
+func (s *Service) IssueAuthorizationCode(ctx context.Context, user *models.User, req auth.AuthorizeRequest) (string, error) {
+	ctx, span := s.startSpan(ctx, "IssueAuthorizationCode")
+	defer span.End()
+
+	granted := intersect(req.Scopes, auth.ScopesForRole(user.Role))
+	if len(granted) == 0 {
+		return "", auth.NewOAuthError(auth.OAuthErrAccessDenied, "user has none of the requested scopes")
+	}
+
+	code, err := auth.GenerateAuthorizationCode()
+	if err != nil {
+		return "", err
+	}
+	err = s.db.NewAuthorizationCode(ctx, models.AuthorizationCode{
+		CodeHash:            auth.HashSecret(code),
+		ClientID:            req.ClientID,
+		UserID:              user.ID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              granted,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(authorizationCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeAuthorizationCode погашает код и возвращает пользователя и согласованные области.
+func (s *Service) ExchangeAuthorizationCode(ctx context.Context, client *models.OAuthClient, code, redirectURI, verifier string) (*models.User, []string, error) {
+	ctx, span := s.startSpan(ctx, "ExchangeAuthorizationCode")
+	defer span.End()
+
+	if !contains(client.GrantTypes, auth.GrantTypeAuthorizationCode) {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrUnauthorizedClient, "client may not use authorization_code")
+	}
+	grant, err := s.db.ConsumeAuthorizationCode(ctx, auth.HashSecret(code))
+	if err != nil {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrInvalidGrant, "authorization code is invalid or already used")
+	}
+	if grant.ClientID != client.ClientID || grant.RedirectURI != redirectURI {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrInvalidGrant, "authorization code was issued to another client or redirect_uri")
+	}
+	if !grant.ExpiresAt.After(time.Now()) {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrInvalidGrant, "authorization code expired")
+	}
+	if !auth.VerifyPKCE(grant.CodeChallenge, grant.CodeChallengeMethod, verifier) {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrInvalidGrant, "code_verifier does not match")
+	}
+
+	user, err := s.db.GetUserByID(ctx, grant.UserID)
+	if err != nil {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrInvalidGrant, "resource owner no longer exists")
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, auth.NewOAuthError(auth.OAuthErrInvalidGrant, "resource owner account is disabled")
+	}
+	return user, grant.Scopes, nil
+}
+
+// ClientCredentialsScopes возвращает области для токена client credentials.
+func (s *Service) ClientCredentialsScopes(client *models.OAuthClient, requested []string) ([]string, error) {
+	if !client.IsConfidential() || !contains(client.GrantTypes, auth.GrantTypeClientCredentials) {
+		return nil, auth.NewOAuthError(auth.OAuthErrUnauthorizedClient, "client may not use client_credentials")
+	}
+	if len(requested) == 0 {
+		return client.Scopes, nil
+	}
+	for _, scope := range requested {
+		if !contains(client.Scopes, scope) {
+			return nil, auth.NewOAuthError(auth.OAuthErrInvalidScope, fmt.Sprintf("scope %q is not allowed for this client", scope))
+		}
+	}
+	return requested, nil
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func intersect(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
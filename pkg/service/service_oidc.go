@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"strings"
+)
+
+// LoginWithOIDC находит локального пользователя, привязанного к внешней учётной
+// записи, или создаёт его (just-in-time). Роль синхронизируется с группами IdP
+// при каждом входе.
+func (s *Service) LoginWithOIDC(ctx context.Context, identity *auth.OIDCIdentity) (*models.User, error) {
+	ctx, span := s.startSpan(ctx, "LoginWithOIDC")
+	defer span.End()
+
+	user, err := s.db.GetUserByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		if user.DisabledAt != nil {
+			return nil, ErrAccountDisabled
+		}
+		if id, ok := tenant.IDFromContext(ctx); ok && id != user.TenantID {
+			return nil, fmt.Errorf("subject %q belongs to another tenant", identity.Subject)
+		}
+		if user.Role != identity.Role {
+			if err := s.db.UpdateUserRole(ctx, user.ID, identity.Role); err != nil {
+				return nil, err
+			}
+			user.Role = identity.Role
+		}
+		return user, nil
+	}
+
+	username, err := s.freeUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	// Пустой хэш не совпадёт ни с одним паролем: такой пользователь входит только через IdP
+	// пользователь появляется в библиотеке, на поддомене которой он впервые вошёл
+	newUser := models.User{Username: username, Password: "", Role: identity.Role, TenantID: tenant.FromContext(ctx)}
+	id, err := s.db.NewUserWithIdentity(ctx, newUser, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	newUser.ID = id
+	return &newUser, nil
+}
+
+// freeUsername подбирает незанятое имя. Существующую локальную учётку с тем же
+// именем к внешней не привязываем — это был бы захват аккаунта.
+func (s *Service) freeUsername(ctx context.Context, identity *auth.OIDCIdentity) (string, error) {
+	candidates := []string{identity.Username, identity.Email, "oidc-" + identity.Subject}
+	for _, name := range candidates {
+		name = strings.TrimSpace(name)
+		if name == "" || len(name) > 50 {
+			continue
+		}
+		if _, err := s.db.GetUserByUsername(ctx, name); err != nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot pick a free username for subject %q", identity.Subject)
+}
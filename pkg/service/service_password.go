@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/notify"
+	"leti/pkg/tenant"
+	"net/url"
+	"time"
+)
+
+const (
+	minPasswordLength = 8
+	passwordResetTTL  = 30 * time.Minute
+)
+
+var (
+	ErrWeakPassword = &apperr.Error{
+		Kind:   apperr.ErrValidation,
+		Msg:    fmt.Sprintf("password must be at least %d characters", minPasswordLength),
+		Fields: []apperr.FieldError{{Field: "new_password", Message: fmt.Sprintf("must be at least %d characters", minPasswordLength)}},
+	}
+	ErrWrongPassword     = apperr.New(apperr.ErrForbidden, "current password is incorrect")
+	ErrInvalidResetToken = errors.New("reset token is invalid or expired")
+	ErrSessionRevoked    = errors.New("session has been revoked")
+)
+
+// ChangePassword меняет пароль после проверки текущего. Все ранее выданные
+// токены пользователя перестают действовать; возвращается обновлённый пользователь,
+// чтобы выдать ему новый токен.
+func (s *Service) ChangePassword(ctx context.Context, userID int, current, newPassword string) (*models.User, error) {
+	ctx, span := s.startSpan(ctx, "ChangePassword")
+	defer span.End()
+
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if auth.CheckPassword(user.Password, current) != nil {
+		return nil, ErrWrongPassword
+	}
+	if err := s.setPassword(ctx, userID, newPassword); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserByID(ctx, userID)
+}
+
+// RequestPasswordReset отправляет пользователю одноразовую ссылку для сброса пароля.
+// Для неизвестных имён молча ничего не делает, чтобы по ответу нельзя было
+// перебирать существующие учётные записи.
+func (s *Service) RequestPasswordReset(ctx context.Context, username string) error {
+	ctx, span := s.startSpan(ctx, "RequestPasswordReset")
+	defer span.End()
+
+	user, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil
+	}
+	// Пользователи внешнего IdP меняют пароль у провайдера; заблокированным сброс не поможет
+	if user.Password == "" || user.DisabledAt != nil {
+		return nil
+	}
+
+	return s.sendPasswordReset(ctx, user)
+}
+
+// sendPasswordReset выпускает одноразовый токен и отправляет его пользователю.
+func (s *Service) sendPasswordReset(ctx context.Context, user *models.User) error {
+	token, err := auth.RandomString()
+	if err != nil {
+		return err
+	}
+	err = s.db.NewPasswordResetToken(ctx, models.PasswordResetToken{
+		TokenHash: auth.HashSecret(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(ctx, notify.Message{
+		To:       user.Email,
+		Username: user.Username,
+		Subject:  "Сброс пароля",
+		Body:     s.resetMessage(token),
+	})
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := s.startSpan(ctx, "ResetPassword")
+	defer span.End()
+
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	reset, err := s.db.ConsumePasswordResetToken(ctx, auth.HashSecret(token), time.Now())
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	return s.setPassword(ctx, reset.UserID, newPassword)
+}
+
+// CheckSession проверяет, что токен по-прежнему действителен: выпущен после
+// последней смены пароля, с текущей ролью и библиотекой пользователя,
+// а учётная запись не заблокирована.
+func (s *Service) CheckSession(ctx context.Context, sub auth.Subject) error {
+	ctx, span := s.startSpan(ctx, "CheckSession")
+	defer span.End()
+
+	user, err := s.db.GetUserByID(ctx, sub.UserID)
+	if err != nil {
+		return ErrSessionRevoked
+	}
+	if user.DisabledAt != nil {
+		return ErrAccountDisabled
+	}
+	// токены, выпущенные до появления арендаторов, относятся к библиотеке по умолчанию
+	tenantID := sub.TenantID
+	if tenantID == 0 {
+		tenantID = tenant.DefaultID
+	}
+	if user.TokenVersion != sub.TokenVersion || user.Role != sub.Role || user.TenantID != tenantID {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func (s *Service) setPassword(ctx context.Context, userID int, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.db.UpdateUserPassword(ctx, userID, hash)
+}
+
+func (s *Service) resetMessage(token string) string {
+	minutes := int(passwordResetTTL.Minutes())
+	body := "Кто-то запросил сброс пароля для вашей учётной записи.\n" +
+		"Если это были не вы, просто проигнорируйте письмо.\n\n"
+	if s.resetURL != "" {
+		return body + fmt.Sprintf("Ссылка для сброса (действует %d минут):\n%s?token=%s\n",
+			minutes, s.resetURL, url.QueryEscape(token))
+	}
+	return body + fmt.Sprintf("Код для сброса (действует %d минут):\n%s\n", minutes, token)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/notify"
+	"leti/pkg/repository/fake"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type recordingNotifier struct {
+	sent []notify.Message
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// resetToken достаёт токен из ссылки в последнем письме.
+func (n *recordingNotifier) resetToken(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, n.sent)
+	body := n.sent[len(n.sent)-1].Body
+	i := strings.Index(body, "?token=")
+	require.NotEqual(t, -1, i)
+	token, err := url.QueryUnescape(strings.TrimSpace(body[i+len("?token="):]))
+	require.NoError(t, err)
+	return token
+}
+
+func newPasswordTestService(t *testing.T) (*Service, *fake.FakeRepo, *recordingNotifier, int) {
+	t.Helper()
+	fakeDB := &fake.FakeRepo{}
+	hash, err := auth.HashPassword("old-password")
+	require.NoError(t, err)
+	userID := fakeDB.AddUser(models.User{Username: "reader", Password: hash, Role: auth.RoleUser, Email: "reader@example.com"})
+	notifier := &recordingNotifier{}
+	svc := NewService(fakeDB, WithNotifier(notifier), WithPasswordResetURL("https://library.example/reset"))
+	return svc, fakeDB, notifier, userID
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	svc, _, _, userID := newPasswordTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.ChangePassword(ctx, userID, "wrong", "new-password")
+	require.ErrorIs(t, err, ErrWrongPassword)
+	_, err = svc.ChangePassword(ctx, userID, "old-password", "short")
+	require.ErrorIs(t, err, ErrWeakPassword)
+
+	require.NoError(t, svc.CheckSession(ctx, auth.Subject{UserID: userID, Role: auth.RoleUser}))
+	user, err := svc.ChangePassword(ctx, userID, "old-password", "new-password")
+	require.NoError(t, err)
+	require.Equal(t, 1, user.TokenVersion)
+
+	// токены, выданные до смены пароля, больше не действуют
+	require.ErrorIs(t, svc.CheckSession(ctx, auth.Subject{UserID: userID, Role: auth.RoleUser}), ErrSessionRevoked)
+	require.NoError(t, svc.CheckSession(ctx, auth.Subject{UserID: userID, Role: auth.RoleUser, TokenVersion: user.TokenVersion}))
+
+	_, err = svc.ValidateUserCredentials(ctx, "reader", "old-password")
+	require.Error(t, err)
+	_, err = svc.ValidateUserCredentials(ctx, "reader", "new-password")
+	require.NoError(t, err)
+}
+
+func TestService_PasswordReset(t *testing.T) {
+	svc, _, notifier, userID := newPasswordTestService(t)
+	ctx := context.Background()
+
+	// неизвестному пользователю ничего не отправляется, но и ошибки нет
+	require.NoError(t, svc.RequestPasswordReset(ctx, "nobody"))
+	require.Empty(t, notifier.sent)
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, "reader"))
+	require.Len(t, notifier.sent, 1)
+	require.Equal(t, "reader@example.com", notifier.sent[0].To)
+	first := notifier.resetToken(t)
+
+	// новая ссылка отменяет предыдущую
+	require.NoError(t, svc.RequestPasswordReset(ctx, "reader"))
+	second := notifier.resetToken(t)
+	require.ErrorIs(t, svc.ResetPassword(ctx, first, "brand-new-password"), ErrInvalidResetToken)
+
+	require.ErrorIs(t, svc.ResetPassword(ctx, second, "short"), ErrWeakPassword)
+	require.NoError(t, svc.ResetPassword(ctx, second, "brand-new-password"))
+	// токен одноразовый
+	require.ErrorIs(t, svc.ResetPassword(ctx, second, "another-password"), ErrInvalidResetToken)
+
+	_, err := svc.ValidateUserCredentials(ctx, "reader", "brand-new-password")
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.CheckSession(ctx, auth.Subject{UserID: userID, Role: auth.RoleUser}), ErrSessionRevoked)
+}
+
+func TestService_LoginUpgradesLegacyHash(t *testing.T) {
+	fakeDB := &fake.FakeRepo{}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	userID := fakeDB.AddUser(models.User{Username: "old-timer", Password: string(legacy), Role: auth.RoleUser})
+	svc := NewService(fakeDB)
+	ctx := context.Background()
+
+	_, err = svc.ValidateUserCredentials(ctx, "old-timer", "password")
+	require.NoError(t, err)
+
+	user, err := fakeDB.GetUserByID(ctx, userID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(user.Password, "$argon2id$"))
+	require.False(t, auth.NeedsRehash(user.Password))
+	// пересчёт хэша не завершает сессии
+	require.Zero(t, user.TokenVersion)
+
+	_, err = svc.ValidateUserCredentials(ctx, "old-timer", "password")
+	require.NoError(t, err)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"regexp"
+	"strings"
+)
+
+// slug станет поддоменом, поэтому допускаем только то, что годится для DNS-метки
+var tenantSlugRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+var ErrTenantNotFound = apperr.New(apperr.ErrNotFound, "tenant not found")
+
+// TenantAdmin — первый администратор новой библиотеки. Пароль он задаёт сам
+// по ссылке из письма.
+type TenantAdmin struct {
+	Username string
+	Email    string
+}
+
+// CreateTenant регистрирует библиотеку и, если задан admin, её администратора.
+func (s *Service) CreateTenant(ctx context.Context, slug, name string, admin *TenantAdmin) (*models.Tenant, error) {
+	ctx, span := s.startSpan(ctx, "CreateTenant")
+	defer span.End()
+
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !tenantSlugRe.MatchString(slug) {
+		return nil, apperr.Field("slug", "must be a lowercase DNS label")
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, apperr.Field("name", "cannot be empty")
+	}
+
+	var adminUser *models.User
+	if admin != nil {
+		if strings.TrimSpace(admin.Username) == "" || strings.TrimSpace(admin.Email) == "" {
+			return nil, apperr.Validation("admin username and email are required",
+				apperr.FieldError{Field: "admin.username", Message: "required"},
+				apperr.FieldError{Field: "admin.email", Message: "required"})
+		}
+		// Случайный пароль, который никто не знает: войти можно только после сброса
+		placeholder, err := auth.RandomString()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := auth.HashPassword(placeholder)
+		if err != nil {
+			return nil, err
+		}
+		adminUser = &models.User{
+			Username: strings.TrimSpace(admin.Username),
+			Password: hash,
+			Role:     auth.RoleAdmin,
+			Email:    strings.TrimSpace(admin.Email),
+		}
+	}
+
+	id, err := s.db.NewTenant(ctx, models.Tenant{Slug: slug, Name: strings.TrimSpace(name)}, adminUser)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.db.GetTenantBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if adminUser != nil {
+		user, err := s.db.GetUserByUsername(ctx, adminUser.Username)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.sendPasswordReset(ctx, user); err != nil {
+			return nil, fmt.Errorf("tenant %d created, but the admin invitation failed: %w", id, err)
+		}
+	}
+	return created, nil
+}
+
+func (s *Service) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	ctx, span := s.startSpan(ctx, "ListTenants")
+	defer span.End()
+
+	return s.db.ListTenants(ctx)
+}
+
+// ResolveTenant находит библиотеку по поддомену.
+func (s *Service) ResolveTenant(ctx context.Context, slug string) (*models.Tenant, error) {
+	ctx, span := s.startSpan(ctx, "ResolveTenant")
+	defer span.End()
+
+	t, err := s.db.GetTenantBySlug(ctx, slug)
+	if err != nil {
+		return nil, ErrTenantNotFound
+	}
+	return t, nil
+}
+
+// tenantUser загружает пользователя библиотеки из контекста. Пользователи других
+// библиотек для администратора не существуют.
+func (s *Service) tenantUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.TenantID != tenant.FromContext(ctx) {
+		return nil, apperr.NotFound("user with id %d not found", id)
+	}
+	return user, nil
+}
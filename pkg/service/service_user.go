@@ -2,19 +2,109 @@ package service
 
 import (
 	"context"
+	"errors"
 	"leti/pkg/auth"
 	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"sync"
 )
 
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// checkDummyPassword тратит на проверку столько же времени, сколько проверка
+// настоящего пароля, чтобы по времени ответа нельзя было понять, есть ли пользователь.
+func checkDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = auth.HashPassword("dummy-password-for-timing")
+	})
+	_ = auth.CheckPassword(dummyHash, password)
+}
+
 func (s *Service) ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error) {
+	ctx, span := s.startSpan(ctx, "ValidateUserCredentials")
+	defer span.End()
+
 	user, err := s.db.GetUserByUsername(ctx, username)
+	// у пользователей внешнего IdP пароля нет (пустой хэш)
+	if err != nil || user.Password == "" {
+		checkDummyPassword(password)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := auth.CheckPassword(user.Password, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	// на поддомене другой библиотеки этой учётной записи нет
+	if id, ok := tenant.IDFromContext(ctx); ok && id != user.TenantID {
+		return nil, ErrInvalidCredentials
+	}
+	// проверяем после пароля, чтобы не подсказывать, что учётная запись существует
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	// Пароль известен только сейчас — самое время пересчитать устаревший хэш.
+	// Ошибка не мешает входу: попробуем снова при следующем.
+	if auth.NeedsRehash(user.Password) {
+		if hash, err := auth.HashPassword(password); err == nil {
+			if err := s.db.UpgradePasswordHash(ctx, user.ID, user.Password, hash); err == nil {
+				user.Password = hash
+			}
+		}
+	}
+	return user, nil
+
+}
+
+// Authenticate проверяет пароль с защитой от перебора. Возвращает
+// *lockout.LockedError, если вход по этому имени или с этого IP временно заблокирован.
+func (s *Service) Authenticate(ctx context.Context, username, password, ip string) (*models.User, error) {
+	ctx, span := s.startSpan(ctx, "Authenticate")
+	defer span.End()
+
+	user, err := s.authenticate(ctx, username, password, ip)
+	s.metrics.ObserveLogin(err == nil)
+	return user, err
+}
+
+func (s *Service) authenticate(ctx context.Context, username, password, ip string) (*models.User, error) {
+	if err := s.lockout.Check(ctx, username, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.ValidateUserCredentials(ctx, username, password)
+	if errors.Is(err, ErrAccountDisabled) {
+		return nil, err
+	}
 	if err != nil {
+		if lockErr := s.lockout.Fail(ctx, username, ip); lockErr != nil {
+			return nil, lockErr
+		}
 		return nil, err
 	}
 
-	if err := auth.CheckPassword(user.Password, password); err != nil {
+	if err := s.lockout.Succeed(ctx, username); err != nil {
 		return nil, err
 	}
 	return user, nil
+}
+
+// UnlockLogin снимает блокировку входа по имени пользователя и/или IP.
+func (s *Service) UnlockLogin(ctx context.Context, username, ip string) error {
+	ctx, span := s.startSpan(ctx, "UnlockLogin")
+	defer span.End()
+
+	return s.lockout.Unlock(ctx, username, ip)
+}
+
+func (s *Service) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, span := s.startSpan(ctx, "GetUserByID")
+	defer span.End()
 
+	return s.db.GetUserByID(ctx, id)
 }
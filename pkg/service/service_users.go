@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"leti/pkg/apperr"
+	"leti/pkg/auth"
+	"leti/pkg/models"
+	"leti/pkg/tenant"
+	"time"
+)
+
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+var (
+	ErrAccountDisabled = apperr.New(apperr.ErrForbidden, "account is disabled")
+	// ErrSelfModification — администратор не может отключить, удалить или понизить сам себя,
+	// иначе легко остаться без единого администратора.
+	ErrSelfModification = apperr.New(apperr.ErrConflict, "cannot apply this action to your own account")
+)
+
+// UserPage — страница списка пользователей с итоговыми параметрами пагинации.
+type UserPage struct {
+	Users   []models.User
+	Total   int
+	Page    int
+	PerPage int
+}
+
+// ListUsers возвращает страницу пользователей. page начинается с 1,
+// некорректные page и perPage заменяются значениями по умолчанию.
+func (s *Service) ListUsers(ctx context.Context, search string, page, perPage int) (*UserPage, error) {
+	ctx, span := s.startSpan(ctx, "ListUsers")
+	defer span.End()
+
+	if perPage <= 0 {
+		perPage = defaultUsersPerPage
+	}
+	perPage = min(perPage, maxUsersPerPage)
+	page = max(page, 1)
+	users, total, err := s.db.ListUsers(ctx, models.UserFilter{
+		TenantID: tenant.FromContext(ctx),
+		Search:   search,
+		Limit:    perPage,
+		Offset:   (page - 1) * perPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UserPage{Users: users, Total: total, Page: page, PerPage: perPage}, nil
+}
+
+func (s *Service) ChangeUserRole(ctx context.Context, actorID, id int, role string) error {
+	ctx, span := s.startSpan(ctx, "ChangeUserRole")
+	defer span.End()
+
+	if role != auth.RoleAdmin && role != auth.RoleUser {
+		return apperr.Field("role", "unknown role %q", role)
+	}
+	if actorID == id && role != auth.RoleAdmin {
+		return ErrSelfModification
+	}
+	if _, err := s.tenantUser(ctx, id); err != nil {
+		return err
+	}
+	return s.db.UpdateUserRole(ctx, id, role)
+}
+
+// SetUserDisabled блокирует учётную запись или снимает блокировку. Уже выданные
+// токены заблокированного пользователя отклоняются при следующем запросе.
+func (s *Service) SetUserDisabled(ctx context.Context, actorID, id int, disabled bool) error {
+	ctx, span := s.startSpan(ctx, "SetUserDisabled")
+	defer span.End()
+
+	if actorID == id && disabled {
+		return ErrSelfModification
+	}
+	if _, err := s.tenantUser(ctx, id); err != nil {
+		return err
+	}
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	return s.db.SetUserDisabled(ctx, id, disabledAt)
+}
+
+// ForcePasswordReset делает текущий пароль недействительным, завершает все сессии
+// и отправляет пользователю ссылку для установки нового пароля.
+func (s *Service) ForcePasswordReset(ctx context.Context, id int) error {
+	ctx, span := s.startSpan(ctx, "ForcePasswordReset")
+	defer span.End()
+
+	user, err := s.tenantUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Password == "" {
+		return apperr.Conflict("user %d signs in through an external provider and has no local password", id)
+	}
+
+	// Случайный пароль, который никто не знает: войти можно только после сброса
+	placeholder, err := auth.RandomString()
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(placeholder)
+	if err != nil {
+		return err
+	}
+	if err := s.db.UpdateUserPassword(ctx, id, hash); err != nil {
+		return err
+	}
+	return s.sendPasswordReset(ctx, user)
+}
+
+func (s *Service) DeleteUser(ctx context.Context, actorID, id int) error {
+	ctx, span := s.startSpan(ctx, "DeleteUser")
+	defer span.End()
+
+	if actorID == id {
+		return ErrSelfModification
+	}
+	if _, err := s.tenantUser(ctx, id); err != nil {
+		return err
+	}
+	return s.db.DeleteUser(ctx, id)
+}
+
+// GetTenantUser возвращает пользователя, только если он из библиотеки текущего запроса.
+func (s *Service) GetTenantUser(ctx context.Context, id int) (*models.User, error) {
+	ctx, span := s.startSpan(ctx, "GetTenantUser")
+	defer span.End()
+
+	return s.tenantUser(ctx, id)
+}
@@ -0,0 +1,59 @@
+// Package tenant переносит текущего арендатора (библиотеку) через context.
+// Репозиторий берёт арендатора только отсюда, поэтому хендлеру не нужно
+// (и нельзя забыть) фильтровать данные самому.
+package tenant
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// DefaultID — арендатор, которому принадлежат данные однотенантной установки.
+// Его администраторы управляют всей платформой.
+const DefaultID = 1
+
+type ctxKey struct{}
+
+// WithID кладёт арендатора в контекст.
+func WithID(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает арендатора из контекста или DefaultID, если он не задан
+// (однотенантная установка, фоновые задачи).
+func FromContext(ctx context.Context) int {
+	if id, ok := IDFromContext(ctx); ok {
+		return id
+	}
+	return DefaultID
+}
+
+// IDFromContext сообщает, задан ли арендатор явно.
+func IDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(ctxKey{}).(int)
+	return id, ok && id != 0
+}
+
+// SlugFromHost извлекает поддомен арендатора: "lib2.library.example.com" при
+// baseDomain "library.example.com" даёт "lib2". Для самого baseDomain и чужих
+// хостов возвращает "".
+func SlugFromHost(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	suffix := "." + strings.ToLower(baseDomain)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	slug := strings.TrimSuffix(host, suffix)
+	// вложенные поддомены не поддерживаем
+	if slug == "" || strings.Contains(slug, ".") {
+		return ""
+	}
+	return slug
+}
@@ -0,0 +1,38 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugFromHost(t *testing.T) {
+	cases := []struct {
+		host, base, want string
+	}{
+		{"lib2.library.test", "library.test", "lib2"},
+		{"LIB2.Library.Test:8080", "library.test", "lib2"},
+		{"library.test", "library.test", ""},
+		{"a.b.library.test", "library.test", ""},
+		{"lib2.evil-library.test", "library.test", ""},
+		{"lib2.library.test", "", ""},
+		{"127.0.0.1:8080", "library.test", ""},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, SlugFromHost(c.host, c.base), c.host)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, DefaultID, FromContext(ctx))
+	_, ok := IDFromContext(ctx)
+	require.False(t, ok)
+
+	ctx = WithID(ctx, 7)
+	require.Equal(t, 7, FromContext(ctx))
+	id, ok := IDFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, 7, id)
+}
@@ -0,0 +1,83 @@
+// Package tracing настраивает OpenTelemetry: экспорт спанов и распространение
+// контекста трассировки через заголовок W3C traceparent.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Поддерживаемые экспортёры.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const defaultServiceName = "library-api"
+
+// Propagator читает и пишет traceparent/tracestate (W3C Trace Context) и baggage.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+type Config struct {
+	// Exporter — "none" (по умолчанию), "stdout" или "otlp".
+	Exporter string
+	// Endpoint — адрес OTLP/HTTP коллектора (http://collector:4318). Пустой —
+	// берётся из стандартной OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint    string
+	ServiceName string
+	// Output — куда пишет экспортёр stdout; по умолчанию os.Stdout.
+	Output io.Writer
+}
+
+// Setup делает провайдер спанов глобальным (otel.GetTracerProvider). Возвращённую
+// функцию нужно вызвать при остановке, чтобы отправить накопленные спаны.
+// При Exporter "none" спаны не записываются, но traceparent по-прежнему
+// передаётся дальше.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		out := cfg.Output
+		if out == nil {
+			out = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = defaultServiceName
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_Stdout(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, Output: &out})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "GET /api/books")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	require.Contains(t, out.String(), `"Name":"GET /api/books"`)
+	require.Contains(t, out.String(), defaultServiceName)
+}
+
+func TestSetup_NoneAndUnknown(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Config{Exporter: "zipkin"})
+	require.EqualError(t, err, `unknown tracing exporter "zipkin"`)
+}
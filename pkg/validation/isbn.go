@@ -0,0 +1,66 @@
+package validation
+
+import "strings"
+
+// NormalizeISBN убирает дефисы и пробелы и приводит X к верхнему регистру.
+// В базе ISBN хранится только в таком виде, иначе «978-5-...» и «9785...»
+// считались бы разными книгами.
+func NormalizeISBN(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '-' || r == ' ':
+		case r == 'x':
+			b.WriteRune('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ValidISBN проверяет ISBN-10 или ISBN-13 вместе с контрольной цифрой.
+func ValidISBN(s string) bool {
+	s = NormalizeISBN(s)
+	switch len(s) {
+	case 10:
+		return validISBN10(s)
+	case 13:
+		return validISBN13(s)
+	default:
+		return false
+	}
+}
+
+func validISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var d int
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(s string) bool {
+	sum := 0
+	for i := 0; i < 13; i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
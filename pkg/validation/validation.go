@@ -0,0 +1,119 @@
+// Package validation проверяет запросы по тегам `validate` (go-playground/validator)
+// и возвращает все нарушения разом в виде apperr.ErrValidation с полями.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"leti/pkg/apperr"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// В ошибках поля называем так же, как в JSON: клиент не знает имён Go-структур
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	mustRegister(v, "notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	mustRegister(v, "isbn", func(fl validator.FieldLevel) bool {
+		return ValidISBN(fl.Field().String())
+	})
+	return v
+}
+
+func mustRegister(v *validator.Validate, tag string, fn validator.Func) {
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+}
+
+// Struct проверяет структуру по тегам. Нарушения возвращаются одной ошибкой
+// apperr.ErrValidation, в которой перечислены все поля.
+func Struct(s any) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	fields := make([]apperr.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, apperr.FieldError{Field: fieldPath(fe), Message: message(fe)})
+	}
+	msg := fields[0].Field + ": " + fields[0].Message
+	if len(fields) > 1 {
+		msg = fmt.Sprintf("%s (and %d more)", msg, len(fields)-1)
+	}
+	return apperr.Validation(msg, fields...)
+}
+
+// fieldPath убирает имя корневой структуры: "CreateTenantRequest.admin.email" -> "admin.email".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func message(fe validator.FieldError) string {
+	return messageFor(fe.Tag(), fe.Param(), fe.Kind())
+}
+
+// messageFor описывает правило словами. Для альтернатив вида "isbn|len=0"
+// объясняем первое правило: остальные обычно разрешают пустое значение.
+func messageFor(tag, param string, kind reflect.Kind) string {
+	if first, _, ok := strings.Cut(tag, "|"); ok {
+		tag, param, _ = strings.Cut(first, "=")
+	}
+	switch tag {
+	case "required", "required_without":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13"
+	case "email":
+		return "must be a valid email address"
+	case "ip":
+		return "must be a valid IP address"
+	case "url":
+		return "must be a valid URL"
+	case "min":
+		return "must be at least " + param + unit(kind)
+	case "max":
+		return "must be at most " + param + unit(kind)
+	default:
+		return fmt.Sprintf("failed %q rule", tag)
+	}
+}
+
+// unit поясняет, что min/max для строк и списков ограничивают длину, а не значение.
+func unit(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " item(s)"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"leti/pkg/apperr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidISBN(t *testing.T) {
+	cases := []struct {
+		isbn  string
+		valid bool
+	}{
+		{"978-5-17-090630-7", true},
+		{"9785170906307", true},
+		{"0-306-40615-2", true},
+		{"0-8044-2957-x", true},
+		{"978-5-17-090630-8", false}, // неверная контрольная цифра
+		{"0-306-40615-3", false},
+		{"12345", false},
+		{"978X170906307", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.valid, ValidISBN(tc.isbn), tc.isbn)
+	}
+	require.Equal(t, "080442957X", NormalizeISBN("0-8044-2957-x"))
+}
+
+type testAddress struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type testRequest struct {
+	Name    string       `json:"name" validate:"required,notblank"`
+	ISBN    string       `json:"isbn,omitempty" validate:"omitempty,isbn"`
+	Price   *int         `json:"price,omitempty" validate:"omitempty,min=0"`
+	Tags    []string     `json:"tags" validate:"max=2"`
+	Address *testAddress `json:"address,omitempty"`
+}
+
+func TestStruct_ReportsAllFields(t *testing.T) {
+	price := -1
+	err := Struct(testRequest{
+		Name:    "   ",
+		ISBN:    "123",
+		Price:   &price,
+		Tags:    []string{"a", "b", "c"},
+		Address: &testAddress{Email: "nope"},
+	})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+	require.Equal(t, []apperr.FieldError{
+		{Field: "name", Message: "must not be blank"},
+		{Field: "isbn", Message: "must be a valid ISBN-10 or ISBN-13"},
+		{Field: "price", Message: "must be at least 0"},
+		{Field: "tags", Message: "must be at most 2 item(s)"},
+		{Field: "address.email", Message: "must be a valid email address"},
+	}, apperr.Fields(err))
+	require.Equal(t, "name: must not be blank (and 4 more)", err.Error())
+}
+
+func TestStruct_Valid(t *testing.T) {
+	require.NoError(t, Struct(testRequest{Name: "Идиот", ISBN: "978-5-17-090630-7"}))
+	require.NoError(t, Struct(&testRequest{Name: "Идиот"}))
+}